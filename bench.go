@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// benchCount is the number of times a benchmark is run for a comparison, so
+// benchstat has enough samples to compute a meaningful delta
+const benchCount = 6
+
+// benchDoneMsg is sent when a benchmark baseline or comparison completes
+type benchDoneMsg struct {
+	item    *runner.TestItem
+	logFile string // Log file with the comparison (empty for a baseline)
+	err     error
+}
+
+// baselineFile returns the path where the benchmark baseline is stored
+func (m *Model) baselineFile(info runner.TestInfo) string {
+	return filepath.Join(m.logDir, runner.LogFilePrefix(info)+".baseline.bench")
+}
+
+// captureBaseline runs the current benchmark and stores the results as the
+// baseline for later comparisons
+func (m *Model) captureBaseline() tea.Cmd {
+	item := m.currentBenchmark()
+	if item == nil {
+		return nil
+	}
+
+	m.setStatusMessage(fmt.Sprintf("capturing baseline for %s...", item.Info.Name))
+	baseline := m.baselineFile(item.Info)
+	return func() tea.Msg {
+		err := m.runner.RunBenchmark(context.Background(), item.Info, benchCount, baseline)
+		return benchDoneMsg{item: item, err: err}
+	}
+}
+
+// compareWithBaseline runs the current benchmark and compares the results
+// with the baseline using benchstat. The comparison is written to a new log
+// file, so it's shown in the output pane.
+func (m *Model) compareWithBaseline() tea.Cmd {
+	item := m.currentBenchmark()
+	if item == nil {
+		return nil
+	}
+
+	baseline := m.baselineFile(item.Info)
+	if _, err := os.Stat(baseline); err != nil {
+		m.setStatusMessage("no baseline captured yet (press b)")
+		return nil
+	}
+
+	benchstat, err := exec.LookPath("benchstat")
+	if err != nil {
+		m.setStatusMessage("benchstat not found (go install golang.org/x/perf/cmd/benchstat@latest)")
+		return nil
+	}
+
+	m.setStatusMessage(fmt.Sprintf("comparing %s with baseline...", item.Info.Name))
+	timestamp := time.Now().Format(runner.DefaultLogTimeFormat)
+	results := filepath.Join(m.logDir, fmt.Sprintf("%s.%s.bench", runner.LogFilePrefix(item.Info), timestamp))
+	logFile := filepath.Join(m.logDir, fmt.Sprintf("%s.%s.log", runner.LogFilePrefix(item.Info), timestamp))
+
+	return func() tea.Msg {
+		if err := m.runner.RunBenchmark(context.Background(), item.Info, benchCount, results); err != nil {
+			return benchDoneMsg{item: item, err: err}
+		}
+
+		out, err := exec.Command(benchstat, baseline, results).CombinedOutput()
+		if err == nil {
+			err = os.WriteFile(logFile, out, 0644)
+		}
+		return benchDoneMsg{item: item, logFile: logFile, err: err}
+	}
+}
+
+// currentBenchmark returns the current item if it's a benchmark that isn't
+// running. Otherwise it shows a status message and returns nil.
+func (m *Model) currentBenchmark() *runner.TestItem {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return nil
+	}
+
+	item := m.filteredList[m.cursor]
+	if item.Info.Kind != runner.KindBenchmark {
+		m.setStatusMessage(fmt.Sprintf("%s is not a benchmark", item.Info.Name))
+		return nil
+	}
+	if item.Status == runner.StatusQueued || item.Status == runner.StatusRunning {
+		m.setStatusMessage(fmt.Sprintf("%s is still running", item.Info.Name))
+		return nil
+	}
+	return item
+}
+
+// handleBenchDone processes the result of a benchmark baseline or comparison
+func (m *Model) handleBenchDone(msg benchDoneMsg) {
+	switch {
+	case msg.err != nil:
+		m.setStatusMessage(fmt.Sprintf("benchmark %s failed: %v", msg.item.Info.Name, msg.err))
+	case msg.logFile == "":
+		m.setStatusMessage(fmt.Sprintf("captured baseline for %s", msg.item.Info.Name))
+	default:
+		msg.item.LogFile = msg.logFile
+		m.setStatusMessage(fmt.Sprintf("compared %s with baseline", msg.item.Info.Name))
+		m.refreshOutput()
+	}
+}
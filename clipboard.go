@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// copyToClipboard copies text to the system clipboard using the OSC 52
+// escape sequence, which also works over SSH and inside tmux
+func copyToClipboard(text string) error {
+	seq := osc52.New(text)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	}
+	_, err := seq.WriteTo(os.Stderr)
+	return err
+}
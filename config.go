@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the name of the per-project configuration file
+const projectConfigFile = ".test-runner.yml"
+
+// Config holds the settings that can be set using configuration files and
+// environment variables. Zero values mean "not set". There is no theme
+// setting, as the colors are built in.
+type Config struct {
+	LogDir           string        `yaml:"log-dir"`
+	Parallel         int           `yaml:"parallel"`
+	Timeout          time.Duration `yaml:"timeout"`
+	TestCommand      string        `yaml:"test-cmd"`
+	LogTimeFormat    string        `yaml:"log-time-format"`
+	Excludes         []string      `yaml:"excludes"`
+	ConfirmThreshold *int          `yaml:"confirm-threshold"` // Zero disables the confirmation
+	FollowThreshold  *int          `yaml:"follow-threshold"`  // Zero only re-enables auto-scroll at the bottom
+	TestSignature    string        `yaml:"test-signature"`
+	Tags             []string      `yaml:"tags"`   // Build tags used to discover and run the tests
+	Editor           string        `yaml:"editor"` // Command template to open a file at a line
+}
+
+// merge overrides the settings in c with the settings that are set in other
+func (c *Config) merge(other Config) {
+	if other.LogDir != "" {
+		c.LogDir = other.LogDir
+	}
+	if other.Parallel > 0 {
+		c.Parallel = other.Parallel
+	}
+	if other.Timeout > 0 {
+		c.Timeout = other.Timeout
+	}
+	if other.TestCommand != "" {
+		c.TestCommand = other.TestCommand
+	}
+	if other.LogTimeFormat != "" {
+		c.LogTimeFormat = other.LogTimeFormat
+	}
+	if len(other.Excludes) > 0 {
+		c.Excludes = other.Excludes
+	}
+	if other.ConfirmThreshold != nil {
+		c.ConfirmThreshold = other.ConfirmThreshold
+	}
+	if other.FollowThreshold != nil {
+		c.FollowThreshold = other.FollowThreshold
+	}
+	if other.TestSignature != "" {
+		c.TestSignature = other.TestSignature
+	}
+	if len(other.Tags) > 0 {
+		c.Tags = other.Tags
+	}
+	if other.Editor != "" {
+		c.Editor = other.Editor
+	}
+}
+
+// LoadConfig loads the configuration for the test directory. Settings are
+// applied in order of precedence: environment variables, the project
+// configuration (found by walking up from the test directory) and the user
+// configuration (~/.test-runner/config.yml).
+func LoadConfig(testDir string) (Config, error) {
+	var cfg Config
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		userCfg, err := readConfigFile(filepath.Join(homeDir, ".test-runner", "config.yml"))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.merge(userCfg)
+	}
+
+	if path := findProjectConfig(testDir); path != "" {
+		projectCfg, err := readConfigFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.merge(projectCfg)
+	}
+
+	envCfg, err := configFromEnv()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.merge(envCfg)
+
+	return cfg, nil
+}
+
+// findProjectConfig walks up from the test directory to find the project
+// configuration file. It returns an empty string if there is none.
+func findProjectConfig(testDir string) string {
+	dir, err := filepath.Abs(testDir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, projectConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readConfigFile reads a configuration file. A missing file results in an
+// empty configuration.
+func readConfigFile(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// configFromEnv reads the TEST_RUNNER_* environment variables
+func configFromEnv() (Config, error) {
+	var cfg Config
+
+	cfg.LogDir = os.Getenv("TEST_RUNNER_LOG_DIR")
+	cfg.TestCommand = os.Getenv("TEST_RUNNER_TEST_CMD")
+	cfg.LogTimeFormat = os.Getenv("TEST_RUNNER_LOG_TIME_FORMAT")
+	cfg.TestSignature = os.Getenv("TEST_RUNNER_TEST_SIGNATURE")
+	cfg.Editor = os.Getenv("TEST_RUNNER_EDITOR")
+
+	if v := os.Getenv("TEST_RUNNER_PARALLEL"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TEST_RUNNER_PARALLEL: %w", err)
+		}
+		cfg.Parallel = n
+	}
+
+	if v := os.Getenv("TEST_RUNNER_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TEST_RUNNER_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = d
+	}
+
+	if v := os.Getenv("TEST_RUNNER_CONFIRM_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TEST_RUNNER_CONFIRM_THRESHOLD: %w", err)
+		}
+		cfg.ConfirmThreshold = &n
+	}
+
+	if v := os.Getenv("TEST_RUNNER_FOLLOW_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TEST_RUNNER_FOLLOW_THRESHOLD: %w", err)
+		}
+		cfg.FollowThreshold = &n
+	}
+
+	if v := os.Getenv("TEST_RUNNER_EXCLUDES"); v != "" {
+		cfg.Excludes = strings.Split(v, ",")
+	}
+
+	if v := os.Getenv("TEST_RUNNER_TAGS"); v != "" {
+		cfg.Tags = strings.Split(v, ",")
+	}
+
+	return cfg, nil
+}
+
+// applyConfig fills the options that weren't set using command line flags
+// from the configuration
+func applyConfig(opts *Options, cfg Config, setFlags map[string]bool) {
+	if !setFlags["log-dir"] && cfg.LogDir != "" {
+		opts.LogDir = cfg.LogDir
+	}
+	if !setFlags["test-timeout"] && cfg.Timeout > 0 {
+		opts.TestTimeout = cfg.Timeout
+	}
+	if !setFlags["test-cmd"] && cfg.TestCommand != "" {
+		opts.TestCommand = cfg.TestCommand
+	}
+	if !setFlags["log-time-format"] && cfg.LogTimeFormat != "" {
+		opts.LogTimeFormat = cfg.LogTimeFormat
+	}
+	if !setFlags["confirm-threshold"] && cfg.ConfirmThreshold != nil {
+		opts.ConfirmThreshold = *cfg.ConfirmThreshold
+	}
+	if !setFlags["follow-threshold"] && cfg.FollowThreshold != nil {
+		opts.FollowThreshold = *cfg.FollowThreshold
+	}
+	if !setFlags["test-signature"] && cfg.TestSignature != "" {
+		opts.TestSignature = cfg.TestSignature
+	}
+	if !setFlags["tags"] && len(cfg.Tags) > 0 {
+		opts.Tags = strings.Join(cfg.Tags, ",")
+	}
+	if !setFlags["editor"] && cfg.Editor != "" {
+		opts.Editor = cfg.Editor
+	}
+	if !setFlags["parallel"] && cfg.Parallel > 0 {
+		opts.Parallel = cfg.Parallel
+	}
+	opts.ParallelSet = setFlags["parallel"] || cfg.Parallel > 0
+	opts.TimeoutSet = setFlags["test-timeout"] || cfg.Timeout > 0
+	opts.Excludes = cfg.Excludes
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	// Use an empty home directory, so no user configuration is found
+	t.Setenv("HOME", t.TempDir())
+
+	root := t.TempDir()
+	testDir := filepath.Join(root, "sub", "pkg")
+	if err := os.MkdirAll(testDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := "parallel: 5\ntimeout: 2m\nexcludes: [Integration]\nconfirm-threshold: 0\n"
+	if err := os.WriteFile(filepath.Join(root, projectConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_RUNNER_PARALLEL", "7")
+
+	cfg, err := LoadConfig(testDir)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Parallel != 7 {
+		t.Errorf("Expected parallel 7 from the environment, got %d", cfg.Parallel)
+	}
+	if cfg.Timeout != 2*time.Minute {
+		t.Errorf("Expected timeout 2m from the project config, got %s", cfg.Timeout)
+	}
+
+	// Flags take precedence over the configuration
+	opts := Options{TestTimeout: time.Minute}
+	applyConfig(&opts, cfg, map[string]bool{"test-timeout": true})
+	if opts.TestTimeout != time.Minute {
+		t.Errorf("Expected timeout 1m from the flag, got %s", opts.TestTimeout)
+	}
+	if len(opts.Excludes) != 1 || opts.Excludes[0] != "Integration" {
+		t.Errorf("Expected excludes from the project config, got %v", opts.Excludes)
+	}
+
+	// A threshold of zero is set explicitly to disable the confirmation
+	if opts.ConfirmThreshold != 0 || cfg.ConfirmThreshold == nil {
+		t.Errorf("Expected the confirmation to be disabled by the project config, got %v", opts.ConfirmThreshold)
+	}
+	opts = Options{ConfirmThreshold: defaultConfirmThreshold}
+	applyConfig(&opts, Config{}, nil)
+	if opts.ConfirmThreshold != defaultConfirmThreshold {
+		t.Errorf("Expected the default threshold without configuration, got %d", opts.ConfirmThreshold)
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	user := "follow-threshold: 3\ntags: [integration]\neditor: vim +{line} {file}\n"
+	if err := os.MkdirAll(filepath.Join(home, ".test-runner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".test-runner", "config.yml"), []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project configuration can set the threshold back to zero
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, projectConfigFile), []byte("follow-threshold: 0\ntags: [integration, e2e]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(root)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	opts := Options{FollowThreshold: 5}
+	applyConfig(&opts, cfg, nil)
+	if opts.FollowThreshold != 0 {
+		t.Errorf("Expected follow threshold 0 from the project config, got %d", opts.FollowThreshold)
+	}
+	if opts.Tags != "integration,e2e" {
+		t.Errorf("Expected the tags of the project config, got %q", opts.Tags)
+	}
+	if opts.Editor != "vim +{line} {file}" {
+		t.Errorf("Expected the editor of the user config, got %q", opts.Editor)
+	}
+	if opts.ParallelSet || opts.TimeoutSet {
+		t.Error("Expected the saved parallelism and timeout to be used when they aren't configured")
+	}
+
+	// The environment takes precedence over the saved parallelism and timeout
+	t.Setenv("TEST_RUNNER_PARALLEL", "3")
+	t.Setenv("TEST_RUNNER_TIMEOUT", "1m")
+	cfg, err = LoadConfig(root)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	opts = Options{}
+	applyConfig(&opts, cfg, nil)
+	if !opts.ParallelSet || !opts.TimeoutSet || opts.Parallel != 3 || opts.TestTimeout != time.Minute {
+		t.Errorf("Expected the parallelism and timeout of the environment, got %d and %s", opts.Parallel, opts.TestTimeout)
+	}
+
+	// Flags take precedence over the configuration
+	opts = Options{Tags: "unit", Editor: "code --goto {file}:{line}"}
+	applyConfig(&opts, cfg, map[string]bool{"tags": true, "editor": true})
+	if opts.Tags != "unit" || opts.Editor != "code --goto {file}:{line}" {
+		t.Errorf("Expected the tags and editor of the flags, got %q and %q", opts.Tags, opts.Editor)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// coverDoneMsg is sent when the HTML coverage report was opened
+type coverDoneMsg struct {
+	name string
+	err  error
+}
+
+// openCoverReport opens the HTML coverage report of the last run of the
+// current test in the browser, using go tool cover
+func (m *Model) openCoverReport() tea.Cmd {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return nil
+	}
+
+	item := m.filteredList[m.cursor]
+	if item.Status == runner.StatusQueued || item.Status == runner.StatusRunning {
+		m.setStatusMessage(fmt.Sprintf("%s is still running", item.Info.Name))
+		return nil
+	}
+	if item.CoverProfile == "" {
+		m.setStatusMessage("no coverage collected for " + item.Info.Name + " (press C to enable)")
+		return nil
+	}
+
+	// The profile refers to the packages, so go tool cover must run in the
+	// module of the test to find their sources
+	cmd := exec.Command("go", "tool", "cover", "-html="+item.CoverProfile)
+	cmd.Dir = filepath.Join(m.testDir, item.Info.Module)
+	name := item.Info.Name
+	return func() tea.Msg {
+		out, err := cmd.CombinedOutput()
+		if err != nil && len(out) > 0 {
+			err = fmt.Errorf("%w: %s", err, out)
+		}
+		return coverDoneMsg{name: name, err: err}
+	}
+}
+
+// handleCoverDone reports whether the coverage report could be opened
+func (m *Model) handleCoverDone(msg coverDoneMsg) {
+	if msg.err != nil {
+		m.setStatusMessage(fmt.Sprintf("coverage report failed: %v", msg.err))
+		return
+	}
+	m.setStatusMessage("opened coverage report of " + msg.name)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// knownEditorArgs are the arguments that make well-known editors open a file
+// at a line, keyed by the name of the executable
+var knownEditorArgs = map[string]string{
+	"code":          "--goto {file}:{line}",
+	"code-insiders": "--goto {file}:{line}",
+	"codium":        "--goto {file}:{line}",
+	"cursor":        "--goto {file}:{line}",
+	"vim":           "+{line} {file}",
+	"nvim":          "+{line} {file}",
+	"vi":            "+{line} {file}",
+	"nano":          "+{line} {file}",
+	"emacs":         "+{line} {file}",
+	"emacsclient":   "+{line} {file}",
+	"micro":         "+{line} {file}",
+	"subl":          "{file}:{line}",
+	"hx":            "{file}:{line}",
+	"zed":           "{file}:{line}",
+}
+
+// detectedEditors are tried in order when neither a template nor a known
+// $EDITOR is available
+var detectedEditors = []string{"code", "cursor", "vim", "nvim", "nano"}
+
+// editorTemplate determines the command template used to open a file at a
+// line. An explicit template takes precedence. $EDITOR is only used when it
+// is a known editor, as the arguments to jump to a line differ per editor.
+// Otherwise, the first detected editor is used. It returns an empty string
+// when no editor is found.
+func editorTemplate(template string, lookPath func(string) (string, error)) string {
+	if template != "" {
+		return template
+	}
+
+	// Keep the arguments of $EDITOR, e.g. "code --wait"
+	if editor := strings.TrimSpace(os.Getenv("EDITOR")); editor != "" {
+		name := strings.TrimSuffix(filepath.Base(strings.Fields(editor)[0]), ".exe")
+		if args, ok := knownEditorArgs[name]; ok {
+			return editor + " " + args
+		}
+	}
+
+	for _, name := range detectedEditors {
+		if _, err := lookPath(name); err == nil {
+			return name + " " + knownEditorArgs[name]
+		}
+	}
+	return ""
+}
+
+// editorCommand renders an editor template with the {file} and {line}
+// placeholders into the command line. The template is split on whitespace
+// before the placeholders are replaced, so file names with spaces remain a
+// single argument.
+func editorCommand(template, file string, line int) []string {
+	args := strings.Fields(template)
+	for i, arg := range args {
+		arg = strings.ReplaceAll(arg, "{file}", file)
+		args[i] = strings.ReplaceAll(arg, "{line}", strconv.Itoa(line))
+	}
+	return args
+}
+
+// openEditor starts the editor for the file at the given line
+func openEditor(template, file string, line int) error {
+	args := editorCommand(editorTemplate(template, exec.LookPath), file, line)
+	if len(args) == 0 {
+		return exec.ErrNotFound
+	}
+	return exec.Command(args[0], args[1:]...).Start()
+}
+
+// sourceLocationRegexp matches references to Go source lines in test output,
+// e.g. "foo_test.go:123" in a failure message or an absolute path in a stack
+// trace
+var sourceLocationRegexp = regexp.MustCompile(`(?:^|[\s(])((?:[A-Za-z]:)?[^\s:()]+\.go):(\d+)\b`)
+
+// sourceLocation is a line in a source file
+type sourceLocation struct {
+	file string
+	line int
+}
+
+// failureLocations returns the source locations referenced in the output, in
+// order of appearance and without duplicates. Relative paths are resolved
+// against the package directory, as go test reports them relative to it.
+// Absolute paths outside the root directory (such as the standard library in
+// a stack trace) are skipped.
+func failureLocations(lines []string, pkgDir, root string) []sourceLocation {
+	var locations []sourceLocation
+	seen := make(map[sourceLocation]bool)
+	for _, line := range lines {
+		for _, match := range sourceLocationRegexp.FindAllStringSubmatch(line, -1) {
+			file := match[1]
+			if filepath.IsAbs(file) {
+				if rel, err := filepath.Rel(root, file); err != nil || strings.HasPrefix(rel, "..") {
+					continue
+				}
+			} else {
+				file = filepath.Join(pkgDir, file)
+			}
+
+			n, err := strconv.Atoi(match[2])
+			if err != nil {
+				continue
+			}
+			location := sourceLocation{file: file, line: n}
+			if !seen[location] {
+				seen[location] = true
+				locations = append(locations, location)
+			}
+		}
+	}
+	return locations
+}
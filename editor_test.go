@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	got := editorCommand("code --goto {file}:{line}", "/src/my pkg/foo_test.go", 42)
+	expected := []string{"code", "--goto", "/src/my pkg/foo_test.go:42"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("editorCommand() = %q, expected %q", got, expected)
+	}
+
+	got = editorCommand("vim +{line} {file}", "foo_test.go", 7)
+	expected = []string{"vim", "+7", "foo_test.go"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("editorCommand() = %q, expected %q", got, expected)
+	}
+}
+
+func TestEditorTemplate(t *testing.T) {
+	onlyVim := func(name string) (string, error) {
+		if name == "vim" {
+			return "/usr/bin/vim", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	cases := []struct {
+		template string
+		editor   string
+		expected string
+	}{
+		{"myedit -l {line} {file}", "code", "myedit -l {line} {file}"},
+		{"", "code", "code --goto {file}:{line}"},
+		{"", "/usr/local/bin/code --wait", "/usr/local/bin/code --wait --goto {file}:{line}"},
+		{"", "nvim", "nvim +{line} {file}"},
+		{"", "unknown-editor", "vim +{line} {file}"},
+		{"", "", "vim +{line} {file}"},
+	}
+	for _, c := range cases {
+		t.Setenv("EDITOR", c.editor)
+		if got := editorTemplate(c.template, onlyVim); got != c.expected {
+			t.Errorf("editorTemplate(%q) with EDITOR=%q = %q, expected %q", c.template, c.editor, got, c.expected)
+		}
+	}
+
+	// EDITOR=code must not open a file named +42
+	t.Setenv("EDITOR", "code")
+	args := editorCommand(editorTemplate("", onlyVim), "foo_test.go", 42)
+	if expected := []string{"code", "--goto", "foo_test.go:42"}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+}
+
+func TestFailureLocations(t *testing.T) {
+	root := filepath.FromSlash("/src/project")
+	pkgDir := filepath.Join(root, "pkg")
+	lines := []string{
+		"=== RUN   TestFoo",
+		"    foo_test.go:12: expected 1, got 2",
+		"    foo_test.go:12: expected 1, got 2",
+		"--- FAIL: TestFoo (0.00s)",
+		"panic: boom [recovered]",
+		"\t" + filepath.FromSlash("/usr/local/go/src/testing/testing.go") + ":1792 +0x123",
+		"\t" + filepath.Join(root, "pkg", "helper.go") + ":34 +0x1d",
+	}
+
+	got := failureLocations(lines, pkgDir, root)
+	expected := []sourceLocation{
+		{file: filepath.Join(pkgDir, "foo_test.go"), line: 12},
+		{file: filepath.Join(root, "pkg", "helper.go"), line: 34},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("failureLocations() = %v, expected %v", got, expected)
+	}
+
+	if got := failureLocations([]string{"ok  \tgithub.com/foo/bar\t0.01s"}, pkgDir, root); len(got) != 0 {
+		t.Errorf("Expected no locations, got %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// runFlakeDetection runs all discovered tests the given number of times
+// without the TUI and prints a report classifying each test as stable-pass,
+// stable-fail or flaky. It returns the process exit code.
+func runFlakeDetection(testDir string, opts Options, runs int) int {
+	m, err := NewModel(testDir, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	for _, item := range m.tests {
+		m.runner.QueueRepeated(item, runs)
+	}
+
+	// Wait until all repeated runs have completed
+	<-m.runner.Idle()
+
+	return printFlakeReport(os.Stdout, m.tests)
+}
+
+// flakeClass classifies a test based on the outcome of its runs
+func flakeClass(item *runner.TestItem) string {
+	runs, failures := item.FlakeRate()
+	switch {
+	case failures == 0:
+		return "stable-pass"
+	case failures == runs:
+		return "stable-fail"
+	default:
+		return "flaky"
+	}
+}
+
+// printFlakeReport writes the flake report with the flaky tests first and
+// returns 1 if any test failed at least once
+func printFlakeReport(w io.Writer, items []*runner.TestItem) int {
+	order := map[string]int{"flaky": 0, "stable-fail": 1, "stable-pass": 2}
+
+	sorted := append([]*runner.TestItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order[flakeClass(sorted[i])] < order[flakeClass(sorted[j])]
+	})
+
+	exitCode := 0
+	for _, item := range sorted {
+		runs, failures := item.FlakeRate()
+		class := flakeClass(item)
+		if class != "stable-pass" {
+			exitCode = 1
+		}
+
+		name := item.Info.Name
+		if item.Info.Package != "" {
+			name = item.Info.Package + "/" + name
+		}
+
+		rate := 0
+		if runs > 0 {
+			rate = failures * 100 / runs
+		}
+		fmt.Fprintf(w, "%-12s %3d%%  %s (%d/%d failed)\n", strings.ToUpper(class), rate, name, failures, runs)
+	}
+
+	return exitCode
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestFlakeClass(t *testing.T) {
+	tests := []struct {
+		runs, failures int
+		expected       string
+	}{
+		{5, 0, "stable-pass"},
+		{5, 5, "stable-fail"},
+		{5, 2, "flaky"},
+		{1, 1, "stable-fail"},
+		{0, 0, "stable-pass"},
+	}
+
+	for _, tt := range tests {
+		item := &runner.TestItem{Runs: tt.runs, Failures: tt.failures}
+		if got := flakeClass(item); got != tt.expected {
+			t.Errorf("flakeClass(%d/%d failed) = %s, expected %s", tt.failures, tt.runs, got, tt.expected)
+		}
+	}
+}
+
+func TestPrintFlakeReport(t *testing.T) {
+	pass := &runner.TestItem{Info: runner.TestInfo{Name: "TestPass"}, Runs: 4}
+	fail := &runner.TestItem{Info: runner.TestInfo{Name: "TestFail", Package: "sub"}, Runs: 4, Failures: 4}
+	flaky := &runner.TestItem{Info: runner.TestInfo{Name: "TestFlaky"}, Runs: 4, Failures: 1}
+
+	tests := []struct {
+		name     string
+		items    []*runner.TestItem
+		expected []string
+		exitCode int
+	}{
+		{"stable-pass", []*runner.TestItem{pass}, []string{"STABLE-PASS    0%  TestPass (0/4 failed)"}, 0},
+		{"stable-fail", []*runner.TestItem{pass, fail}, []string{"STABLE-FAIL  100%  sub/TestFail (4/4 failed)", "STABLE-PASS    0%  TestPass (0/4 failed)"}, 1},
+		{"flaky", []*runner.TestItem{pass, fail, flaky}, []string{"FLAKY         25%  TestFlaky (1/4 failed)", "STABLE-FAIL  100%  sub/TestFail (4/4 failed)", "STABLE-PASS    0%  TestPass (0/4 failed)"}, 1},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		exitCode := printFlakeReport(&out, tt.items)
+		if exitCode != tt.exitCode {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.exitCode, exitCode)
+		}
+		if expected := strings.Join(tt.expected, "\n") + "\n"; out.String() != expected {
+			t.Errorf("%s: expected the report\n%s\ngot\n%s", tt.name, expected, out.String())
+		}
+	}
+}
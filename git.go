@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// gitChanges are the Go files with changes according to git, relative to the
+// test directory and using forward slashes
+type gitChanges struct {
+	packages  map[string]bool // Packages with changed files other than tests (same format as runner.TestInfo.Package)
+	testFiles map[string]bool // Changed test files
+}
+
+// includes checks if a test is affected by the changes: all tests of a
+// package are affected when its code changed, otherwise only the tests in the
+// changed test files
+func (c gitChanges) includes(t runner.TestInfo, testDir string) bool {
+	if c.packages[t.Package] {
+		return true
+	}
+	file, err := filepath.Rel(testDir, t.File)
+	return err == nil && c.testFiles[filepath.ToSlash(file)]
+}
+
+// changedFiles returns the Go files that are modified, staged or untracked
+// according to git. With a base ref, the files that changed since the merge
+// base of the ref and HEAD are included as well, so all changes of the
+// current branch are found.
+func changedFiles(testDir, base string) (gitChanges, error) {
+	changes := gitChanges{packages: make(map[string]bool), testFiles: make(map[string]bool)}
+
+	// Git reports paths with symlinks resolved, so the test directory must be
+	// resolved as well to compare them
+	absDir, err := filepath.Abs(testDir)
+	if err != nil {
+		return changes, err
+	}
+	if resolved, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = resolved
+	}
+
+	root, err := gitOutput(absDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return changes, err
+	}
+	root = strings.TrimSpace(root)
+
+	since := "HEAD"
+	if base != "" {
+		mergeBase, err := gitOutput(absDir, "merge-base", base, "HEAD")
+		if err != nil {
+			return changes, err
+		}
+		since = strings.TrimSpace(mergeBase)
+	}
+	changed, err := gitOutput(absDir, "diff", "--name-only", since)
+	if err != nil {
+		return changes, err
+	}
+	untracked, err := gitOutput(absDir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return changes, err
+	}
+
+	for _, file := range strings.Split(changed+"\n"+untracked, "\n") {
+		file = strings.TrimSpace(file)
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+
+		rel, err := filepath.Rel(absDir, filepath.Join(root, file))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue // Outside the test directory
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasSuffix(rel, "_test.go") {
+			changes.testFiles[rel] = true
+			continue
+		}
+
+		pkgDir := filepath.ToSlash(filepath.Dir(rel))
+		if pkgDir == "." {
+			pkgDir = ""
+		}
+		changes.packages[pkgDir] = true
+	}
+
+	return changes, nil
+}
+
+// gitOutput runs a git command in the given directory and returns its output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(out), err
+}
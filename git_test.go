@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// git runs a git command in the directory and fails the test on an error
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChangedTests(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/changed\n\ngo 1.21\n")
+	for _, pkg := range []string{"code", "tests", "untouched"} {
+		writeFile(t, filepath.Join(dir, pkg, pkg+".go"), "package "+pkg+"\n")
+		writeFile(t, filepath.Join(dir, pkg, "a_test.go"), "package "+pkg+"\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n")
+		writeFile(t, filepath.Join(dir, pkg, "b_test.go"), "package "+pkg+"\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {}\n")
+	}
+	git(t, dir, "init", "-q", "-b", "main")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "initial")
+
+	// The code of one package changes on a branch, and a test file of another
+	// package has an uncommitted change
+	git(t, dir, "checkout", "-q", "-b", "feature")
+	writeFile(t, filepath.Join(dir, "code", "code.go"), "package code\n\nvar X = 1\n")
+	git(t, dir, "commit", "-q", "-am", "change code")
+	writeFile(t, filepath.Join(dir, "tests", "b_test.go"), "package tests\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) { t.Log() }\n")
+
+	discover := func(base string) []string {
+		tests, err := discoverTests(dir, runner.DiscoverOptions{Recursive: true}, true, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, test := range tests {
+			names = append(names, test.Package+"/"+test.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	// Only the test in the changed test file is affected by the uncommitted
+	// change
+	if got, expected := discover(""), []string{"tests/TestB"}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %v for the uncommitted changes, got %v", expected, got)
+	}
+
+	// All tests of the package with changed code are affected by the branch
+	if got, expected := discover("main"), []string{"code/TestA", "code/TestB", "tests/TestB"}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %v for the changes of the branch, got %v", expected, got)
+	}
+
+	// An unknown base ref results in all tests
+	if got := discover("unknown"); len(got) != 6 {
+		t.Errorf("Expected all tests for an unknown base ref, got %v", got)
+	}
+}
+
+func TestChangedTestsOutsideGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a_test.go"), "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n")
+	tests, err := discoverTests(dir, runner.DiscoverOptions{Recursive: true}, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 1 {
+		t.Errorf("Expected all tests outside a git repository, got %v", tests)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// listRow is a row in the test list. In the grouped view, the tests of each
+// package are preceded by a header row.
+type listRow struct {
+	index  int    // Index in the filtered list (of the first test of the package for a header)
+	header bool   // Package header in the grouped view
+	pkg    string // Package of the header
+	count  int    // Number of tests in the package of the header
+}
+
+// visibleRows returns the rows of the test list. In the grouped view, the
+// tests of collapsed packages are left out, so navigation skips them.
+func (m *Model) visibleRows() []listRow {
+	rows := make([]listRow, 0, len(m.filteredList))
+	if !m.grouped {
+		for i := range m.filteredList {
+			rows = append(rows, listRow{index: i})
+		}
+		return rows
+	}
+
+	for i := 0; i < len(m.filteredList); {
+		pkg := m.filteredList[i].Info.Package
+		end := i + 1
+		for end < len(m.filteredList) && m.filteredList[end].Info.Package == pkg {
+			end++
+		}
+
+		rows = append(rows, listRow{index: i, header: true, pkg: pkg, count: end - i})
+		if !m.collapsedPackages[pkg] {
+			for j := i; j < end; j++ {
+				rows = append(rows, listRow{index: j})
+			}
+		}
+		i = end
+	}
+	return rows
+}
+
+// cursorOnHeader returns whether the cursor is on a package header. The
+// cursor is always on the header of a collapsed package.
+func (m *Model) cursorOnHeader() bool {
+	if !m.grouped || m.cursor >= len(m.filteredList) {
+		return false
+	}
+	item := m.filteredList[m.cursor]
+	return m.headerItem == item || m.collapsedPackages[item.Info.Package]
+}
+
+// cursorRow returns the index of the row under the cursor
+func (m *Model) cursorRow(rows []listRow) int {
+	onHeader := m.cursorOnHeader()
+	for r, row := range rows {
+		if onHeader && row.header && m.cursor >= row.index && m.cursor < row.index+row.count {
+			return r
+		}
+		if !onHeader && !row.header && row.index == m.cursor {
+			return r
+		}
+	}
+	return 0
+}
+
+// moveCursor moves the cursor by a number of rows, staying within the list
+func (m *Model) moveCursor(delta int) {
+	rows := m.visibleRows()
+	if len(rows) == 0 {
+		return
+	}
+
+	current := m.cursorRow(rows)
+	target := min(max(current+delta, 0), len(rows)-1)
+	if target == current {
+		return
+	}
+
+	row := rows[target]
+	m.cursor = row.index
+	m.headerItem = nil
+	if row.header {
+		m.headerItem = m.filteredList[row.index]
+	}
+	m.resetOutputScroll()
+}
+
+// cursorTests returns the tests under the cursor: all tests of the package on
+// a header row, otherwise the current test
+func (m *Model) cursorTests() []*runner.TestItem {
+	if m.cursor >= len(m.filteredList) {
+		return nil
+	}
+
+	item := m.filteredList[m.cursor]
+	if !m.cursorOnHeader() {
+		return []*runner.TestItem{item}
+	}
+
+	var tests []*runner.TestItem
+	for _, t := range m.filteredList {
+		if t.Info.Package == item.Info.Package {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
+
+// selectionScope returns the tests that select all, deselect all and invert
+// apply to: the tests of the package when the cursor is on a header,
+// otherwise all tests in the list
+func (m *Model) selectionScope() []*runner.TestItem {
+	if m.cursorOnHeader() {
+		return m.cursorTests()
+	}
+	return m.filteredList
+}
+
+// toggleGrouped switches between the flat list and the list grouped by
+// package, keeping the cursor on the current test
+func (m *Model) toggleGrouped() {
+	var current *runner.TestItem
+	if m.cursor < len(m.filteredList) {
+		current = m.filteredList[m.cursor]
+	}
+
+	m.grouped = !m.grouped
+	m.headerItem = nil
+	m.applyFilter()
+	m.applySorting()
+
+	for i, t := range m.filteredList {
+		if t == current {
+			m.cursor = i
+			break
+		}
+	}
+	m.resetOutputScroll()
+}
+
+// togglePackage collapses or expands the package under the cursor. The
+// cursor moves to the header, so it remains visible.
+func (m *Model) togglePackage() {
+	if m.cursor >= len(m.filteredList) {
+		return
+	}
+
+	// Move the cursor to the first test of the package, which the header
+	// represents
+	pkg := m.filteredList[m.cursor].Info.Package
+	for i, t := range m.filteredList {
+		if t.Info.Package == pkg {
+			m.cursor = i
+			break
+		}
+	}
+
+	if m.collapsedPackages == nil {
+		m.collapsedPackages = make(map[string]bool)
+	}
+	m.collapsedPackages[pkg] = !m.collapsedPackages[pkg]
+	m.headerItem = m.filteredList[m.cursor]
+	m.resetOutputScroll()
+}
+
+// groupByPackage orders the tests by package. The sort is stable, so the
+// tests are sorted within their package by the current sort mode.
+func groupByPackage(tests []*runner.TestItem) {
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Info.Package < tests[j].Info.Package
+	})
+}
+
+// renderGroupHeader renders the header row of a package in the grouped view.
+// It shows the most significant status and how many tests passed.
+func (m *Model) renderGroupHeader(row listRow, width int, isCursor bool) string {
+	tests := m.filteredList[row.index : row.index+row.count]
+
+	status := runner.StatusIdle
+	passed, allSelected := 0, true
+	for _, t := range tests {
+		if miniMapPriority[t.Status] > miniMapPriority[status] {
+			status = t.Status
+		}
+		if t.Status == runner.StatusPassed {
+			passed++
+		}
+		allSelected = allSelected && t.Selected
+	}
+
+	var line strings.Builder
+	if allSelected {
+		line.WriteString("●")
+	} else {
+		line.WriteString(" ")
+	}
+	line.WriteString(statusIcons[status])
+
+	marker := "▾ "
+	if m.collapsedPackages[row.pkg] {
+		marker = "▸ "
+	}
+	name := row.pkg
+	if name == "" {
+		name = "."
+	}
+	summary := fmt.Sprintf(" %d/%d", passed, row.count)
+
+	maxNameWidth := width - 10 - lipgloss.Width(marker) - len(summary)
+	name = truncateName(name, maxNameWidth)
+	line.WriteString(marker + name + summary)
+
+	style := lipgloss.NewStyle().Bold(true)
+	switch {
+	case isCursor:
+		style = style.Background(cursorColor).Foreground(lipgloss.Color("0"))
+	case m.showPackageColors:
+		style = style.Foreground(packageColor(row.pkg))
+	}
+	return style.Render(line.String())
+}
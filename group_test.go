@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestGroupedView(t *testing.T) {
+	b1 := &runner.TestItem{Info: runner.TestInfo{Name: "TestB1", Package: "b"}}
+	a1 := &runner.TestItem{Info: runner.TestInfo{Name: "TestA1", Package: "a"}}
+	b2 := &runner.TestItem{Info: runner.TestInfo{Name: "TestB2", Package: "b"}}
+	a2 := &runner.TestItem{Info: runner.TestInfo{Name: "TestA2", Package: "a"}}
+	tests := []*runner.TestItem{b1, a1, b2, a2}
+
+	m := &Model{tests: tests, runner: runner.NewTestRunner(t.TempDir(), t.TempDir(), 0, 0), logDir: t.TempDir()}
+	m.applyFilter()
+	m.toggleGrouped()
+
+	// Headers precede the tests of each package, sorted within the package
+	rows := m.visibleRows()
+	var got []string
+	for _, row := range rows {
+		if row.header {
+			got = append(got, "["+row.pkg+"]")
+		} else {
+			got = append(got, m.filteredList[row.index].Info.Name)
+		}
+	}
+	expected := "[a] TestA1 TestA2 [b] TestB1 TestB2"
+	if s := strings.Join(got, " "); s != expected {
+		t.Fatalf("Expected rows %q, got %q", expected, s)
+	}
+
+	// Move to the header of package a and select its tests only
+	m.cursor = 0
+	m.moveCursor(-10)
+	if !m.cursorOnHeader() || len(m.cursorTests()) != 2 {
+		t.Fatalf("Expected the cursor on the header of package a, got %d tests", len(m.cursorTests()))
+	}
+	for _, item := range m.selectionScope() {
+		item.Selected = true
+	}
+	if !a1.Selected || !a2.Selected || b1.Selected || b2.Selected {
+		t.Error("Expected only the tests of package a to be selected")
+	}
+
+	// Navigation skips the tests of a collapsed package
+	m.togglePackage()
+	m.moveCursor(1)
+	if !m.cursorOnHeader() || m.filteredList[m.cursor] != b1 {
+		t.Errorf("Expected the cursor on the header of package b, got %s", m.filteredList[m.cursor].Info.Name)
+	}
+	m.moveCursor(1)
+	if m.cursorOnHeader() || m.filteredList[m.cursor] != b1 {
+		t.Errorf("Expected the cursor on TestB1, got %s", m.filteredList[m.cursor].Info.Name)
+	}
+
+	// The flat view is still available
+	m.toggleGrouped()
+	if m.filteredList[m.cursor] != b1 || len(m.visibleRows()) != 4 {
+		t.Errorf("Expected the flat view with the cursor on TestB1")
+	}
+}
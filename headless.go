@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// runHeadless runs all tests in the list without the TUI, like go test does,
+// printing a line for each finished test and a summary at the end. It returns
+// the process exit code, which is non-zero when a test failed.
+func runHeadless(testDir string, opts Options) int {
+	m, err := NewModel(testDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Subtests run as part of their parent and benchmarks are only run on
+	// request
+	var items []*runner.TestItem
+	for _, item := range m.tests {
+		if item.Info.Kind == runner.KindTest && !item.Info.IsSubtest() {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		fmt.Println("no tests found")
+		return 0
+	}
+
+	var mu sync.Mutex
+	done := 0
+	m.runner.SetFinishedCallback(func(item *runner.TestItem) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		fmt.Println(headlessProgressLine(item, done, len(items)))
+	})
+
+	// Stop the tests when interrupted, so no test processes are left behind
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(interrupt)
+
+	started := time.Now()
+	for _, item := range items {
+		m.runner.QueueTest(item)
+	}
+
+	select {
+	case <-m.runner.Idle():
+	case <-interrupt:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		m.runner.Shutdown(ctx)
+		return 130
+	}
+
+	passed, failed, skipped := m.batchCounts(m.runner.CurrentBatch())
+	fmt.Printf("%d passed, %d failed, %d skipped in %s\n", passed, failed, skipped, formatDuration(time.Since(started)))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// headlessProgressLine returns the line that's printed when a test finished
+func headlessProgressLine(item *runner.TestItem, done, total int) string {
+	status, _ := item.LogState()
+	name := item.Info.Name
+	if item.Info.Package != "" {
+		name = item.Info.Package + "/" + name
+	}
+	width := len(fmt.Sprint(total))
+	return fmt.Sprintf("[%*d/%d] %-7s %s (%s)", width, done, total, strings.ToUpper(status.String()), name, formatDuration(item.Duration()))
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestHeadlessProgressLine(t *testing.T) {
+	finished := time.Now()
+	item := &runner.TestItem{
+		Info:       runner.TestInfo{Name: "TestFoo", Package: "sub"},
+		Status:     runner.StatusFailed,
+		StartedAt:  finished.Add(-1500 * time.Millisecond),
+		FinishedAt: finished,
+	}
+
+	got := headlessProgressLine(item, 3, 12)
+	expected := "[ 3/12] FAILED  sub/TestFoo (" + formatDuration(1500*time.Millisecond) + ")"
+	if got != expected {
+		t.Errorf("headlessProgressLine() = %q, expected %q", got, expected)
+	}
+}
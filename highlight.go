@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+)
+
+// DiffKind classifies an output line that is part of a diff
+type DiffKind int
+
+const (
+	DiffNone DiffKind = iota
+	DiffHeader
+	DiffHunk
+	DiffAdded
+	DiffRemoved
+)
+
+// classifyDiffLines detects diff blocks in test output and classifies each
+// line. It recognizes unified diffs (as printed by testify) and go-cmp style
+// "(-want +got)" diffs. Detection is heuristic: a diff block starts at a known
+// diff marker and ends at the first empty line or go test status line.
+func classifyDiffLines(lines []string) []DiffKind {
+	kinds := make([]DiffKind, len(lines))
+	inDiff := false
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if isDiffStart(trimmed) {
+			inDiff = true
+			kinds[i] = DiffHeader
+			continue
+		}
+
+		if !inDiff {
+			continue
+		}
+
+		if trimmed == "" || isTestStatusLine(trimmed) {
+			inDiff = false
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "+++"), strings.HasPrefix(trimmed, "---"):
+			kinds[i] = DiffHeader
+		case strings.HasPrefix(trimmed, "@@"):
+			kinds[i] = DiffHunk
+		case strings.HasPrefix(trimmed, "+"):
+			kinds[i] = DiffAdded
+		case strings.HasPrefix(trimmed, "-"):
+			kinds[i] = DiffRemoved
+		}
+	}
+
+	return kinds
+}
+
+// isDiffStart checks if a line starts a diff block
+func isDiffStart(trimmed string) bool {
+	return strings.Contains(trimmed, "(-want +got)") ||
+		strings.Contains(trimmed, "(-got +want)") ||
+		strings.Contains(trimmed, "(-expected +actual)") ||
+		strings.HasPrefix(trimmed, "Diff:") ||
+		strings.HasPrefix(trimmed, "--- Expected") ||
+		strings.HasPrefix(trimmed, "diff --git ")
+}
+
+// isTestStatusLine checks if a line is a go test status line, which ends a
+// diff block
+func isTestStatusLine(trimmed string) bool {
+	for _, prefix := range []string{"=== ", "--- PASS", "--- FAIL", "--- SKIP", "PASS", "FAIL", "ok "} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
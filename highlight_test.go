@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyDiffLines(t *testing.T) {
+	lines := []string{
+		"=== RUN   TestCompare",
+		"    compare_test.go:12: mismatch (-want +got):",
+		"          []string{",
+		"        - \"a\",",
+		"        + \"b\",",
+		"          }",
+		"--- FAIL: TestCompare (0.00s)",
+		"- not a diff",
+	}
+	expected := []DiffKind{
+		DiffNone,
+		DiffHeader,
+		DiffNone,
+		DiffRemoved,
+		DiffAdded,
+		DiffNone,
+		DiffNone,
+		DiffNone,
+	}
+
+	if kinds := classifyDiffLines(lines); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// historyRuns is the number of most recent runs used to determine the
+// reliability of a test
+const historyRuns = 10
+
+// testHistory holds the results of the most recent runs of a test
+type testHistory struct {
+	runs     int
+	failures int
+}
+
+// historyLog is a finished log file of a test
+type historyLog struct {
+	file      string
+	timestamp time.Time
+}
+
+// updateHistory determines the results of the most recent runs of each test
+// from the log files. Results of finished logs are cached, as they never
+// change.
+func (m *Model) updateHistory() {
+	layout := m.runner.LogTimeFormat()
+	entries, err := os.ReadDir(m.logDir)
+	if err != nil {
+		return
+	}
+
+	// Group the log files by test
+	logs := make(map[string][]historyLog)
+	for _, entry := range entries {
+		info, ts, ok := runner.ParseLogFileName(entry.Name(), layout)
+		if !ok {
+			continue
+		}
+		prefix := runner.LogFilePrefix(info)
+		logs[prefix] = append(logs[prefix], historyLog{file: filepath.Join(m.logDir, entry.Name()), timestamp: ts})
+	}
+
+	if m.logResults == nil {
+		m.logResults = make(map[string]runner.TestStatus)
+	}
+
+	history := make(map[string]testHistory)
+	for prefix, testLogs := range logs {
+		sort.Slice(testLogs, func(i, j int) bool {
+			return testLogs[i].timestamp.After(testLogs[j].timestamp)
+		})
+
+		var h testHistory
+		for _, log := range testLogs {
+			if h.runs == historyRuns {
+				break
+			}
+
+			status, ok := m.logResults[log.file]
+			if !ok {
+				status = logResultStatus(log.file)
+
+				// Logs without a result may still be written to
+				if status != runner.StatusIdle {
+					m.logResults[log.file] = status
+				}
+			}
+
+			switch status {
+			case runner.StatusPassed:
+				h.runs++
+			case runner.StatusFailed:
+				h.runs++
+				h.failures++
+			}
+		}
+		history[prefix] = h
+	}
+
+	m.history = history
+}
+
+// testReliability returns the results of the most recent runs of a test
+func (m *Model) testReliability(info runner.TestInfo) testHistory {
+	return m.history[runner.LogFilePrefix(info)]
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleJumpKey handles keys while typing the jump-to search in the left
+// pane. The cursor moves to the first matching test while typing, without
+// hiding any tests.
+func (m *Model) handleJumpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	switch key {
+	case "enter":
+		// Keep the search text, so n/N cycle through the matches
+		m.jumpMode = false
+
+	case "esc":
+		m.jumpMode = false
+		m.jumpText = ""
+
+	case "backspace":
+		if len(m.jumpText) > 0 {
+			m.jumpText = trimLastRune(m.jumpText)
+			m.jumpFrom(m.jumpOrigin, 1)
+		}
+
+	default:
+		if len(key) == 1 {
+			m.jumpText += key
+			m.jumpFrom(m.jumpOrigin, 1)
+		}
+	}
+
+	return m, nil
+}
+
+// startJump starts the jump-to search from the current test
+func (m *Model) startJump() {
+	m.jumpMode = true
+	m.jumpText = ""
+	m.jumpOrigin = m.cursor
+}
+
+// jumpFrom moves the cursor to the first test matching the jump-to search,
+// starting at the given index and searching in the given direction. The
+// search wraps around the end of the list.
+func (m *Model) jumpFrom(start, dir int) bool {
+	n := len(m.filteredList)
+	if m.jumpText == "" || n == 0 {
+		return false
+	}
+
+	text := strings.ToLower(m.jumpText)
+	for i := range n {
+		idx := ((start+i*dir)%n + n) % n
+		if strings.Contains(strings.ToLower(m.filteredList[idx].Info.Name), text) {
+			if idx != m.cursor {
+				m.cursor = idx
+				m.resetOutputScroll()
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// jumpToNextMatch moves the cursor to the next (dir 1) or previous (dir -1)
+// test matching the jump-to search
+func (m *Model) jumpToNextMatch(dir int) {
+	if !m.jumpFrom(m.cursor+dir, dir) && m.jumpText != "" {
+		m.setStatusMessage("no test matches '" + m.jumpText + "'")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// junitFile is the name of the JUnit report in the log directory
+const junitFile = "junit.xml"
+
+// junitLogTailLines is the number of log lines included in a failure
+const junitLogTailLines = 50
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the tests of a single package
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a single test
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+// junitFailure describes why a test failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// buildJUnitReport converts the finished tests into a JUnit report, with a
+// test suite per package. Tests that haven't finished are left out.
+func buildJUnitReport(tests []runner.TestSnapshot) junitTestSuites {
+	var report junitTestSuites
+	suites := make(map[string]*junitTestSuite)
+	seconds := make(map[string]float64)
+	var total float64
+
+	for _, t := range tests {
+		if !t.Status.Finished() {
+			continue
+		}
+
+		pkg := t.Info.Package
+		if pkg == "" {
+			pkg = "."
+		}
+		suite := suites[pkg]
+		if suite == nil {
+			suite = &junitTestSuite{Name: pkg}
+			suites[pkg] = suite
+		}
+
+		testCase := junitTestCase{
+			Name:      t.Info.Name,
+			ClassName: pkg,
+			Time:      fmt.Sprintf("%.3f", t.Duration.Seconds()),
+		}
+		switch t.Status {
+		case runner.StatusFailed:
+			testCase.Failure = &junitFailure{
+				Message: "Failed",
+				Body:    strings.Join(logTail(t.LogFile, junitLogTailLines), "\n"),
+			}
+			if t.BuildFailed {
+				testCase.Failure.Message = "Build failed"
+			}
+			suite.Failures++
+		case runner.StatusSkipped:
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+		seconds[pkg] += t.Duration.Seconds()
+		total += t.Duration.Seconds()
+	}
+
+	for pkg, suite := range suites {
+		suite.Time = fmt.Sprintf("%.3f", seconds[pkg])
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, *suite)
+	}
+	sort.Slice(report.Suites, func(i, j int) bool {
+		return report.Suites[i].Name < report.Suites[j].Name
+	})
+	report.Time = fmt.Sprintf("%.3f", total)
+	return report
+}
+
+// logTail returns the last lines of a log file (nil if it can't be read)
+func logTail(logFile string, n int) []string {
+	if logFile == "" {
+		return nil
+	}
+	file, err := os.Open(logFile)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	lines := readLines(file)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// writeJUnitReport writes the results of all finished tests as a JUnit XML
+// report
+func writeJUnitReport(path string, r *runner.TestRunner) error {
+	data, err := xml.MarshalIndent(buildJUnitReport(r.Snapshot()), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append([]byte(xml.Header), append(data, '\n')...))
+}
+
+// writeFileAtomic writes a file via a temporary file in the same directory,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// exportJUnit writes the JUnit report to the path given on the command line
+// or to the log directory
+func (m *Model) exportJUnit() {
+	path := m.junitPath
+	if path == "" {
+		path = filepath.Join(m.logDir, junitFile)
+	}
+	if err := writeJUnitReport(path, m.runner); err != nil {
+		m.setStatusMessage(fmt.Sprintf("junit export failed: %v", err))
+		return
+	}
+	m.setStatusMessage("junit report written to " + path)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestBuildJUnitReport(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "fail.log")
+	if err := os.WriteFile(logFile, []byte("=== RUN   TestFail\n    foo_test.go:12: boom\n--- FAIL: TestFail (0.10s)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := buildJUnitReport([]runner.TestSnapshot{
+		{Info: runner.TestInfo{Name: "TestPass"}, Status: runner.StatusPassed, Duration: 1500 * time.Millisecond},
+		{Info: runner.TestInfo{Name: "TestFail", Package: "sub"}, Status: runner.StatusFailed, Duration: 100 * time.Millisecond, LogFile: logFile},
+		{Info: runner.TestInfo{Name: "TestSkip", Package: "sub"}, Status: runner.StatusSkipped},
+		{Info: runner.TestInfo{Name: "TestIdle"}, Status: runner.StatusIdle},
+	})
+
+	if report.Tests != 3 || report.Failures != 1 || report.Skipped != 1 {
+		t.Errorf("report has %d tests, %d failures and %d skipped, expected 3, 1 and 1", report.Tests, report.Failures, report.Skipped)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "." || report.Suites[1].Name != "sub" {
+		t.Fatalf("unexpected suites: %+v", report.Suites)
+	}
+	if report.Suites[0].Time != "1.500" {
+		t.Errorf("suite time = %s, expected 1.500", report.Suites[0].Time)
+	}
+
+	failed := report.Suites[1].TestCases[0]
+	if failed.Failure == nil || !strings.Contains(failed.Failure.Body, "foo_test.go:12: boom") {
+		t.Errorf("failure doesn't include the log tail: %+v", failed.Failure)
+	}
+	if skipped := report.Suites[1].TestCases[1]; skipped.Skipped == nil {
+		t.Errorf("skipped test isn't marked as skipped")
+	}
+
+	data, err := xml.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<testcase name="TestSkip" classname="sub" time="0.000"><skipped></skipped></testcase>`) {
+		t.Errorf("unexpected XML: %s", data)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "junit.xml")
+	if err := writeFileAtomic(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("file contains %q (%v), expected %q", data, err, "second")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files are left behind: %v", entries)
+	}
+}
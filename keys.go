@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// keyBinding describes a key and what it does. The bindings are the single
+// source of truth for the help overlay and the hints in the status bar.
+type keyBinding struct {
+	keys string // Keys as shown to the user
+	desc string // Description in the help overlay
+	hint string // Hint in the status bar (empty when not shown there)
+}
+
+// keyGroup is a group of bindings that are active in the same context
+type keyGroup struct {
+	title    string
+	bindings []keyBinding
+}
+
+// keyGroups are all key bindings, grouped by the context they're used in
+var keyGroups = []keyGroup{
+	{"Global", []keyBinding{
+		{"Tab", "Switch focus between panes", ""},
+		{"q", "Quit (running tests are stopped)", "quit"},
+		{"?", "Show this help", "help"},
+	}},
+	{"Test list", []keyBinding{
+		{"j / k", "Move cursor down/up", ""},
+		{"Home / End", "Go to the first/last test", ""},
+		{"PgUp / PgDn", "Page up/down", ""},
+		{"Space", "Toggle test selection", ""},
+		{"a / d / i", "Select all/deselect all/invert selection", ""},
+		{"g", "Run selected tests (or current)", "go"},
+		{"t", "Stop test or remove from queue", "stop"},
+		{"^", "Run the current test next (queues it if needed)", ""},
+		{"R", "Restart selected tests (or current)", ""},
+		{"V", "Re-run failed selected tests once with -v", ""},
+		{"K", "Run selected tests repeatedly to detect flakiness", ""},
+		{"U", "Run the current test until it fails", ""},
+		{"G", "Run all visible tests", ""},
+		{"F", "Re-run all visible failed tests", ""},
+		{"X", "Stop all visible tests", ""},
+		{"Ctrl+X", "Stop all running and queued tests", ""},
+		{"s", "Toggle sort mode (name/selection/status)", "sort"},
+		{"r", "Toggle recursive test discovery", "rec"},
+		{"e", "Open test in editor", "edit"},
+		{"E", "Open the failure location (cycles)", ""},
+		{"!", "Open a shell in the package directory", ""},
+		{"y", "Copy a command that reproduces the test", ""},
+		{"x", "Export the results as JUnit XML", ""},
+		{"W", "Write a JSON report to the log directory", ""},
+		{"b / B", "Capture/compare a benchmark baseline", ""},
+		{"/", "Filter tests (pkg: for packages, ! to exclude)", "filter"},
+		{"f", "Cycle the status filter", ""},
+		{"z", "Collapse or expand subtests (a package on a header)", ""},
+		{"L", "Toggle grouping tests by package", ""},
+		{"Z", "Collapse or expand the current package", ""},
+		{"J", "Jump to a test by name", ""},
+		{"n / N", "Next/previous jump match", ""},
+		{"} / {", "Next/previous failed test", ""},
+		{"[ / ]", "Move current test up/down", ""},
+		{"O", "Toggle combined output of selected tests", ""},
+		{"v", "Toggle a preview of the tests g would run", ""},
+		{"p", "Peek at the first failure in the output", ""},
+		{"m", "Mark the shown run as the diff baseline", ""},
+		{"u", "Toggle the diff with the baseline run", ""},
+		{"D", "Toggle doc comments", ""},
+		{"H", "Toggle the reliability indicator", ""},
+		{"P", "Toggle package colors", ""},
+		{"M", "Toggle the mini-map", ""},
+		{"A", "Toggle duration or time since finished", ""},
+		{"+ / -", "Increase/decrease parallelism", "par"},
+		{"o", "Toggle sequential mode", ""},
+		{"Q", "Toggle starting the fastest tests first", ""},
+		{"I", "Toggle running a package's tests in one go test", ""},
+		{") / (", "Increase/decrease the test timeout", ""},
+		{"> / <", "Increase/decrease retries of failed runs", ""},
+		{"w", "Toggle auto-run of changed packages", ""},
+		{"Ctrl+R", "Toggle the race detector", ""},
+		{"C", "Toggle coverage collection", ""},
+		{"c", "Open the HTML coverage report", ""},
+		{"T", "Edit extra go test flags", ""},
+	}},
+	{"Output", []keyBinding{
+		{"j / k", "Scroll down/up", ""},
+		{"h / l", "Scroll left/right", ""},
+		{"Shift+←/→", "Scroll left/right by half a page", ""},
+		{"w", "Toggle wrapping long lines", ""},
+		{"PgUp / PgDn", "Page up/down", ""},
+		{"Home / End", "Go to the beginning/end", ""},
+		{"F", "Follow the output", ""},
+		{"/", "Search text (case-insensitive)", ""},
+		{"\\", "Search a regular expression", ""},
+		{"n / N", "Next/previous search match", ""},
+		{"c", "Toggle diff colorization", ""},
+		{"y / Y", "Copy the output/search matches", ""},
+	}},
+	{"Filter, search and input", []keyBinding{
+		{"Enter", "Apply", ""},
+		{"Esc", "Close the input (a filter is kept)", ""},
+		{"Backspace", "Delete the last character", ""},
+	}},
+}
+
+// minHelpColumnWidth is the minimum width of a column in the help overlay
+const minHelpColumnWidth = 36
+
+// statusBarHints returns the key hints shown in the status bar
+func statusBarHints() string {
+	var hints []string
+	for _, group := range keyGroups {
+		for _, binding := range group.bindings {
+			if binding.hint != "" {
+				keys := strings.ReplaceAll(binding.keys, " ", "")
+				hints = append(hints, keys+":"+binding.hint)
+			}
+		}
+	}
+	return strings.Join(hints, " │ ")
+}
+
+// renderHelp renders the help overlay with all key bindings. The groups flow
+// into as many columns as needed to fit the height.
+func renderHelp(width, height int) string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(focusedBorderColor)
+	keyStyle := lipgloss.NewStyle().Bold(true)
+
+	keyWidth := 0
+	for _, group := range keyGroups {
+		for _, binding := range group.bindings {
+			keyWidth = max(keyWidth, lipgloss.Width(binding.keys))
+		}
+	}
+
+	// Lay out the lines of all groups, separated by empty lines
+	type helpLine struct {
+		title   string
+		binding keyBinding
+	}
+	var lines []helpLine
+	for i, group := range keyGroups {
+		if i > 0 {
+			lines = append(lines, helpLine{})
+		}
+		lines = append(lines, helpLine{title: group.title})
+		for _, binding := range group.bindings {
+			lines = append(lines, helpLine{binding: binding})
+		}
+	}
+
+	// Leave room for the border, padding and footer. Lines that don't fit
+	// in the terminal are left out.
+	rows := max(height-6, 1)
+	columns := (len(lines) + rows - 1) / rows
+	maxColumns := max((width-4)/(minHelpColumnWidth+2), 1)
+	if columns > maxColumns {
+		columns = maxColumns
+		lines = append(lines[:rows*columns-1], helpLine{title: "… (enlarge the terminal to see all keys)"})
+	}
+	columnWidth := max((width-4)/columns-2, 1)
+
+	var rendered []string
+	for c := 0; c < columns; c++ {
+		var column strings.Builder
+		for r := 0; r < rows; r++ {
+			i := c*rows + r
+			if i >= len(lines) {
+				break
+			}
+			// Don't start a column with an empty separator line
+			if r == 0 && lines[i].title == "" && lines[i].binding.keys == "" {
+				continue
+			}
+
+			line := lines[i]
+			var text string
+			switch {
+			case line.title != "":
+				text = titleStyle.Render(line.title)
+			case line.binding.keys != "":
+				keys := fmt.Sprintf("%-*s", keyWidth, line.binding.keys)
+				text = keyStyle.Render(keys) + "  " + line.binding.desc
+			}
+			column.WriteString(ansi.Truncate(text, columnWidth, "…"))
+			column.WriteString("\n")
+		}
+		rendered = append(rendered, lipgloss.NewStyle().Width(columnWidth).MarginRight(2).Render(strings.TrimSuffix(column.String(), "\n")))
+	}
+
+	footer := lipgloss.NewStyle().Faint(true).Render("Press any key to close")
+	content := lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, rendered...), "", footer)
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(focusedBorderColor).
+		Padding(0, 1).
+		Render(content)
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// logIndexFile is the file in the base log directory that remembers which
+// test directory uses which log directory, so the logs of a project are
+// found again after it's moved
+const logIndexFile = "index.json"
+
+// logIndexEntry maps a test directory to its log directory
+type logIndexEntry struct {
+	Path    string `json:"path"`    // Absolute path of the test directory
+	Project string `json:"project"` // Module path followed by the directory within the module
+	LogDir  string `json:"logDir"`  // Name of the log directory in the base log directory
+}
+
+// getDefaultLogDir returns the default log directory based on test directory
+// hash. When there are no logs for the hash yet, but a moved test directory
+// of the same project has logs, its log directory is reused.
+func getDefaultLogDir(testDir string) (string, error) {
+	absPath, err := filepath.Abs(testDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Get home directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return resolveLogDir(filepath.Join(homeDir, ".test-runner"), absPath), nil
+}
+
+// resolveLogDir returns the log directory for a test directory in the base
+// log directory and records it in the index. Errors reading or writing the
+// index are ignored, as it's a convenience only.
+func resolveLogDir(baseDir, absPath string) string {
+	// Create hash of the absolute path
+	hash := sha256.Sum256([]byte(absPath))
+	logDir := hex.EncodeToString(hash[:8]) // Use first 8 bytes (16 hex chars)
+
+	project := projectKey(absPath)
+	entries := loadLogIndex(baseDir)
+	if _, err := os.Stat(filepath.Join(baseDir, logDir)); err != nil && project != "" {
+		for _, entry := range entries {
+			// A test directory that still exists is another copy of the
+			// project, which keeps its own logs
+			if entry.Project != project || entry.Path == absPath || pathExists(entry.Path) {
+				continue
+			}
+			if pathExists(filepath.Join(baseDir, entry.LogDir)) {
+				logDir = entry.LogDir
+				break
+			}
+		}
+	}
+
+	updated := logIndexEntry{Path: absPath, Project: project, LogDir: logDir}
+	index := []logIndexEntry{updated}
+	changed := true
+	for _, entry := range entries {
+		if entry == updated {
+			changed = false
+			continue
+		}
+		if entry.Path != absPath && entry.LogDir != logDir {
+			index = append(index, entry)
+		}
+	}
+	if changed || len(index) != len(entries) {
+		saveLogIndex(baseDir, index)
+	}
+
+	return filepath.Join(baseDir, logDir)
+}
+
+// projectKey identifies the test directory by the module path of its go.mod
+// file and the directory within the module. It returns an empty string if
+// the test directory isn't part of a module.
+func projectKey(absPath string) string {
+	root := runner.FindModuleRoot(absPath)
+	if root == "" {
+		return ""
+	}
+	module := modulePath(filepath.Join(root, "go.mod"))
+	if module == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == "." {
+		return module
+	}
+	return module + "/" + filepath.ToSlash(rel)
+}
+
+// modulePath returns the module path declared in a go.mod file or an empty
+// string if it can't be read
+func modulePath(goMod string) string {
+	file, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		module, ok := strings.CutPrefix(line, "module")
+		if !ok || module == "" || (module[0] != ' ' && module[0] != '\t') {
+			continue
+		}
+		module, _, _ = strings.Cut(module, "//")
+		module = strings.TrimSpace(module)
+		if unquoted, err := strconv.Unquote(module); err == nil {
+			module = unquoted
+		}
+		return module
+	}
+	return ""
+}
+
+// loadLogIndex loads the index of log directories (nil if it can't be read)
+func loadLogIndex(baseDir string) []logIndexEntry {
+	data, err := os.ReadFile(filepath.Join(baseDir, logIndexFile))
+	if err != nil {
+		return nil
+	}
+	var entries []logIndexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil
+	}
+	return entries
+}
+
+// saveLogIndex writes the index of log directories
+func saveLogIndex(baseDir string, entries []logIndexEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(baseDir, logIndexFile), data)
+}
+
+// pathExists returns whether a file or directory exists
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
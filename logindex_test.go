@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveLogDirAfterMove(t *testing.T) {
+	base := t.TempDir()
+	root := t.TempDir()
+
+	oldPath := filepath.Join(root, "old")
+	if err := os.MkdirAll(filepath.Join(oldPath, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldPath, "go.mod"), []byte("module example.com/proj\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logDir := resolveLogDir(base, filepath.Join(oldPath, "sub"))
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy of the project keeps its own logs
+	copyPath := filepath.Join(root, "copy")
+	if err := os.MkdirAll(filepath.Join(copyPath, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(copyPath, "go.mod"), []byte("module example.com/proj\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolveLogDir(base, filepath.Join(copyPath, "sub")); got == logDir {
+		t.Errorf("Expected a copy of the project to get its own log directory")
+	}
+
+	// A moved project reuses its logs
+	newPath := filepath.Join(root, "new")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolveLogDir(base, filepath.Join(newPath, "sub")); got != logDir {
+		t.Errorf("Expected the moved project to use %s, got %s", logDir, got)
+	}
+
+	// Another directory of the moved project doesn't match
+	if got := resolveLogDir(base, newPath); got == logDir {
+		t.Errorf("Expected another directory in the module to get its own log directory")
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(goMod, []byte("// comment\nmodule \"example.com/quoted\" // trailing\n\ngo 1.25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := modulePath(goMod); got != "example.com/quoted" {
+		t.Errorf("modulePath() = %q, expected %q", got, "example.com/quoted")
+	}
+}
@@ -1,24 +1,75 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ramondeklein/test-runner/pkg/runner"
 )
 
 func main() {
 	// Parse command line flags
 	logDir := flag.String("log-dir", "", "Directory for log files (default: ~/.test-runner/<hash>)")
 	testTimeout := flag.Duration("test-timeout", 0, "Timeout for each test (default: 30m)")
+	testCmd := flag.String("test-cmd", "", "Command template to run a test, using {pkg}, {test} (or {run}) and {timeout} placeholders; a template ending with -- gets the go test arguments appended (default: \""+runner.DefaultTestCommand+"\")")
+	logTimeFormat := flag.String("log-time-format", "", "Go time layout for timestamps in log file names (default: "+runner.DefaultLogTimeFormat+")")
+	parallel := flag.Int("parallel", 0, fmt.Sprintf("Number of tests run in parallel; overrides the parallelism of the previous session (default: %d, the number of CPUs)", runtime.NumCPU()))
+	logKeep := flag.Int("log-keep", 0, "Number of logs kept per test; older logs are deleted when the test finishes (default: keep all)")
+	logMaxAge := flag.Duration("log-max-age", 0, "Delete the logs of a test that are older than this when the test finishes, e.g. 168h (default: keep all)")
+	testSignature := flag.String("test-signature", "", "Function signatures that count as tests: strict (only *testing.T), tb (also testing.TB) or relaxed (extra parameters allowed) (default: strict)")
+	changedOnly := flag.Bool("changed", false, "Only show tests affected by uncommitted changes according to git")
+	changedBase := flag.String("changed-base", "", "Only show tests affected by the changes since the merge base with this git ref (e.g. main), including uncommitted changes")
+	headless := flag.Bool("headless", false, "Run all tests without the TUI, printing a line for each finished test; the exit code is 1 when a test failed")
+	report := flag.String("report", "", "Run the selected tests (or all tests when none are selected) without the TUI and print a report in this format to stdout (supported: json)")
+	flakeRuns := flag.Int("flake-runs", 0, "Run all tests this many times without the TUI and report flaky tests")
+	confirmThreshold := flag.Int("confirm-threshold", defaultConfirmThreshold, "Ask for confirmation before queuing more than this many tests (0 never asks)")
+	wrap := flag.Bool("wrap", false, "Wrap long output lines instead of truncating them (toggle with w in the output pane)")
+	followThreshold := flag.Int("follow-threshold", 0, "Number of lines from the bottom of the output within which scrolling down re-enables auto-scroll (default: 0)")
+	httpAddr := flag.String("http", "", "Serve a JSON status endpoint on this address (e.g. :8080)")
+	goos := flag.String("goos", "", "Build tests for this GOOS (tests for another platform are only built, not executed)")
+	goarch := flag.String("goarch", "", "Build tests for this GOARCH (tests for another platform are only built, not executed)")
+	tags := flag.String("tags", "", "Comma-separated build tags; only tests in files that satisfy them are discovered, and they are passed to go test with -tags")
+	run := flag.String("run", "", "Only list tests with a name matching this regular expression, like go test -run")
+	watch := flag.Bool("watch", false, "Discover the tests again when test files are created, modified, removed or renamed")
+	autoRun := flag.Bool("auto", false, "Start in auto-run mode: saving a Go file re-runs the tests of its package (toggle with w)")
+	autoRunFailed := flag.Bool("auto-failed", false, "In auto-run mode, also re-run the tests that are failing")
+	editor := flag.String("editor", "", "Command template to open a test in the editor, using {file} and {line} placeholders, e.g. \"code --goto {file}:{line}\" (default: $EDITOR if it's a known editor, otherwise the first of code, cursor, vim, nvim and nano that is installed)")
+	bell := flag.Bool("bell", false, "Ring the terminal bell when all queued tests finished")
+	junit := flag.String("junit", "", "Write the results as a JUnit XML report to this path whenever all queued tests finished and on exit (x writes it on request)")
+	notify := flag.Bool("notify", false, "Show a desktop notification with the number of passed and failed tests when all queued tests finished (uses notify-send, osascript or toast)")
+	batchSummary := flag.Bool("batch-summary", false, "Show a one-line summary in the status bar when all queued tests finished")
+	fastFirst := flag.Bool("fast-first", false, "Start the queued tests that were fastest in their last run first, for quicker feedback (toggle with Q)")
+	packageRuns := flag.Bool("package-runs", false, "Run the queued tests of a package in a single go test invocation, so the package is built once (toggle with I)")
+	race := flag.Bool("race", false, "Run tests with the race detector (go test -race)")
+	var env envFlag
+	flag.Var(&env, "env", "Environment variable (KEY=VALUE) set for all tests; can be repeated. A "+runner.PackageEnvFile+" file in a package directory sets variables for the tests of that package, which take precedence")
+	testFlags := flag.String("test-flags", "", "Extra flags appended to the test command, separated by spaces (e.g. \"-count=1 -tags=integration\"); they come last, so they override the flags of the command template (default: the last-used flags)")
+	retries := flag.Int("retries", 0, "Number of times a failed run is retried before the test is reported as failed")
+	cover := flag.Bool("cover", false, "Collect a coverage profile for each run (stored next to the log file)")
+	outputLines := flag.Int("output-lines", 0, fmt.Sprintf("Maximum number of output lines kept in memory per test; older lines are only in the log file (default: %d)", runner.DefaultOutputLines))
+	noPersist := flag.Bool("no-persist", false, "Don't restore or save the session state (order, selection, results, parallelism and test flags)")
+	review := flag.Bool("review", false, "Browse the logs in the given log directory without running tests")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [test-directory]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [test-directory]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -review [log-directory]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
 	// Get test directory from remaining args or use current directory
 	testDir := "."
 	if flag.NArg() > 0 {
@@ -36,18 +87,128 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the configuration files and environment variables
+	cfg, err := LoadConfig(testDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := Options{
+		LogDir:           *logDir,
+		TestTimeout:      *testTimeout,
+		TestCommand:      *testCmd,
+		LogTimeFormat:    *logTimeFormat,
+		ChangedOnly:      *changedOnly,
+		ChangedBase:      *changedBase,
+		Parallel:         *parallel,
+		FlakeRuns:        *flakeRuns,
+		GOOS:             *goos,
+		GOARCH:           *goarch,
+		ConfirmThreshold: *confirmThreshold,
+		FollowThreshold:  *followThreshold,
+		Wrap:             *wrap,
+		TestSignature:    *testSignature,
+		LogKeep:          *logKeep,
+		LogMaxAge:        *logMaxAge,
+		Review:           *review,
+		OutputLines:      *outputLines,
+		Race:             *race,
+		FastFirst:        *fastFirst,
+		PackageRuns:      *packageRuns,
+		Tags:             *tags,
+		Run:              *run,
+		Watch:            *watch,
+		Editor:           *editor,
+		Bell:             *bell,
+		BatchSummary:     *batchSummary,
+		Notify:           *notify,
+		JUnit:            *junit,
+		AutoRun:          *autoRun,
+		AutoRunFailed:    *autoRunFailed,
+		Cover:            *cover,
+		Retries:          *retries,
+		TestFlags:        *testFlags,
+		Env:              env,
+		NoPersist:        *noPersist,
+	}
+
+	// Command line flags take precedence over the configuration
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	applyConfig(&opts, cfg, setFlags)
+
+	// Run flake detection without the TUI
+	if *flakeRuns > 0 {
+		os.Exit(runFlakeDetection(testDir, opts, *flakeRuns))
+	}
+
+	// Run the tests without the TUI
+	if *headless {
+		os.Exit(runHeadless(testDir, opts))
+	}
+
+	// Run the tests without the TUI and print a report
+	if *report != "" {
+		os.Exit(runReport(testDir, opts, *report))
+	}
+
 	// Create the model
-	model, err := NewModel(testDir, *logDir, *testTimeout)
+	model, err := NewModel(testDir, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Serve the status endpoint for remote monitoring
+	if *httpAddr != "" {
+		model.statusServer, err = startStatusServer(*httpAddr, model.runner)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot serve status endpoint: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create and run the program
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
-	if _, err := p.Run(); err != nil {
+	// Bubble Tea quits on SIGINT and SIGTERM, but closing the terminal
+	// window sends SIGHUP
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		<-hangup
+		p.Quit()
+	}()
+
+	_, err = p.Run()
+
+	// Never leave running test processes behind, however the program exits
+	model.shutdown()
+
+	if errors.Is(err, tea.ErrInterrupted) {
+		os.Exit(130)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// envFlag collects the environment variables of the repeatable -env flag
+type envFlag []string
+
+func (e *envFlag) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *envFlag) Set(s string) error {
+	key, value, err := runner.ParseEnv(s)
+	if err != nil {
+		return err
+	}
+	*e = append(*e, key+"="+value)
+	return nil
+}
@@ -2,17 +2,22 @@ package main
 
 import (
 	"bufio"
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"github.com/ramondeklein/test-runner/pkg/runner"
 )
 
 // Pane represents which pane has focus
@@ -32,90 +37,319 @@ const (
 	SortByStatus
 )
 
+// StatusFilter restricts the test list to tests with a status
+type StatusFilter int
+
+const (
+	StatusFilterAll StatusFilter = iota
+	StatusFilterRunning
+	StatusFilterPassed
+	StatusFilterFailed
+	StatusFilterSkipped
+)
+
+// statusFilterCount is the number of status filters that are cycled through
+const statusFilterCount = 5
+
+// String returns the name of the status filter
+func (f StatusFilter) String() string {
+	switch f {
+	case StatusFilterRunning:
+		return "running"
+	case StatusFilterPassed:
+		return "passed"
+	case StatusFilterFailed:
+		return "failed"
+	case StatusFilterSkipped:
+		return "skipped"
+	default:
+		return "all"
+	}
+}
+
+// matches reports whether a test with the given status passes the filter
+func (f StatusFilter) matches(status runner.TestStatus) bool {
+	switch f {
+	case StatusFilterRunning:
+		return status == runner.StatusRunning
+	case StatusFilterPassed:
+		return status == runner.StatusPassed
+	case StatusFilterFailed:
+		return status == runner.StatusFailed
+	case StatusFilterSkipped:
+		return status == runner.StatusSkipped
+	default:
+		return true
+	}
+}
+
 // Model is the main application model
 type Model struct {
-	tests       []*TestItem
+	tests       []*runner.TestItem
 	cursor      int
 	focusedPane Pane
-	runner      *TestRunner
+	runner      *runner.TestRunner
 	testDir     string
 	logDir      string
 
 	// Recursive mode (default true)
 	recursive bool
 
+	// Read-only review of the logs in a log directory
+	reviewMode bool
+
+	// Only show tests affected by changes according to git, optionally
+	// including the changes since the merge base with a ref
+	changedOnly bool
+	changedBase string
+
+	// Only show tests with a matching name (nil for all tests)
+	runFilter *regexp.Regexp
+
+	// Function signatures that count as tests
+	testSignature runner.TestSignature
+
+	// Build tags used to discover and run the tests
+	tags []string
+
+	// Tests of the test files that didn't change since they were parsed
+	discoveryCache *runner.DiscoveryCache
+
+	// Command template to open a file in the editor (empty to detect it)
+	editor string
+
+	// Failure location that was opened last, so repeated presses cycle
+	// through the locations in the output
+	failureLogFile  string
+	failureLocation int
+
+	// Watches the test directory for changes of Go files (nil when not
+	// watching)
+	watcher       *testWatcher
+	watchTests    bool // Discover the tests again when test files change
+	autoRun       bool // Re-queue the tests of packages with changed files
+	autoRunFailed bool // Re-queue the failing tests as well in auto-run mode
+
 	// Sort mode
 	sortMode SortMode
 
+	// Show the time since finished tests completed instead of their duration
+	showFinishedAgo bool
+
+	// Number of runs used to detect flaky tests
+	flakeRuns int
+
+	// Show a mini-map with the status of all tests next to the list
+	showMiniMap bool
+
+	// Show the doc comment of each test next to its name
+	showDocs bool
+
+	// Show the combined logs of the selected tests instead of the current test
+	combinedOutput bool
+
+	// Show which tests running the selection would queue instead of the output
+	showPreview bool
+
+	// Color the package of each test using a color derived from its path
+	showPackageColors bool
+
+	// Show the reliability of each test based on its recent runs
+	showHistory bool
+	history     map[string]testHistory       // Recent results by log file prefix
+	logResults  map[string]runner.TestStatus // Cached results of finished log files
+
 	// Filter state
 	filterMode   bool
 	filterText   string
-	filteredList []*TestItem
+	filteredList []*runner.TestItem
+
+	// Subtests are only shown when their parent is expanded
+	expanded    map[string]bool // Expanded parents by parent key
+	hasSubtests map[string]bool // Parents that have subtests by parent key
+
+	// In the grouped view, the tests are shown below a header per package
+	grouped           bool
+	collapsedPackages map[string]bool  // Collapsed packages in the grouped view
+	headerItem        *runner.TestItem // The cursor is on the header of this test's package
+
+	noPersist bool // Don't save the session state
+	showHelp  bool // Show the help overlay with all key bindings
+
+	// Status filter, applied together with the filter text
+	statusFilter StatusFilter
+
+	// Extra go test flags input state
+	testFlagsMode bool
+	testFlagsText string
+
+	// Jump-to search state (left pane)
+	jumpMode   bool
+	jumpText   string
+	jumpOrigin int // Cursor position when the search started
 
 	// Output view state
 	outputLines         []string
 	outputScroll        int
 	autoScroll          bool
+	followThreshold     int // Lines from the bottom that still count as "at the bottom"
 	horizontalScroll    int
-	currentLogFile      string    // Currently displayed log file
-	currentLogTimestamp time.Time // Timestamp of currently displayed log
+	currentLogFile      string               // Currently displayed log file
+	currentLogTimestamp time.Time            // Timestamp of currently displayed log
+	currentLogSize      int64                // Size of currently displayed log in bytes
+	buildErrorLog       string               // Log of a failed build that was scrolled to its first compiler error
+	outputBuffer        *runner.OutputBuffer // In-memory output that is displayed (nil when read from disk)
+	outputVersion       uint64               // Version of the output buffer that is displayed
+	outputBench         *runner.BenchResult  // Benchmark result shown above the output
+	viewingHistorical   bool                 // Displayed log is from a previous session
+	colorizeDiffs       bool                 // Colorize diff blocks in the output
+	wrapOutput          bool                 // Wrap long output lines instead of truncating them
+	wrapRows            []outputRow          // Cached rows of the wrapped output
+	wrapWidth           int                  // Width of the cached rows
+	wrapLines           int                  // Number of output lines of the cached rows
+	diffBaseline        string               // Log file of the run that other runs are compared with
+	runDiff             bool                 // Show the diff with the baseline run instead of the output
+	runDiffKey          string               // Logs and sizes of the shown diff, to only compute it again on changes
+	diffKinds           []DiffKind           // Diff classification of each output line
+
+	// Outputs shown in the combined output, to only rebuild it on changes, and
+	// the logs that were read from disk for it by path
+	combinedSources []combinedSource
+	combinedLogs    map[string]combinedLog
+
+	// Confirmation state
+	confirmMode   bool
+	confirmPrompt string
+	confirmAction func()
+
+	// Number of tests above which bulk actions ask for confirmation
+	confirmThreshold int
 
 	// Search state (right pane)
 	searchMode      bool
 	searchText      string
+	searchRegex     bool  // Search text is a regular expression
+	searchErr       error // Search text isn't a valid regular expression
 	searchMatches   []int // Line numbers with matches
 	currentMatchIdx int   // Index in searchMatches
 
+	// Transient message shown in the status bar
+	statusMessage   string
+	statusMessageAt time.Time
+
 	// Window dimensions
 	width  int
 	height int
 
 	// Update ticker
 	lastUpdate time.Time
+
+	// Last time test results were checked for staleness
+	lastStaleCheck time.Time
+
+	// Pass rate of the previous session (nil if unknown)
+	previousPassRate *int
+
+	// Last batch whose completion was reported, and how to report it
+	reportedBatch int
+	bell          bool // Ring the terminal bell when a batch finishes
+	batchSummary  bool // Show a summary when a batch finishes
+	notify        bool // Show a desktop notification when a batch finishes
+
+	// Path of the JUnit report, which is updated when a batch finishes (empty
+	// to only write it on request)
+	junitPath string
+
+	// Status endpoint, which is closed on shutdown (nil when not served)
+	statusServer *statusServer
+}
+
+// Options holds the settings used to create the model
+type Options struct {
+	LogDir           string        // Directory for log files (empty for default)
+	TestTimeout      time.Duration // Timeout for each test (zero for default)
+	TimeoutSet       bool          // TestTimeout was set using a flag, environment variable or configuration file, so it overrides the saved timeout
+	TestCommand      string        // Command template to run a test (empty for default)
+	LogTimeFormat    string        // Timestamp format used in log file names (empty for default)
+	LogKeep          int           // Number of logs kept per test (zero to keep all)
+	LogMaxAge        time.Duration // Maximum age of logs (zero to keep them forever)
+	ChangedOnly      bool          // Only discover tests affected by changes according to git
+	ChangedBase      string        // Also include the changes since the merge base with this ref (implies ChangedOnly)
+	FlakeRuns        int           // Number of runs used to detect flaky tests (zero for default)
+	Parallel         int           // Initial number of parallel tests (zero for default)
+	ParallelSet      bool          // Parallel was set using a flag, environment variable or configuration file, so it overrides the saved parallelism
+	Excludes         []string      // Test name patterns that are initially filtered out
+	GOOS             string        // Target GOOS for building tests (empty for the host)
+	GOARCH           string        // Target GOARCH for building tests (empty for the host)
+	ConfirmThreshold int           // Number of tests above which bulk actions ask for confirmation (zero to never ask)
+	TestSignature    string        // Function signatures that count as tests (empty for strict)
+	Tags             string        // Comma-separated build tags used to discover and run tests
+	Run              string        // Regular expression that the names of the listed tests must match (empty for all)
+	Watch            bool          // Discover the tests again when test files change
+	Bell             bool          // Ring the terminal bell when all queued tests finished
+	BatchSummary     bool          // Show a one-line summary when all queued tests finished
+	Notify           bool          // Show a desktop notification when all queued tests finished
+	JUnit            string        // Path of a JUnit XML report that is written when all queued tests finished
+	Editor           string        // Command template to open a file at a line, using {file} and {line} placeholders (empty to detect it)
+	AutoRun          bool          // Re-queue the tests of packages with changed files
+	AutoRunFailed    bool          // Re-queue the failing tests as well in auto-run mode
+	Wrap             bool          // Wrap long output lines instead of truncating them
+	FollowThreshold  int           // Number of lines from the bottom within which auto-scroll is re-enabled
+	Review           bool          // Browse the logs in the test directory without running tests
+	Race             bool          // Run tests with the race detector
+	FastFirst        bool          // Start the queued tests that were fastest in their last run first
+	PackageRuns      bool          // Run the queued tests of a package in a single go test invocation
+	Env              []string      // Environment variables (KEY=VALUE) set for all tests
+	Cover            bool          // Collect a coverage profile for each run
+	Retries          int           // Number of times a failed run is retried
+	TestFlags        string        // Extra flags appended to the test command (empty for the saved flags)
+	NoPersist        bool          // Don't restore or save the session state
+	OutputLines      int           // Maximum number of output lines kept in memory per test (zero for default)
 }
 
+// statusMessageDuration is how long a transient status bar message is shown
+const statusMessageDuration = 5 * time.Second
+
+// shutdownTimeout is how long to wait for running tests to exit on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// defaultFlakeRuns is the default number of runs used to detect flaky tests
+const defaultFlakeRuns = 10
+
+// staleCheckInterval is how often package sources are checked for changes
+const staleCheckInterval = 2 * time.Second
+
+// defaultConfirmThreshold is the default number of tests above which bulk
+// actions ask for confirmation
+const defaultConfirmThreshold = 20
+
 // tickMsg is sent periodically to update the display
 type tickMsg time.Time
 
 // updateMsg is sent when test status changes
 type updateMsg struct{}
 
-// getDefaultLogDir returns the default log directory based on test directory hash
-func getDefaultLogDir(testDir string) (string, error) {
-	absPath, err := filepath.Abs(testDir)
-	if err != nil {
-		return "", err
-	}
-
-	// Create hash of the absolute path
-	hash := sha256.Sum256([]byte(absPath))
-	hashStr := hex.EncodeToString(hash[:8]) // Use first 8 bytes (16 hex chars)
-
-	// Get home directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+// NewModel creates a new application model
+func NewModel(testDir string, opts Options) (*Model, error) {
+	if opts.Review {
+		return newReviewModel(testDir, opts)
 	}
 
-	return filepath.Join(homeDir, ".test-runner", hashStr), nil
-}
-
-// NewModel creates a new application model
-func NewModel(testDir string, logDir string, testTimeout time.Duration) (*Model, error) {
-	tests, err := DiscoverTests(testDir)
+	signature, err := runner.ParseTestSignature(opts.TestSignature)
 	if err != nil {
-		return nil, fmt.Errorf("failed to discover tests: %w", err)
+		return nil, err
 	}
 
-	items := make([]*TestItem, len(tests))
-	for i, t := range tests {
-		items[i] = &TestItem{
-			Info:   t,
-			Status: StatusIdle,
+	var runFilter *regexp.Regexp
+	if opts.Run != "" {
+		runFilter, err = regexp.Compile(opts.Run)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -run pattern: %w", err)
 		}
 	}
 
 	// Determine log directory
+	logDir := opts.LogDir
 	if logDir == "" {
 		logDir, err = getDefaultLogDir(testDir)
 		if err != nil {
@@ -128,25 +362,258 @@ func NewModel(testDir string, logDir string, testTimeout time.Duration) (*Model,
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	runner := NewTestRunner(testDir, logDir, 3, testTimeout) // Default parallelism
+	// Files that didn't change since the last session aren't parsed again
+	discoveryCache := runner.LoadDiscoveryCache(filepath.Join(logDir, runner.DiscoveryCacheFile))
+
+	tags := parseTags(opts.Tags)
+	tests, err := discoverTests(testDir, runner.DiscoverOptions{Recursive: true, Signature: signature, Tags: tags, Cache: discoveryCache}, opts.ChangedOnly || opts.ChangedBase != "", opts.ChangedBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover tests: %w", err)
+	}
+	tests = filterTestsByName(tests, runFilter)
+
+	items := make([]*runner.TestItem, len(tests))
+	for i, t := range tests {
+		items[i] = &runner.TestItem{
+			Info:   t,
+			Status: runner.StatusIdle,
+		}
+	}
+
+	// The parallelism and timeout of the previous session are used when they
+	// weren't set explicitly, so they don't need to be tuned again
+	parallel := opts.Parallel
+	var state sessionState
+	if !opts.NoPersist {
+		state, _ = loadState(logDir)
+	}
+	if state.Parallel > 0 && !opts.ParallelSet {
+		parallel = state.Parallel
+	}
+	if parallel <= 0 {
+		parallel = runtime.NumCPU() // Default parallelism
+	}
+	parallel = min(parallel, maxParallelism())
+	timeout := opts.TestTimeout
+	if state.Timeout > 0 && !opts.TimeoutSet {
+		timeout = state.Timeout
+	}
+
+	// Restore the order, selection and results of the previous session
+	items = restoreTests(items, state.Tests)
+
+	testRunner := runner.NewTestRunner(testDir, logDir, parallel, timeout)
+	testRunner.SetTestCommand(opts.TestCommand)
+	testRunner.SetLogTimeFormat(opts.LogTimeFormat)
+	testRunner.SetLogRetention(opts.LogKeep, opts.LogMaxAge)
+	testRunner.SetOutputLines(opts.OutputLines)
+	testRunner.SetRace(opts.Race)
+	testRunner.SetFastFirst(opts.FastFirst)
+	testRunner.SetPackageRuns(opts.PackageRuns)
+	testRunner.SetEnv(opts.Env)
+	testRunner.SetCover(opts.Cover)
+	testRunner.SetRetries(opts.Retries)
+	testRunner.SetTags(tags)
+
+	// Flags on the command line take precedence over the last-used flags
+	if opts.TestFlags != "" {
+		testRunner.SetTestFlags(strings.Fields(opts.TestFlags))
+	} else if state.TestFlags != nil {
+		testRunner.SetTestFlags(strings.Fields(*state.TestFlags))
+	}
+	testRunner.SetTargetPlatform(opts.GOOS, opts.GOARCH)
 
 	m := &Model{
-		tests:        items,
-		filteredList: items,
-		runner:       runner,
-		testDir:      testDir,
-		logDir:       logDir,
-		autoScroll:   true,
-		recursive:    true, // Default to recursive
-		sortMode:     SortByName,
+		tests:            items,
+		filteredList:     items,
+		runner:           testRunner,
+		testDir:          testDir,
+		logDir:           logDir,
+		autoScroll:       true,
+		wrapOutput:       opts.Wrap,
+		recursive:        true, // Default to recursive
+		changedOnly:      opts.ChangedOnly || opts.ChangedBase != "",
+		changedBase:      opts.ChangedBase,
+		runFilter:        runFilter,
+		testSignature:    signature,
+		tags:             tags,
+		discoveryCache:   discoveryCache,
+		editor:           opts.Editor,
+		flakeRuns:        opts.FlakeRuns,
+		confirmThreshold: opts.ConfirmThreshold,
+		followThreshold:  max(opts.FollowThreshold, 0),
+		sortMode:         SortByName,
+		previousPassRate: state.PassRate,
+		noPersist:        opts.NoPersist,
+		watchTests:       opts.Watch,
+		autoRun:          opts.AutoRun,
+		autoRunFailed:    opts.AutoRunFailed,
+		bell:             opts.Bell,
+		notify:           opts.Notify,
+		junitPath:        opts.JUnit,
+		batchSummary:     opts.BatchSummary,
+	}
+
+	if m.flakeRuns <= 0 {
+		m.flakeRuns = defaultFlakeRuns
+	}
+
+	// Hide excluded tests using the filter, so they can still be shown
+	if len(opts.Excludes) > 0 {
+		for _, exclude := range opts.Excludes {
+			m.filterText = strings.TrimSpace(m.filterText + " !" + strings.TrimSpace(exclude))
+		}
 	}
+	m.applyFilter()
 
 	// Set the test list reference on the runner
-	runner.SetTestList(&m.filteredList)
+	testRunner.SetTestList(&m.tests)
+
+	if opts.Watch || opts.AutoRun {
+		watcher, err := newTestWatcher(testDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch %s: %w", testDir, err)
+		}
+		m.watcher = watcher
+	}
 
 	return m, nil
 }
 
+// checkBatchDone reports a batch once all of its tests have finished, using a
+// summary in the status bar, the terminal bell and/or a desktop notification
+func (m *Model) checkBatchDone() tea.Cmd {
+	batch := m.runner.CurrentBatch()
+	if batch == m.reportedBatch {
+		return nil
+	}
+	elapsed, done := m.runner.BatchElapsed()
+	if !done {
+		return nil
+	}
+	m.reportedBatch = batch
+
+	passed, failed, skipped := m.batchCounts(batch)
+	summary := fmt.Sprintf("%d passed, %d failed, %d skipped", passed, failed, skipped)
+	if m.batchSummary {
+		m.setStatusMessage(fmt.Sprintf("done in %s: %s", formatDuration(elapsed), summary))
+	}
+
+	if m.junitPath != "" {
+		m.exportJUnit()
+	}
+
+	var cmds []tea.Cmd
+	if m.bell {
+		cmds = append(cmds, func() tea.Msg {
+			os.Stderr.WriteString("\a")
+			return nil
+		})
+	}
+	if m.notify {
+		title := notificationTitle(m.testDir)
+		body := fmt.Sprintf("%s in %s", summary, formatDuration(elapsed))
+		cmds = append(cmds, func() tea.Msg {
+			sendNotification(title, body)
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// batchCounts returns how many tests of a batch passed, failed and were
+// skipped
+func (m *Model) batchCounts(batch int) (passed, failed, skipped int) {
+	for _, item := range m.tests {
+		if item.Batch != batch {
+			continue
+		}
+		switch item.Status {
+		case runner.StatusPassed:
+			passed++
+		case runner.StatusFailed:
+			failed++
+		case runner.StatusSkipped:
+			skipped++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// shutdown saves the session state and stops all tests, so no test
+// processes are left behind when the application exits
+func (m *Model) shutdown() {
+	if !m.reviewMode {
+		m.saveState()
+	}
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	if m.statusServer != nil {
+		if err := m.statusServer.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: status endpoint stopped: %v\n", err)
+		}
+	}
+	if m.junitPath != "" {
+		writeJUnitReport(m.junitPath, m.runner)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := m.runner.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %d tests were still running after %s\n", m.runner.GetRunningCount(), shutdownTimeout)
+	}
+}
+
+// discoverTests discovers the tests in the test directory. When changedOnly
+// is set, only tests affected by changes according to git are returned (see
+// changedFiles for the base ref). If the directory isn't part of a git
+// repository or the base ref doesn't exist, all tests are returned.
+func discoverTests(testDir string, opts runner.DiscoverOptions, changedOnly bool, changedBase string) ([]runner.TestInfo, error) {
+	tests, err := runner.DiscoverTestsWithOptions(testDir, opts)
+	opts.Cache.Save() // The cache only speeds up discovery, so errors are ignored
+	if err != nil || !changedOnly {
+		return tests, err
+	}
+
+	changes, err := changedFiles(testDir, changedBase)
+	if err != nil {
+		return tests, nil
+	}
+
+	var changed []runner.TestInfo
+	for _, t := range tests {
+		if changes.includes(t, testDir) {
+			changed = append(changed, t)
+		}
+	}
+	return changed, nil
+}
+
+// filterTestsByName returns the tests with a name that matches the pattern,
+// like go test -run does. A nil pattern matches all tests.
+func filterTestsByName(tests []runner.TestInfo, pattern *regexp.Regexp) []runner.TestInfo {
+	if pattern == nil {
+		return tests
+	}
+
+	var matching []runner.TestInfo
+	for _, t := range tests {
+		if pattern.MatchString(t.Name) {
+			matching = append(matching, t)
+		}
+	}
+	return matching
+}
+
+// parseTags splits a list of build tags like go test does, which accepts both
+// commas and spaces as separators
+func parseTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
 // Init initializes the model
 func (m *Model) Init() tea.Cmd {
 	// Set up update callback
@@ -154,9 +621,11 @@ func (m *Model) Init() tea.Cmd {
 		// This is called from goroutines, we'll handle updates via tick
 	})
 
-	return tea.Batch(
-		tickCmd(),
-	)
+	cmds := []tea.Cmd{tickCmd()}
+	if m.watcher != nil {
+		cmds = append(cmds, m.watcher.wait())
+	}
+	return tea.Batch(cmds...)
 }
 
 // tickCmd returns a command that sends tick messages
@@ -178,8 +647,37 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tickMsg:
+		m.refreshStatusFilter()
 		m.refreshOutput()
-		return m, tickCmd()
+		if time.Since(m.lastStaleCheck) >= staleCheckInterval {
+			m.updateStaleResults()
+			if m.showHistory {
+				m.updateHistory()
+			}
+			m.lastStaleCheck = time.Now()
+		}
+		return m, tea.Batch(tickCmd(), m.checkBatchDone())
+
+	case benchDoneMsg:
+		m.handleBenchDone(msg)
+		return m, nil
+
+	case shellDoneMsg:
+		m.handleShellDone(msg)
+		return m, nil
+
+	case coverDoneMsg:
+		m.handleCoverDone(msg)
+		return m, nil
+
+	case filesChangedMsg:
+		if m.watchTests && msg.testFiles {
+			m.rediscoverTests()
+		}
+		if m.autoRun {
+			m.autoRunTests(msg.dirs)
+		}
+		return m, m.watcher.wait()
 
 	case updateMsg:
 		return m, nil
@@ -190,11 +688,32 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKey processes keyboard input
 func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Any key closes the help overlay
+	if m.showHelp {
+		m.showHelp = false
+		return m, nil
+	}
+
+	// Handle confirmation prompt
+	if m.confirmMode {
+		return m.handleConfirmKey(msg)
+	}
+
 	// Handle filter mode input (left pane)
 	if m.filterMode {
 		return m.handleFilterKey(msg)
 	}
 
+	// Handle editing the extra go test flags
+	if m.testFlagsMode {
+		return m.handleTestFlagsKey(msg)
+	}
+
+	// Handle jump-to search input (left pane)
+	if m.jumpMode {
+		return m.handleJumpKey(msg)
+	}
+
 	// Handle search mode input (right pane)
 	if m.searchMode {
 		return m.handleSearchKey(msg)
@@ -206,6 +725,10 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "q", "ctrl+c":
 		return m, tea.Quit
 
+	case "?":
+		m.showHelp = true
+		return m, nil
+
 	case "tab":
 		if m.focusedPane == LeftPane {
 			m.focusedPane = RightPane
@@ -229,76 +752,63 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m *Model) handleLeftPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	// Running tests isn't possible while reviewing logs
+	if m.reviewMode && reviewBlockedKeys[key] {
+		m.setStatusMessage("not available in review mode")
+		return m, nil
+	}
+
 	switch key {
 	case "up", "k":
-		if m.cursor > 0 {
-			m.cursor--
-			m.resetOutputScroll()
-		}
+		m.moveCursor(-1)
 
 	case "down", "j":
-		if m.cursor < len(m.filteredList)-1 {
-			m.cursor++
-			m.resetOutputScroll()
-		}
+		m.moveCursor(1)
 
 	case "home":
-		if m.cursor > 0 {
-			m.cursor = 0
-			m.resetOutputScroll()
-		}
+		m.moveCursor(-len(m.filteredList))
 
 	case "end":
-		if m.cursor < len(m.filteredList)-1 {
-			m.cursor = len(m.filteredList) - 1
-			m.resetOutputScroll()
-		}
+		m.moveCursor(len(m.filteredList))
 
 	case "pgup":
-		pageSize := m.height - 3 // Account for borders and status bar
-		m.cursor -= pageSize
-		if m.cursor < 0 {
-			m.cursor = 0
-		}
-		m.resetOutputScroll()
+		m.moveCursor(-max(m.height-3, 1)) // Account for borders and status bar
 
 	case "pgdown":
-		pageSize := m.height - 3 // Account for borders and status bar
-		m.cursor += pageSize
-		if m.cursor >= len(m.filteredList) {
-			m.cursor = len(m.filteredList) - 1
-		}
-		if m.cursor < 0 {
-			m.cursor = 0
-		}
-		m.resetOutputScroll()
+		m.moveCursor(max(m.height-3, 1)) // Account for borders and status bar
 
 	case "/":
 		m.filterMode = true
 		m.filterText = ""
 
 	case "a":
-		// Select all
-		for _, t := range m.filteredList {
+		// Select all (of the package on a header)
+		for _, t := range m.selectionScope() {
 			t.Selected = true
 		}
 
 	case "d":
-		// Deselect all
-		for _, t := range m.filteredList {
+		// Deselect all (of the package on a header)
+		for _, t := range m.selectionScope() {
 			t.Selected = false
 		}
 
 	case "i":
-		// Invert selection
-		for _, t := range m.filteredList {
+		// Invert selection (of the package on a header)
+		for _, t := range m.selectionScope() {
 			t.Selected = !t.Selected
 		}
 
 	case " ":
-		// Toggle current item selection
-		if len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
-			m.filteredList[m.cursor].Selected = !m.filteredList[m.cursor].Selected
+		// Toggle current item selection, or of all tests of the package on a
+		// header
+		tests := m.cursorTests()
+		selected := true
+		for _, t := range tests {
+			selected = selected && t.Selected
+		}
+		for _, t := range tests {
+			t.Selected = !selected
 		}
 
 	case "g":
@@ -309,6 +819,84 @@ func (m *Model) handleLeftPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Stop selected tests (or current if none selected)
 		m.stopSelectedTests()
 
+	case "^":
+		// Run the current test (or the package on a header) next
+		m.prioritizeCurrent()
+
+	case "R":
+		// Restart selected tests (or current if none selected)
+		for _, t := range m.selectedOrCurrent() {
+			m.runner.RestartTest(t)
+		}
+
+	case "y":
+		// Copy a command that reproduces the current test
+		m.copyReproduceCommand()
+
+	case "z":
+		// Collapse or expand the package on a header, otherwise the subtests
+		// of the current test
+		if m.cursorOnHeader() {
+			m.togglePackage()
+		} else {
+			m.toggleSubtests()
+		}
+
+	case "Z":
+		if m.grouped {
+			m.togglePackage()
+		}
+
+	case "L":
+		// Toggle grouping the tests by package
+		m.toggleGrouped()
+
+	case "J":
+		// Jump to a test by typing part of its name
+		m.startJump()
+
+	case "n":
+		m.jumpToNextMatch(1)
+
+	case "N":
+		m.jumpToNextMatch(-1)
+
+	case "V":
+		// Re-run the selected failed tests (or current) with verbose output
+		m.rerunFailedVerbose()
+
+	case "K":
+		// Run selected tests (or current if none selected) repeatedly to detect flakiness
+		m.detectFlakySelectedTests()
+
+	case "U":
+		// Run the current test repeatedly until it fails
+		m.runCurrentUntilFail()
+
+	case "G":
+		// Run all visible tests
+		m.runVisibleTests()
+
+	case "f":
+		// Cycle through the status filters
+		m.cycleStatusFilter()
+
+	case "T":
+		// Edit the extra go test flags
+		m.startTestFlags()
+
+	case "F":
+		// Re-run all visible failed tests
+		m.runFailedTests()
+
+	case "X":
+		// Stop all visible tests
+		m.stopVisibleTests()
+
+	case "ctrl+x":
+		// Stop all tests, including the ones that are filtered out
+		m.stopAllTests()
+
 	case "s":
 		// Toggle sort mode (capital S to avoid conflict with stop)
 		m.toggleSortMode()
@@ -317,10 +905,22 @@ func (m *Model) handleLeftPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Edit: open IDE at test function
 		m.openInEditor()
 
+	case "!":
+		// Open a shell in the package directory of the current test
+		return m, m.openShell()
+
 	case "r":
 		// Toggle recursive mode
 		m.toggleRecursive()
 
+	case "}":
+		// Jump to the next failed test
+		m.jumpToStatus(runner.StatusFailed, 1)
+
+	case "{":
+		// Jump to the previous failed test
+		m.jumpToStatus(runner.StatusFailed, -1)
+
 	case "[":
 		// Move current item up
 		m.moveItemUp()
@@ -329,14 +929,142 @@ func (m *Model) handleLeftPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Move current item down
 		m.moveItemDown()
 
+	case "b":
+		// Capture a baseline for the current benchmark
+		return m, m.captureBaseline()
+
+	case "B":
+		// Compare the current benchmark with its baseline
+		return m, m.compareWithBaseline()
+
+	case "D":
+		// Toggle doc comments
+		m.showDocs = !m.showDocs
+
+	case "H":
+		// Toggle the reliability indicator
+		m.showHistory = !m.showHistory
+		if m.showHistory {
+			m.updateHistory()
+		}
+
+	case "O":
+		// Toggle showing the combined logs of the selected tests
+		m.combinedOutput = !m.combinedOutput
+		m.refreshOutput()
+
+	case "v":
+		// Toggle the dry run preview
+		m.showPreview = !m.showPreview
+
+	case "P":
+		// Toggle package colors
+		m.showPackageColors = !m.showPackageColors
+
+	case "M":
+		// Toggle the mini-map
+		m.showMiniMap = !m.showMiniMap
+
+	case "A":
+		// Toggle between test duration and time since finished
+		m.showFinishedAgo = !m.showFinishedAgo
+
+	case "E":
+		m.openFailureInEditor()
+
+	case "x":
+		m.exportJUnit()
+
+	case "W":
+		m.exportJSONReport()
+
+	case "m":
+		m.markDiffBaseline()
+
+	case "u":
+		m.toggleRunDiff()
+
+	case "p":
+		// Peek at the failure (or the end) of the output without switching focus
+		m.peekOutput()
+
+	case ")":
+		// Increase the timeout of tests that are started from now on
+		m.runner.SetTestTimeout(nextTimeout(m.runner.GetTestTimeout(), 1))
+
+	case "(":
+		// Decrease the timeout of tests that are started from now on
+		m.runner.SetTestTimeout(nextTimeout(m.runner.GetTestTimeout(), -1))
+
+	case ">":
+		// Retry failed runs once more
+		m.runner.SetRetries(m.runner.GetRetries() + 1)
+
+	case "<":
+		// Retry failed runs once less
+		m.runner.SetRetries(m.runner.GetRetries() - 1)
+
+	case "C":
+		// Toggle coverage collection for tests that are started from now on
+		m.runner.SetCover(!m.runner.IsCover())
+		if m.runner.IsCover() {
+			m.setStatusMessage("coverage enabled for new runs")
+		} else {
+			m.setStatusMessage("coverage disabled for new runs")
+		}
+
+	case "c":
+		// Open the HTML coverage report of the current test
+		return m, m.openCoverReport()
+
+	case "o":
+		// Toggle sequential ordered execution
+		m.runner.SetSequential(!m.runner.IsSequential())
+
+	case "Q":
+		// Toggle starting the fastest queued tests first
+		m.runner.SetFastFirst(!m.runner.IsFastFirst())
+		if m.runner.IsFastFirst() {
+			m.setStatusMessage("queued tests with the shortest last run start first")
+		} else {
+			m.setStatusMessage("queued tests start in the order they were queued")
+		}
+
+	case "I":
+		// Toggle running the queued tests of a package together
+		m.runner.SetPackageRuns(!m.runner.IsPackageRuns())
+		if m.runner.IsPackageRuns() {
+			m.setStatusMessage("queued tests of a package run together in a single go test")
+		} else {
+			m.setStatusMessage("each test runs in its own go test")
+		}
+
+	case "w":
+		return m, m.toggleAutoRun()
+
+	case "ctrl+r":
+		// Toggle the race detector for tests that are started from now on
+		m.runner.SetRace(!m.runner.IsRace())
+		if m.runner.IsRace() {
+			m.setStatusMessage("race detector enabled for new runs")
+		} else {
+			m.setStatusMessage("race detector disabled for new runs")
+		}
+
 	case "+", "=":
 		// Increase parallelism
+		if m.runner.GetMaxParallel() >= maxParallelism() {
+			m.setStatusMessage(fmt.Sprintf("parallelism is limited to %d", maxParallelism()))
+			break
+		}
 		m.runner.SetMaxParallel(m.runner.GetMaxParallel() + 1)
+		m.saveState()
 
 	case "-", "_":
 		// Decrease parallelism
 		if m.runner.GetMaxParallel() > 1 {
 			m.runner.SetMaxParallel(m.runner.GetMaxParallel() - 1)
+			m.saveState()
 		}
 	}
 
@@ -359,17 +1087,22 @@ func (m *Model) handleRightPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.outputScroll < maxScroll {
 			m.outputScroll++
 		}
-		if m.outputScroll >= maxScroll {
-			m.autoScroll = true
-		}
+		m.updateFollow()
 
 	case "left", "h":
-		if m.horizontalScroll > 0 {
-			m.horizontalScroll--
-		}
+		m.scrollHorizontal(-1)
 
 	case "right", "l":
-		m.horizontalScroll++
+		m.scrollHorizontal(1)
+
+	case "shift+left":
+		m.scrollHorizontal(-m.outputWidth() / 2)
+
+	case "shift+right":
+		m.scrollHorizontal(m.outputWidth() / 2)
+
+	case "w":
+		m.toggleWrap()
 
 	case "pgup":
 		m.outputScroll -= m.outputHeight()
@@ -383,9 +1116,7 @@ func (m *Model) handleRightPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.outputScroll > maxScroll {
 			m.outputScroll = maxScroll
 		}
-		if m.outputScroll >= maxScroll {
-			m.autoScroll = true
-		}
+		m.updateFollow()
 
 	case "home":
 		m.outputScroll = 0
@@ -395,9 +1126,17 @@ func (m *Model) handleRightPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.outputScroll = maxScroll
 		m.autoScroll = true
 
-	case "/":
-		// Start search mode
+	case "F":
+		// Jump to the bottom and follow the output, also when scrolled sideways
+		m.outputScroll = maxScroll
+		m.horizontalScroll = 0
+		m.autoScroll = true
+
+	case "/", "\\":
+		// Start search mode, using a regular expression for '\'
 		m.searchMode = true
+		m.searchRegex = key == "\\"
+		m.searchErr = nil
 		m.searchText = ""
 		m.searchMatches = nil
 		m.currentMatchIdx = 0
@@ -409,6 +1148,20 @@ func (m *Model) handleRightPaneKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "N":
 		// Go to previous search match
 		m.goToPrevMatch()
+
+	case "c":
+		// Toggle diff colorization
+		m.colorizeDiffs = !m.colorizeDiffs
+		m.outputBuffer, m.combinedSources = nil, nil // Classify the lines again
+		m.refreshOutput()
+
+	case "y":
+		// Copy the output to the clipboard
+		m.copyOutput(false)
+
+	case "Y":
+		// Copy only the lines matching the search to the clipboard
+		m.copyOutput(true)
 	}
 
 	return m, nil
@@ -425,7 +1178,7 @@ func (m *Model) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "backspace":
 		if len(m.filterText) > 0 {
-			m.filterText = m.filterText[:len(m.filterText)-1]
+			m.filterText = trimLastRune(m.filterText)
 			m.applyFilter()
 		}
 
@@ -439,22 +1192,69 @@ func (m *Model) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// handleSearchKey handles keys in search mode (right pane)
-func (m *Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	key := msg.String()
+// handleConfirmKey handles keys while a confirmation prompt is shown
+func (m *Model) handleConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		if m.confirmAction != nil {
+			m.confirmAction()
+		}
+		m.clearConfirm()
 
-	switch key {
-	case "enter":
-		m.searchMode = false
-		m.performSearch()
-		m.goToNextMatch()
+	case "n", "N", "esc", "q":
+		m.clearConfirm()
+	}
 
-	case "esc":
+	return m, nil
+}
+
+// confirm asks for confirmation before executing the action
+func (m *Model) confirm(prompt string, action func()) {
+	m.confirmMode = true
+	m.confirmPrompt = prompt
+	m.confirmAction = action
+}
+
+// setStatusMessage shows a transient message in the status bar
+func (m *Model) setStatusMessage(msg string) {
+	m.statusMessage = msg
+	m.statusMessageAt = time.Now()
+}
+
+// confirmLarge executes the action right away, unless it affects more tests
+// than the confirmation threshold. In that case it asks for confirmation first.
+// A threshold of zero never asks.
+func (m *Model) confirmLarge(count int, prompt string, action func()) {
+	if m.confirmThreshold > 0 && count > m.confirmThreshold {
+		m.confirm(prompt, action)
+		return
+	}
+	action()
+}
+
+// clearConfirm resets the confirmation state
+func (m *Model) clearConfirm() {
+	m.confirmMode = false
+	m.confirmPrompt = ""
+	m.confirmAction = nil
+}
+
+// handleSearchKey handles keys in search mode (right pane)
+func (m *Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	switch key {
+	case "enter":
+		m.searchMode = false
+		m.performSearch()
+		m.goToNextMatch()
+
+	case "esc":
 		m.searchMode = false
 
 	case "backspace":
 		if len(m.searchText) > 0 {
-			m.searchText = m.searchText[:len(m.searchText)-1]
+			m.searchText = trimLastRune(m.searchText)
 		}
 
 	default:
@@ -466,23 +1266,38 @@ func (m *Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// performSearch searches for text in output lines (case insensitive)
+// performSearch searches for text in output lines (case insensitive), or
+// for a regular expression in regex mode
 func (m *Model) performSearch() {
 	m.searchMatches = nil
 	m.currentMatchIdx = -1
+	m.searchErr = nil
 
 	if m.searchText == "" {
 		return
 	}
 
-	searchLower := strings.ToLower(m.searchText)
+	re, err := m.searchPattern()
+	if err != nil {
+		m.searchErr = err
+		return
+	}
 	for i, line := range m.outputLines {
-		if strings.Contains(strings.ToLower(line), searchLower) {
+		if re.MatchString(line) {
 			m.searchMatches = append(m.searchMatches, i)
 		}
 	}
 }
 
+// searchPattern returns the regular expression for the search text. Plain
+// searches match the text literally, ignoring case.
+func (m *Model) searchPattern() (*regexp.Regexp, error) {
+	if m.searchRegex {
+		return regexp.Compile(m.searchText)
+	}
+	return regexp.Compile("(?i)" + regexp.QuoteMeta(m.searchText))
+}
+
 // goToNextMatch scrolls to the next search match
 func (m *Model) goToNextMatch() {
 	if len(m.searchMatches) == 0 {
@@ -495,7 +1310,7 @@ func (m *Model) goToNextMatch() {
 	}
 
 	// Scroll to the match
-	m.outputScroll = m.searchMatches[m.currentMatchIdx]
+	m.outputScroll = m.lineRow(m.searchMatches[m.currentMatchIdx])
 	m.autoScroll = false
 }
 
@@ -511,23 +1326,43 @@ func (m *Model) goToPrevMatch() {
 	}
 
 	// Scroll to the match
-	m.outputScroll = m.searchMatches[m.currentMatchIdx]
+	m.outputScroll = m.lineRow(m.searchMatches[m.currentMatchIdx])
 	m.autoScroll = false
 }
 
 // applyFilter filters the test list based on filter text
 func (m *Model) applyFilter() {
-	if m.filterText == "" {
+	m.hasSubtests = make(map[string]bool)
+	listed := make(map[string]bool) // Top-level tests by parent key
+	for _, t := range m.tests {
+		if t.Info.IsSubtest() {
+			m.hasSubtests[parentKey(t.Info)] = true
+		} else {
+			listed[parentKey(t.Info)] = true
+		}
+	}
+
+	filtering := m.filterText != "" || m.statusFilter != StatusFilterAll
+	if !filtering && len(m.hasSubtests) == 0 && !m.grouped {
 		m.filteredList = m.tests
 	} else {
 		m.filteredList = nil
-		filter := strings.ToLower(m.filterText)
 		for _, t := range m.tests {
-			if strings.Contains(strings.ToLower(t.Info.Name), filter) {
+			// Subtests of collapsed parents are hidden, unless filtering. In
+			// review mode, a subtest may have logs without its parent, so
+			// it's shown on its own.
+			key := parentKey(t.Info)
+			if !filtering && t.Info.IsSubtest() && listed[key] && !m.expanded[key] {
+				continue
+			}
+			if matchesFilter(t.Info, m.filterText) && m.statusFilter.matches(t.Status) {
 				m.filteredList = append(m.filteredList, t)
 			}
 		}
 	}
+	if m.grouped {
+		groupByPackage(m.filteredList)
+	}
 
 	// Adjust cursor if needed
 	if m.cursor >= len(m.filteredList) {
@@ -536,21 +1371,246 @@ func (m *Model) applyFilter() {
 	if m.cursor < 0 {
 		m.cursor = 0
 	}
+	m.updateSequence()
+}
+
+// updateSequence passes the order of the shown tests to the runner, so
+// sequential mode runs them in the order the user sees
+func (m *Model) updateSequence() {
+	m.runner.SetSequence(m.filteredList)
+}
+
+// cycleStatusFilter switches to the next status filter
+func (m *Model) cycleStatusFilter() {
+	m.statusFilter = (m.statusFilter + 1) % statusFilterCount
+	m.applyFilter()
+	m.resetOutputScroll()
+}
+
+// refreshStatusFilter applies the status filter again, as the status of the
+// tests changes while they run. The cursor stays on the current test if it
+// still matches.
+func (m *Model) refreshStatusFilter() {
+	if m.statusFilter == StatusFilterAll {
+		return
+	}
+
+	// Only rebuild the list when a test started or stopped matching
+	changed := false
+	for _, t := range m.filteredList {
+		if !m.statusFilter.matches(t.Status) || !matchesFilter(t.Info, m.filterText) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		matching := 0
+		for _, t := range m.tests {
+			if m.statusFilter.matches(t.Status) && matchesFilter(t.Info, m.filterText) {
+				matching++
+			}
+		}
+		changed = matching != len(m.filteredList)
+	}
+	if !changed {
+		return
+	}
+
+	var current *runner.TestItem
+	if m.cursor < len(m.filteredList) {
+		current = m.filteredList[m.cursor]
+	}
+	m.applyFilter()
+	for i, t := range m.filteredList {
+		if t == current {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// parentKey returns the key of the top-level test of a test, which is used
+// to collapse and expand subtests
+func parentKey(info runner.TestInfo) string {
+	return info.Package + "/" + info.Parent()
+}
+
+// toggleSubtests collapses or expands the subtests of the current test (or
+// of its parent when the current test is a subtest)
+func (m *Model) toggleSubtests() {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return
+	}
+
+	key := parentKey(m.filteredList[m.cursor].Info)
+	if !m.hasSubtests[key] {
+		return
+	}
+	if m.expanded == nil {
+		m.expanded = make(map[string]bool)
+	}
+	m.expanded[key] = !m.expanded[key]
+	m.applyFilter()
+
+	// Keep the cursor on the parent
+	for i, t := range m.filteredList {
+		if !t.Info.IsSubtest() && parentKey(t.Info) == key {
+			m.cursor = i
+			break
+		}
+	}
+	m.resetOutputScroll()
+}
+
+// matchesFilter checks if a test matches the filter (case insensitive). The
+// filter consists of space-separated terms; the name must contain all plain
+// terms and none of the terms prefixed with '!'. Terms prefixed with "pkg:"
+// match the package of the test instead of its name.
+func matchesFilter(info runner.TestInfo, filter string) bool {
+	name := strings.ToLower(info.Name)
+	pkg := strings.ToLower(info.Package)
+	for _, term := range strings.Fields(strings.ToLower(filter)) {
+		exclude := false
+		if t, ok := strings.CutPrefix(term, "!"); ok {
+			exclude = true
+			term = t
+		}
+
+		target := name
+		if t, ok := strings.CutPrefix(term, "pkg:"); ok {
+			target = pkg
+			term = t
+		}
+
+		// A lone '!' or "pkg:" doesn't filter anything
+		if term == "" {
+			continue
+		}
+		if strings.Contains(target, term) == exclude {
+			return false
+		}
+	}
+	return true
 }
 
 // runSelectedTests queues selected tests for execution
 func (m *Model) runSelectedTests() {
-	hasSelected := false
-	for _, t := range m.filteredList {
-		if t.Selected {
-			hasSelected = true
+	items := m.selectedOrCurrent()
+	m.confirmLarge(len(items), fmt.Sprintf("Run %d selected tests?", len(items)), func() {
+		for _, t := range items {
 			m.runner.QueueTest(t)
 		}
+	})
+}
+
+// detectFlakySelectedTests runs the selected tests (or current if none
+// selected) several times to detect flaky tests
+func (m *Model) detectFlakySelectedTests() {
+	items := m.selectedOrCurrent()
+	prompt := fmt.Sprintf("Run %d selected tests %d times?", len(items), m.flakeRuns)
+	m.confirmLarge(len(items)*m.flakeRuns, prompt, func() {
+		for _, t := range items {
+			m.runner.QueueRepeated(t, m.flakeRuns)
+		}
+	})
+}
+
+// runCurrentUntilFail runs the current test again after each run, until a run
+// fails or the test is stopped
+func (m *Model) runCurrentUntilFail() {
+	if m.cursor >= len(m.filteredList) || m.cursorOnHeader() {
+		return
+	}
+	item := m.filteredList[m.cursor]
+	if item.Status == runner.StatusQueued || item.Status == runner.StatusRunning {
+		m.setStatusMessage(fmt.Sprintf("%s is still running", item.Info.Name))
+		return
+	}
+	m.runner.QueueUntilFail(item)
+	m.setStatusMessage(fmt.Sprintf("running %s until it fails (t to stop)", item.Info.Name))
+}
+
+// rerunFailedVerbose re-runs the failed tests among the selected tests (or
+// the current test) once with verbose output
+func (m *Model) rerunFailedVerbose() {
+	queued := 0
+	for _, t := range m.selectedOrCurrent() {
+		if t.Status == runner.StatusFailed {
+			m.runner.QueueVerbose(t)
+			queued++
+		}
+	}
+
+	if queued == 0 {
+		m.setStatusMessage("no failed tests to re-run")
+	}
+}
+
+// copyReproduceCommand copies a command that runs the current test from the
+// module root to the clipboard
+func (m *Model) copyReproduceCommand() {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return
+	}
+
+	cmd := m.runner.ReproduceCommand(m.filteredList[m.cursor].Info)
+	if err := copyToClipboard(cmd); err != nil {
+		m.setStatusMessage(fmt.Sprintf("copy failed: %v", err))
+		return
+	}
+	m.setStatusMessage("copied: " + cmd)
+}
+
+// copyOutput copies the output lines to the clipboard. When matchesOnly is
+// set, only the lines matching the search are copied.
+func (m *Model) copyOutput(matchesOnly bool) {
+	lines := m.outputLines
+	if matchesOnly {
+		if m.searchText == "" {
+			m.setStatusMessage("no search to copy the matches of (press /)")
+			return
+		}
+		lines = make([]string, 0, len(m.searchMatches))
+		for _, idx := range m.searchMatches {
+			if idx < len(m.outputLines) {
+				lines = append(lines, m.outputLines[idx])
+			}
+		}
 	}
+	if len(lines) == 0 {
+		m.setStatusMessage("no output to copy")
+		return
+	}
+
+	if err := copyToClipboard(strings.Join(lines, "\n") + "\n"); err != nil {
+		m.setStatusMessage(fmt.Sprintf("copy failed: %v", err))
+		return
+	}
+	m.setStatusMessage(fmt.Sprintf("copied %s lines", formatCount(len(lines))))
+}
 
-	// If none selected, run current item
-	if !hasSelected && len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
-		m.runner.QueueTest(m.filteredList[m.cursor])
+// selectedOrCurrent returns the selected tests, or the current test if none
+// are selected
+func (m *Model) selectedOrCurrent() []*runner.TestItem {
+	items := m.selectedTests()
+	if len(items) == 0 {
+		items = m.cursorTests()
+	}
+	return items
+}
+
+// prioritizeCurrent moves the current test (or the tests of the package on a
+// header) to the front of the queue
+func (m *Model) prioritizeCurrent() {
+	items := m.cursorTests()
+	if len(items) == 0 {
+		return
+	}
+	m.runner.PrioritizeTests(items)
+	if len(items) == 1 {
+		m.setStatusMessage(items[0].Info.Name + " runs next")
+	} else {
+		m.setStatusMessage(fmt.Sprintf("%d tests run next", len(items)))
 	}
 }
 
@@ -564,29 +1624,223 @@ func (m *Model) stopSelectedTests() {
 		}
 	}
 
-	// If none selected, stop current item
-	if !hasSelected && len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
-		m.runner.StopTest(m.filteredList[m.cursor])
+	// If none selected, stop current item (or the package on a header)
+	if !hasSelected {
+		for _, t := range m.cursorTests() {
+			m.runner.StopTest(t)
+		}
+	}
+}
+
+// runVisibleTests queues all tests in the filtered list, regardless of selection
+func (m *Model) runVisibleTests() {
+	items := append([]*runner.TestItem(nil), m.filteredList...)
+	run := func() {
+		for _, t := range items {
+			m.runner.QueueTest(t)
+		}
+	}
+
+	m.confirmLarge(len(items), fmt.Sprintf("Run all %d visible tests?", len(items)), run)
+}
+
+// toggleAutoRun toggles re-queuing the tests of packages with changed files.
+// The watcher is started when it isn't running yet.
+func (m *Model) toggleAutoRun() tea.Cmd {
+	m.autoRun = !m.autoRun
+	if !m.autoRun {
+		m.setStatusMessage("auto-run disabled")
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if m.watcher == nil {
+		watcher, err := newTestWatcher(m.testDir)
+		if err != nil {
+			m.autoRun = false
+			m.setStatusMessage(fmt.Sprintf("cannot watch %s: %v", m.testDir, err))
+			return nil
+		}
+		m.watcher = watcher
+		cmd = watcher.wait()
+	}
+	m.setStatusMessage("auto-run enabled: saving a file re-runs the tests of its package")
+	return cmd
+}
+
+// autoRunTests queues the tests in the packages of the changed directories
+// and, when enabled, the failing tests. Tests that are still queued or
+// running aren't queued again.
+func (m *Model) autoRunTests(dirs []string) {
+	packages := make(map[string]bool)
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(m.testDir, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+		packages[rel] = true
+	}
+
+	queued := 0
+	for _, item := range m.tests {
+		status, _ := item.LogState()
+		if status == runner.StatusQueued || status == runner.StatusRunning {
+			continue
+		}
+
+		// Subtests run as part of their parent and benchmarks are only run
+		// on request
+		affected := packages[item.Info.Package] && item.Info.Kind == runner.KindTest && !item.Info.IsSubtest()
+		failing := m.autoRunFailed && status == runner.StatusFailed
+		if affected || failing {
+			m.runner.QueueTest(item)
+			queued++
+		}
+	}
+
+	if queued > 0 {
+		m.setStatusMessage(fmt.Sprintf("auto-run: queued %d tests", queued))
+	}
+}
+
+// runFailedTests queues all failed tests in the filtered list, regardless of
+// selection, so a filter can narrow down which failures are re-run
+func (m *Model) runFailedTests() {
+	var items []*runner.TestItem
+	for _, t := range m.filteredList {
+		if t.Status == runner.StatusFailed {
+			items = append(items, t)
+		}
+	}
+	if len(items) == 0 {
+		m.setStatusMessage("no failed tests to re-run")
+		return
+	}
+
+	run := func() {
+		for _, t := range items {
+			m.runner.QueueTest(t)
+		}
+	}
+
+	m.confirmLarge(len(items), fmt.Sprintf("Re-run all %d visible failed tests?", len(items)), run)
+}
+
+// stopVisibleTests stops all tests in the filtered list, regardless of selection
+func (m *Model) stopVisibleTests() {
+	items := append([]*runner.TestItem(nil), m.filteredList...)
+	stop := func() {
+		for _, t := range items {
+			m.runner.StopTest(t)
+		}
+	}
+
+	m.confirmLarge(len(items), fmt.Sprintf("Stop all %d visible tests?", len(items)), stop)
+}
+
+// stopAllTests stops all running and queued tests, without asking for
+// confirmation, as it's meant for stopping an accidentally queued suite
+func (m *Model) stopAllTests() {
+	queued, running := m.runner.StopEverything()
+	if queued == 0 && running == 0 {
+		m.setStatusMessage("no tests are queued or running")
+		return
+	}
+	m.setStatusMessage(fmt.Sprintf("stopped %d running and removed %d queued tests", running, queued))
+}
+
+// jumpToStatus moves the cursor to the next (dir = 1) or previous (dir = -1)
+// test with the given status, wrapping around at the end of the list
+func (m *Model) jumpToStatus(status runner.TestStatus, dir int) {
+	n := len(m.filteredList)
+	for i := 1; i <= n; i++ {
+		idx := ((m.cursor+dir*i)%n + n) % n
+		if m.filteredList[idx].Status == status {
+			if idx != m.cursor {
+				m.cursor = idx
+				m.resetOutputScroll()
+			}
+			return
+		}
 	}
 }
 
 // moveItemUp moves the current item up in the list
 func (m *Model) moveItemUp() {
-	if m.cursor <= 0 || len(m.filteredList) == 0 {
+	if m.cursor <= 0 || len(m.filteredList) == 0 || !m.canMoveItem(m.cursor-1) {
 		return
 	}
 
-	m.filteredList[m.cursor], m.filteredList[m.cursor-1] = m.filteredList[m.cursor-1], m.filteredList[m.cursor]
-	m.cursor--
+	m.moveItem(m.cursor - 1)
 }
 
 // moveItemDown moves the current item down in the list
 func (m *Model) moveItemDown() {
-	if m.cursor >= len(m.filteredList)-1 || len(m.filteredList) == 0 {
+	if m.cursor >= len(m.filteredList)-1 || len(m.filteredList) == 0 || !m.canMoveItem(m.cursor+1) {
 		return
 	}
-	m.filteredList[m.cursor], m.filteredList[m.cursor+1] = m.filteredList[m.cursor+1], m.filteredList[m.cursor]
-	m.cursor++
+	m.moveItem(m.cursor + 1)
+}
+
+// moveItem swaps the current item with the item at the given index and moves
+// the cursor along with it
+func (m *Model) moveItem(other int) {
+	m.moveInTests(m.filteredList[m.cursor], m.filteredList[other], other < m.cursor)
+	// Without a filter the filtered list is the same slice as the list of all
+	// tests, so the move already swapped both items
+	if &m.filteredList[0] != &m.tests[0] {
+		m.filteredList[m.cursor], m.filteredList[other] = m.filteredList[other], m.filteredList[m.cursor]
+	}
+	m.cursor = other
+	m.updateSequence()
+}
+
+// moveInTests moves an item in the list of all tests, so it ends up directly
+// before or after its neighbour in the filtered list. That's the order that is
+// saved, as the filtered list only holds the visible tests. Subtests move
+// along with their parent.
+func (m *Model) moveInTests(item, neighbour *runner.TestItem, before bool) {
+	var block, rest []*runner.TestItem
+	for _, t := range m.tests {
+		if t == item || isDescendant(t, item) {
+			block = append(block, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+
+	at := slices.Index(rest, neighbour)
+	if at < 0 {
+		return
+	}
+	if !before {
+		at++
+		for at < len(rest) && isDescendant(rest[at], neighbour) {
+			at++
+		}
+	}
+
+	// Copy the new order into the existing slice, as the filtered list may be
+	// the same slice
+	copy(m.tests, slices.Concat(rest[:at], block, rest[at:]))
+}
+
+// isDescendant reports whether a test is a subtest of another test
+func isDescendant(t, parent *runner.TestItem) bool {
+	return t.Info.Package == parent.Info.Package && strings.HasPrefix(t.Info.Name, parent.Info.Name+"/")
+}
+
+// canMoveItem returns whether the current item can swap places with the item
+// at the given index. In the grouped view, tests stay within their package.
+func (m *Model) canMoveItem(other int) bool {
+	if !m.grouped {
+		return true
+	}
+	return !m.cursorOnHeader() && m.filteredList[other].Info.Package == m.filteredList[m.cursor].Info.Package
 }
 
 // toggleSortMode cycles through sort modes
@@ -595,10 +1849,20 @@ func (m *Model) toggleSortMode() {
 	m.applySorting()
 }
 
+// statusOrder is the order of the statuses when sorting by status
+var statusOrder = map[runner.TestStatus]int{
+	runner.StatusFailed:  0,
+	runner.StatusSkipped: 1,
+	runner.StatusPassed:  2,
+	runner.StatusRunning: 3,
+	runner.StatusQueued:  4,
+	runner.StatusIdle:    5,
+}
+
 // applySorting sorts the filtered list based on current sort mode
 func (m *Model) applySorting() {
 	// Remember current item
-	var currentItem *TestItem
+	var currentItem *runner.TestItem
 	if len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
 		currentItem = m.filteredList[m.cursor]
 	}
@@ -618,12 +1882,16 @@ func (m *Model) applySorting() {
 	case SortByStatus:
 		sort.Slice(m.filteredList, func(i, j int) bool {
 			if m.filteredList[i].Status != m.filteredList[j].Status {
-				return m.filteredList[i].Status > m.filteredList[j].Status
+				return statusOrder[m.filteredList[i].Status] < statusOrder[m.filteredList[j].Status]
 			}
 			return m.filteredList[i].Info.Name < m.filteredList[j].Info.Name
 		})
 	}
 
+	if m.grouped {
+		groupByPackage(m.filteredList)
+	}
+
 	// Restore cursor position to current item
 	if currentItem != nil {
 		for i, item := range m.filteredList {
@@ -633,6 +1901,7 @@ func (m *Model) applySorting() {
 			}
 		}
 	}
+	m.updateSequence()
 }
 
 // toggleRecursive toggles recursive test discovery and refreshes the list
@@ -643,87 +1912,141 @@ func (m *Model) toggleRecursive() {
 
 // rediscoverTests re-runs test discovery with current settings
 func (m *Model) rediscoverTests() {
-	tests, err := DiscoverTestsRecursive(m.testDir, m.recursive)
+	opts := runner.DiscoverOptions{Recursive: m.recursive, Signature: m.testSignature, Tags: m.tags, Cache: m.discoveryCache}
+	tests, err := discoverTests(m.testDir, opts, m.changedOnly, m.changedBase)
 	if err != nil {
 		return
 	}
+	tests = filterTestsByName(tests, m.runFilter)
 
-	items := make([]*TestItem, len(tests))
-	for i, t := range tests {
-		items[i] = &TestItem{
-			Info:   t,
-			Status: StatusIdle,
+	// Tests that still exist keep their item, so their status, selection
+	// and position in the list are retained
+	discovered := make(map[string]runner.TestInfo, len(tests))
+	for _, t := range tests {
+		discovered[t.Package+"/"+t.Name] = t
+	}
+
+	items := make([]*runner.TestItem, 0, len(tests))
+	infos := make(map[*runner.TestItem]runner.TestInfo, len(m.tests))
+	for _, item := range m.tests {
+		key := item.Info.Package + "/" + item.Info.Name
+		info, ok := discovered[key]
+		if !ok {
+			continue
+		}
+		delete(discovered, key)
+		infos[item] = info
+		items = append(items, item)
+	}
+	for _, t := range tests {
+		if _, ok := discovered[t.Package+"/"+t.Name]; ok {
+			items = append(items, &runner.TestItem{
+				Info:   t,
+				Status: runner.StatusIdle,
+			})
 		}
 	}
 
-	m.tests = items
+	// The runner reads the list and the info of the tests while starting
+	// tests, so it swaps them under its lock. The list is m.tests.
+	m.runner.UpdateTests(items, infos)
 	m.applyFilter()
 	m.applySorting()
 }
 
-// openInEditor opens the current test in the IDE
-func (m *Model) openInEditor() {
-	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+// updateStaleResults marks finished tests whose package sources were
+// modified after the test finished
+func (m *Model) updateStaleResults() {
+	if m.reviewMode {
 		return
 	}
 
-	item := m.filteredList[m.cursor]
-	file := item.Info.File
-	line := item.Info.Line
-
-	// Try common editors in order of preference
-	// Format: editor +line file
-	editors := []struct {
-		cmd  string
-		args []string
-	}{
-		{"code", []string{"--goto", fmt.Sprintf("%s:%d", file, line)}},
-		{"cursor", []string{"--goto", fmt.Sprintf("%s:%d", file, line)}},
-		{"vim", []string{fmt.Sprintf("+%d", line), file}},
-		{"nvim", []string{fmt.Sprintf("+%d", line), file}},
-		{"nano", []string{fmt.Sprintf("+%d", line), file}},
-	}
-
-	// Check EDITOR environment variable first
-	if editor := os.Getenv("EDITOR"); editor != "" {
-		cmd := exec.Command(editor, fmt.Sprintf("+%d", line), file)
-		cmd.Start()
-		return
-	}
-
-	// Try each editor
-	for _, e := range editors {
-		if _, err := exec.LookPath(e.cmd); err == nil {
-			cmd := exec.Command(e.cmd, e.args...)
-			cmd.Start()
-			return
+	modTimes := make(map[string]time.Time)
+	for _, item := range m.tests {
+		if status, _ := item.LogState(); !status.Finished() {
+			continue
 		}
+
+		dir := filepath.Dir(item.Info.File)
+		modTime, ok := modTimes[dir]
+		if !ok {
+			modTime = latestSourceModTime(dir)
+			modTimes[dir] = modTime
+		}
+		item.SetStale(modTime)
 	}
 }
 
-// findMostRecentLogFile finds the most recent log file for a test in the log directory
-func (m *Model) findMostRecentLogFile(testName string) (string, time.Time) {
-	pattern := filepath.Join(m.logDir, testName+".*.log")
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
-		return "", time.Time{}
-	}
+// latestSourceModTime returns the most recent modification time of the Go
+// source files in a directory
+func latestSourceModTime(dir string) time.Time {
+	var latest time.Time
 
-	var mostRecent string
-	var mostRecentTime time.Time
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return latest
+	}
 
-	for _, match := range matches {
-		info, err := os.Stat(match)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		if info.ModTime().After(mostRecentTime) {
-			mostRecentTime = info.ModTime()
-			mostRecent = match
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
 		}
 	}
 
-	return mostRecent, mostRecentTime
+	return latest
+}
+
+// openInEditor opens the current test in the IDE
+func (m *Model) openInEditor() {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return
+	}
+
+	item := m.filteredList[m.cursor]
+	if err := openEditor(m.editor, item.Info.File, item.Info.Line); err != nil {
+		m.setStatusMessage(fmt.Sprintf("cannot open editor: %v (use -editor)", err))
+	}
+}
+
+// openFailureInEditor opens the first source location referenced in the
+// output of the current test in the editor. Repeated presses cycle through
+// the other locations.
+func (m *Model) openFailureInEditor() {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return
+	}
+
+	item := m.filteredList[m.cursor]
+	root, err := filepath.Abs(m.testDir)
+	if err != nil {
+		return
+	}
+	locations := failureLocations(m.outputLines, filepath.Join(root, filepath.FromSlash(item.Info.Package)), root)
+	if len(locations) == 0 {
+		m.setStatusMessage("no source locations in the output (press e to open the test)")
+		return
+	}
+
+	if m.failureLogFile == m.currentLogFile {
+		m.failureLocation = (m.failureLocation + 1) % len(locations)
+	} else {
+		m.failureLogFile = m.currentLogFile
+		m.failureLocation = 0
+	}
+	location := locations[m.failureLocation]
+
+	if err := openEditor(m.editor, location.file, location.line); err != nil {
+		m.setStatusMessage(fmt.Sprintf("cannot open editor: %v (use -editor)", err))
+		return
+	}
+	m.setStatusMessage(fmt.Sprintf("opened %s:%d (%d/%d)", filepath.Base(location.file), location.line, m.failureLocation+1, len(locations)))
 }
 
 // refreshOutput reloads the output file content
@@ -735,15 +2058,46 @@ func (m *Model) refreshOutput() {
 		return
 	}
 
+	// Show the combined logs of the selected tests in combined mode
+	if m.combinedOutput {
+		if items := m.selectedTests(); len(items) > 0 {
+			m.refreshCombinedOutput(items)
+			return
+		}
+	}
+
+	m.combinedSources = nil
 	item := m.filteredList[m.cursor]
-	logFile := item.LogFile
+	if m.runDiff {
+		m.refreshRunDiff(item)
+		return
+	}
+	m.refreshTestOutput(item)
+	m.jumpToBuildError(item)
+}
+
+// refreshTestOutput loads the output of the last run of a test
+func (m *Model) refreshTestOutput(item *runner.TestItem) {
+	status, logFile := item.LogState()
 	var logTimestamp time.Time
+	historical := false
 
 	// If test hasn't run yet, try to find most recent log file
 	if logFile == "" {
-		logFile, logTimestamp = m.findMostRecentLogFile(item.Info.Name)
+		logFile, logTimestamp = runner.FindMostRecentLogFile(m.logDir, item.Info)
+		historical = true
 	}
 
+	// A different log (e.g. when a test is re-queued while its previous log
+	// is shown) is tailed from the start again
+	if logFile != m.currentLogFile {
+		m.autoScroll = true
+		m.horizontalScroll = 0
+		m.searchMatches = nil
+		m.currentMatchIdx = -1
+	}
+	m.viewingHistorical = historical
+
 	if logFile == "" {
 		m.outputLines = nil
 		m.currentLogFile = ""
@@ -751,6 +2105,39 @@ func (m *Model) refreshOutput() {
 		return
 	}
 
+	// The output of a run that started in this session is streamed to
+	// memory, so the log file only needs to be read for older runs
+	if output := item.Output(); output != nil && !historical {
+		unchanged := logFile == m.currentLogFile && output == m.outputBuffer && output.Version() == m.outputVersion && item.Bench == m.outputBench
+		m.currentLogFile = logFile
+		m.currentLogTimestamp = time.Time{}
+		m.currentLogSize = output.Size()
+		if unchanged {
+			return
+		}
+		m.outputVersion = output.Version()
+		lines := output.Lines()
+		if dropped := output.Dropped(); dropped > 0 {
+			lines = append([]string{fmt.Sprintf("... %d earlier lines are only in the log file %s", dropped, logFile)}, lines...)
+		}
+		m.setOutputLines(withBenchResult(item, lines))
+		m.outputBuffer = output
+		m.outputBench = item.Bench
+		return
+	}
+
+	// A queued test doesn't have a log file until it starts running
+	if status == runner.StatusQueued {
+		if _, err := os.Stat(logFile); os.IsNotExist(err) {
+			m.outputLines = nil
+			m.currentLogFile = logFile
+			m.currentLogTimestamp = time.Time{}
+			m.currentLogSize = 0
+			m.outputScroll = 0
+			return
+		}
+	}
+
 	file, err := os.Open(logFile)
 	if err != nil {
 		m.outputLines = nil
@@ -761,28 +2148,237 @@ func (m *Model) refreshOutput() {
 	defer file.Close()
 
 	// Get file mod time if we don't have a timestamp yet
-	if logTimestamp.IsZero() {
-		if info, err := file.Stat(); err == nil {
+	var logSize int64
+	if info, err := file.Stat(); err == nil {
+		if logTimestamp.IsZero() {
 			logTimestamp = info.ModTime()
 		}
+		logSize = info.Size()
 	}
 
 	m.currentLogFile = logFile
 	m.currentLogTimestamp = logTimestamp
+	m.currentLogSize = logSize
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	m.setOutputLines(withBenchResult(item, readLines(file)))
+}
+
+// withBenchResult puts the result of a benchmark above its output. Without a
+// result of the current run, it's parsed from the output.
+func withBenchResult(item *runner.TestItem, lines []string) []string {
+	if item.Info.Kind != runner.KindBenchmark {
+		return lines
+	}
+	bench := item.Bench
+	if bench == nil {
+		result, ok := runner.ParseBenchResult(lines, item.Info.Name)
+		if !ok {
+			return lines
+		}
+		bench = &result
 	}
+	summary := fmt.Sprintf("📊 %s (%d iterations)", bench, bench.Iterations)
+	return append([]string{summary, ""}, lines...)
+}
 
+// setOutputLines replaces the output lines and scrolls to the end when
+// auto-scroll is enabled
+func (m *Model) setOutputLines(lines []string) {
 	m.outputLines = lines
+	m.outputBuffer = nil
+	m.wrapRows = nil
+	m.diffKinds = nil
+	if m.colorizeDiffs {
+		m.diffKinds = classifyDiffLines(lines)
+	}
 
 	if m.autoScroll {
 		m.outputScroll = m.maxOutputScroll()
 	}
 }
 
+// readLines reads all lines from a reader. Unlike bufio.Scanner, there is no
+// limit on the length of a line, so tests that log large payloads without
+// newlines don't lose any output.
+func readLines(r io.Reader) []string {
+	var lines []string
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil || line != "" {
+			line = strings.TrimSuffix(line, "\n")
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+		}
+		if err != nil {
+			return lines
+		}
+	}
+}
+
+// combinedSource is the output of a test in the combined output, used to
+// detect whether the combined output changed
+type combinedSource struct {
+	status  runner.TestStatus
+	logFile string
+	output  *runner.OutputBuffer // In-memory output (nil when read from disk)
+	version uint64               // Version of the in-memory output
+}
+
+// combinedLog is a log that was read from disk for the combined output
+type combinedLog struct {
+	lines []string
+	size  int64
+}
+
+// refreshCombinedOutput shows the logs of multiple tests one after another,
+// each preceded by a separator with the test name. The output of runs in
+// this session comes from memory, and only older logs are read from disk,
+// once while they're shown.
+func (m *Model) refreshCombinedOutput(items []*runner.TestItem) {
+	sources := make([]combinedSource, len(items))
+	logFiles := make([]string, len(items))
+	for i, item := range items {
+		status, logFile := item.LogState()
+		source := combinedSource{status: status}
+		if output := item.Output(); output != nil && logFile != "" {
+			source.output, source.version = output, output.Version()
+		} else if logFile == "" {
+			logFile, _ = runner.FindMostRecentLogFile(m.logDir, item.Info)
+		}
+		source.logFile = logFile
+		sources[i], logFiles[i] = source, logFile
+	}
+
+	// Nothing needs to be rebuilt when none of the outputs changed
+	combined := strings.Join(logFiles, "\n")
+	if combined == m.currentLogFile && slices.Equal(sources, m.combinedSources) {
+		return
+	}
+
+	var lines []string
+	var size int64
+	logs := make(map[string]combinedLog)
+	for i, item := range items {
+		source := sources[i]
+		name := item.Info.Name
+		if item.Info.Package != "" {
+			name = item.Info.Package + "/" + name
+		}
+		lines = append(lines, fmt.Sprintf("═══ %s (%s) ═══", name, source.status))
+
+		if source.output != nil {
+			if dropped := source.output.Dropped(); dropped > 0 {
+				lines = append(lines, fmt.Sprintf("... %d earlier lines are only in the log file %s", dropped, source.logFile))
+			}
+			lines = append(lines, source.output.Lines()...)
+			lines = append(lines, "")
+			size += source.output.Size()
+			continue
+		}
+
+		log, ok := m.combinedLogs[source.logFile]
+		if !ok {
+			data, err := os.ReadFile(source.logFile)
+			if err != nil {
+				lines = append(lines, "(no output)", "")
+				continue
+			}
+			log = combinedLog{lines: readLines(bytes.NewReader(data)), size: int64(len(data))}
+		}
+		logs[source.logFile] = log
+		lines = append(lines, log.lines...)
+		lines = append(lines, "")
+		size += log.size
+	}
+
+	// A different set of logs is tailed from the start again
+	if combined != m.currentLogFile {
+		m.autoScroll = true
+		m.horizontalScroll = 0
+		m.searchMatches = nil
+		m.currentMatchIdx = -1
+	}
+
+	m.currentLogFile = combined
+	m.currentLogTimestamp = time.Time{}
+	m.currentLogSize = size
+	m.viewingHistorical = false
+	m.setOutputLines(lines)
+	m.combinedSources = sources
+	m.combinedLogs = logs
+}
+
+// selectedTests returns the selected tests in the list
+func (m *Model) selectedTests() []*runner.TestItem {
+	var items []*runner.TestItem
+	for _, t := range m.filteredList {
+		if t.Selected {
+			items = append(items, t)
+		}
+	}
+	return items
+}
+
+// peekOutput scrolls the output to the first failure, or to the end when
+// there is no failure, without changing the focused pane
+func (m *Model) peekOutput() {
+	if idx := findFailureLine(m.outputLines); idx >= 0 {
+		// Center the failure, as the error details are usually logged before it
+		m.outputScroll = min(max(m.lineRow(idx)-m.outputHeight()/2, 0), m.maxOutputScroll())
+		m.autoScroll = false
+		return
+	}
+
+	m.outputScroll = m.maxOutputScroll()
+	m.autoScroll = true
+}
+
+// jumpToBuildError scrolls the output of a test whose package didn't build
+// to the first compiler error. It only scrolls once for each log, so the
+// output can be scrolled away from it.
+func (m *Model) jumpToBuildError(item *runner.TestItem) {
+	if m.currentLogFile == "" || m.currentLogFile == m.buildErrorLog || !item.IsBuildFailed() {
+		return
+	}
+	m.buildErrorLog = m.currentLogFile
+	if idx := findCompilerError(m.outputLines); idx >= 0 {
+		// Keep the line naming the package above the error in view
+		m.outputScroll = min(m.lineRow(max(idx-1, 0)), m.maxOutputScroll())
+		m.autoScroll = false
+	}
+}
+
+// findCompilerError returns the index of the first line with a compiler error
+// (a line starting with a source location) or -1 if there is none
+func findCompilerError(lines []string) int {
+	for i, line := range lines {
+		if loc := sourceLocationRegexp.FindStringIndex(line); loc != nil && loc[0] == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// findFailureLine returns the index of the first line reporting a failure or
+// -1 if there is none
+func findFailureLine(lines []string) int {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--- FAIL") || strings.HasPrefix(trimmed, "panic:") {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateFollow re-enables auto-scroll when the output is scrolled to within
+// the follow threshold of the bottom
+func (m *Model) updateFollow() {
+	if m.outputScroll >= m.maxOutputScroll()-m.followThreshold {
+		m.autoScroll = true
+	}
+}
+
 // resetOutputScroll resets output scroll when changing selection
 func (m *Model) resetOutputScroll() {
 	m.autoScroll = true
@@ -792,12 +2388,38 @@ func (m *Model) resetOutputScroll() {
 
 // outputHeight returns the height available for output
 func (m *Model) outputHeight() int {
-	return m.height - 3 // Account for borders and status bar
+	return max(m.height-3, 1) // Account for borders and status bar
+}
+
+// maxHorizontalScroll returns the number of columns the output can be scrolled
+// sideways, so the end of the longest visible line is at the edge of the pane
+func (m *Model) maxHorizontalScroll() int {
+	// Wrapped lines don't extend beyond the pane
+	if m.wrapOutput {
+		return 0
+	}
+	longest := 0
+	for i := m.outputScroll; i < min(m.outputScroll+m.outputHeight(), len(m.outputLines)); i++ {
+		longest = max(longest, runewidth.StringWidth(m.outputLines[i]))
+	}
+	return max(longest-m.outputWidth(), 0)
+}
+
+// scrollHorizontal scrolls the output sideways by a number of columns
+func (m *Model) scrollHorizontal(columns int) {
+	m.horizontalScroll = max(min(m.horizontalScroll+columns, m.maxHorizontalScroll()), 0)
+}
+
+// maxParallelism returns the highest number of tests that can run in
+// parallel, which guards against running far more builds than the machine can
+// handle
+func maxParallelism() int {
+	return max(4*runtime.NumCPU(), 16)
 }
 
 // maxOutputScroll returns the maximum scroll position
 func (m *Model) maxOutputScroll() int {
-	max := len(m.outputLines) - m.outputHeight()
+	max := m.outputRowCount() - m.outputHeight()
 	if max < 0 {
 		return 0
 	}
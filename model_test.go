@@ -0,0 +1,659 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"TestLogin", "", true},
+		{"TestLogin", "login", true},
+		{"TestLogin", "logout", false},
+		{"TestLoginIntegration", "!integration", false},
+		{"TestLogin", "!integration", true},
+		{"TestLoginIntegration", "login !slow", true},
+		{"TestLoginSlow", "login !slow", false},
+		{"TestLogin", "!", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchesFilter(runner.TestInfo{Name: tt.name}, tt.filter); got != tt.expected {
+			t.Errorf("matchesFilter(%q, %q) = %v, expected %v", tt.name, tt.filter, got, tt.expected)
+		}
+	}
+}
+
+func TestQueueWhileViewingPreviousLog(t *testing.T) {
+	logDir := t.TempDir()
+	info := runner.TestInfo{Name: "TestFoo"}
+
+	// Log of a previous session
+	oldLog := filepath.Join(logDir, runner.LogFilePrefix(info)+".20240101-120000.000.log")
+	if err := os.WriteFile(oldLog, []byte("old output\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &runner.TestItem{Info: info}
+	tests := []*runner.TestItem{item}
+
+	// Without parallelism the queued test never starts
+	r := runner.NewTestRunner(".", logDir, 0, 0)
+	r.SetTestList(&tests)
+
+	m := &Model{
+		tests:        tests,
+		filteredList: tests,
+		runner:       r,
+		logDir:       logDir,
+		autoScroll:   true,
+		height:       20,
+	}
+
+	m.refreshOutput()
+	if m.currentLogFile != oldLog || !m.viewingHistorical {
+		t.Fatalf("Expected to view the previous log %s, got %s", oldLog, m.currentLogFile)
+	}
+
+	// Scroll away, then queue the test
+	m.autoScroll = false
+	r.QueueTest(item)
+	m.refreshOutput()
+
+	_, newLog := item.LogState()
+	if m.currentLogFile != newLog || m.viewingHistorical {
+		t.Fatalf("Expected to view the new log %s, got %s", newLog, m.currentLogFile)
+	}
+	if len(m.outputLines) != 0 {
+		t.Errorf("Expected no output while queued, got %v", m.outputLines)
+	}
+	if !m.autoScroll {
+		t.Error("Expected auto-scroll to be enabled for the new run")
+	}
+
+	// Output of the new run is tailed once it's written
+	if err := os.WriteFile(newLog, []byte("new output\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.refreshOutput()
+	if len(m.outputLines) != 1 || m.outputLines[0] != "new output" {
+		t.Errorf("Expected the output of the new run, got %v", m.outputLines)
+	}
+}
+
+func TestMatchesFilterPackage(t *testing.T) {
+	tests := []struct {
+		pkg      string
+		filter   string
+		expected bool
+	}{
+		{"internal/foo", "pkg:internal/foo", true},
+		{"internal/foo", "pkg:Internal", true},
+		{"internal/bar", "pkg:internal/foo", false},
+		{"internal/foo", "!pkg:foo", false},
+		{"internal/bar", "!pkg:foo", true},
+		{"internal/foo", "pkg:foo login", true},
+		{"internal/foo", "pkg:foo logout", false},
+		{"", "pkg:", true},
+		{"", "pkg:foo", false},
+	}
+
+	for _, tt := range tests {
+		info := runner.TestInfo{Name: "TestLogin", Package: tt.pkg}
+		if got := matchesFilter(info, tt.filter); got != tt.expected {
+			t.Errorf("matchesFilter(%q, %q) = %v, expected %v", tt.pkg, tt.filter, got, tt.expected)
+		}
+	}
+}
+
+func TestJumpToTest(t *testing.T) {
+	var tests []*runner.TestItem
+	for _, name := range []string{"TestLogin", "TestLogout", "TestSignup", "TestLoginSlow"} {
+		tests = append(tests, &runner.TestItem{Info: runner.TestInfo{Name: name}})
+	}
+
+	m := &Model{
+		tests:        tests,
+		filteredList: tests,
+		runner:       runner.NewTestRunner(".", t.TempDir(), 1, 0),
+		logDir:       t.TempDir(),
+		cursor:       1,
+	}
+
+	m.startJump()
+	for _, key := range "login" {
+		m.handleJumpKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+	}
+	if m.cursor != 3 {
+		t.Fatalf("Expected the first match after the cursor, got %d", m.cursor)
+	}
+
+	m.handleJumpKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m.jumpToNextMatch(1)
+	if m.cursor != 0 {
+		t.Errorf("Expected the search to wrap around, got %d", m.cursor)
+	}
+	if len(m.filteredList) != len(tests) {
+		t.Error("Expected no tests to be hidden")
+	}
+}
+
+func TestReadLinesLongLine(t *testing.T) {
+	long := strings.Repeat("x", 5*1024*1024)
+	lines := readLines(strings.NewReader("first\r\n" + long + "\nlast"))
+
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if lines[0] != "first" || lines[2] != "last" {
+		t.Errorf("Expected the surrounding lines to be kept, got %q and %q", lines[0], lines[2])
+	}
+	if len(lines[1]) != len(long) {
+		t.Errorf("Expected the long line to be kept in full, got %d bytes", len(lines[1]))
+	}
+}
+
+func TestRunFailedTests(t *testing.T) {
+	passed := &runner.TestItem{Info: runner.TestInfo{Name: "TestPassed"}, Status: runner.StatusPassed}
+	failed := &runner.TestItem{Info: runner.TestInfo{Name: "TestFailed"}, Status: runner.StatusFailed}
+	hidden := &runner.TestItem{Info: runner.TestInfo{Name: "TestHidden"}, Status: runner.StatusFailed}
+	tests := []*runner.TestItem{passed, failed, hidden}
+
+	testRunner := runner.NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	m := &Model{
+		tests:            tests,
+		filteredList:     tests[:2],
+		runner:           testRunner,
+		logDir:           t.TempDir(),
+		confirmThreshold: defaultConfirmThreshold,
+	}
+	testRunner.SetTestList(&m.tests)
+
+	m.runFailedTests()
+	if failed.Status != runner.StatusQueued {
+		t.Errorf("Expected the failed test to be queued, got %s", failed.Status)
+	}
+	if passed.Status != runner.StatusPassed || hidden.Status != runner.StatusFailed {
+		t.Errorf("Expected only visible failed tests to be queued, got %s and %s", passed.Status, hidden.Status)
+	}
+
+	m.filteredList = tests[:1]
+	m.runFailedTests()
+	if m.statusMessage != "no failed tests to re-run" {
+		t.Errorf("Expected a status message without failed tests, got %q", m.statusMessage)
+	}
+}
+
+func TestStatusFilter(t *testing.T) {
+	passed := &runner.TestItem{Info: runner.TestInfo{Name: "TestPassed"}, Status: runner.StatusPassed}
+	failed := &runner.TestItem{Info: runner.TestInfo{Name: "TestFailed"}, Status: runner.StatusFailed}
+	other := &runner.TestItem{Info: runner.TestInfo{Name: "TestOther"}, Status: runner.StatusPassed}
+	tests := []*runner.TestItem{passed, failed, other}
+
+	m := &Model{
+		tests:        tests,
+		filteredList: tests,
+		runner:       runner.NewTestRunner(".", t.TempDir(), 1, 0),
+		logDir:       t.TempDir(),
+		cursor:       2,
+	}
+
+	// Cycle to the failed filter: running, passed, failed
+	for range 3 {
+		m.cycleStatusFilter()
+	}
+	if m.statusFilter != StatusFilterFailed {
+		t.Fatalf("Expected the failed filter, got %s", m.statusFilter)
+	}
+	if len(m.filteredList) != 1 || m.filteredList[0] != failed {
+		t.Fatalf("Expected only the failed test, got %d tests", len(m.filteredList))
+	}
+	if m.cursor != 0 {
+		t.Errorf("Expected the cursor to be clamped, got %d", m.cursor)
+	}
+
+	// Tests that start failing are shown, combined with the filter text
+	m.filterText = "Other"
+	other.Status = runner.StatusFailed
+	m.refreshStatusFilter()
+	if len(m.filteredList) != 1 || m.filteredList[0] != other {
+		t.Errorf("Expected only the other failed test, got %d tests", len(m.filteredList))
+	}
+
+	// Cycling back to all shows all matching tests again
+	m.filterText = ""
+	m.cycleStatusFilter()
+	m.cycleStatusFilter()
+	if m.statusFilter != StatusFilterAll || len(m.filteredList) != 3 {
+		t.Errorf("Expected all tests, got %d tests with the %s filter", len(m.filteredList), m.statusFilter)
+	}
+}
+
+func TestRestoreTests(t *testing.T) {
+	var items []*runner.TestItem
+	for _, name := range []string{"TestA", "TestB", "TestNew"} {
+		items = append(items, &runner.TestItem{Info: runner.TestInfo{Name: name, Package: "pkg"}})
+	}
+
+	finishedAt := time.Now().Add(-time.Hour)
+	saved := []savedTest{
+		{Package: "pkg", Name: "TestB", Selected: true, Status: "failed", FinishedAt: finishedAt, Duration: time.Second},
+		{Package: "pkg", Name: "TestRemoved"},
+		{Package: "pkg", Name: "TestA", Status: "running", Duration: 2 * time.Second},
+	}
+
+	restored := restoreTests(items, saved)
+	var names []string
+	for _, item := range restored {
+		names = append(names, item.Info.Name)
+	}
+	if strings.Join(names, ",") != "TestB,TestA,TestNew" {
+		t.Fatalf("Expected the saved order with new tests appended, got %v", names)
+	}
+
+	b := restored[0]
+	if !b.Selected || b.Status != runner.StatusFailed || !b.FinishedAt.Equal(finishedAt) || b.Duration() != time.Second {
+		t.Errorf("Expected TestB to be restored, got selected=%v status=%s duration=%s", b.Selected, b.Status, b.Duration())
+	}
+	if a := restored[1]; a.Selected || a.Status != runner.StatusIdle {
+		t.Errorf("Expected TestA to be idle and not selected, got selected=%v status=%s", a.Selected, a.Status)
+	}
+	if a := restored[1]; a.LastDuration != 2*time.Second {
+		t.Errorf("Expected the last duration of TestA to be restored, got %s", a.LastDuration)
+	}
+}
+
+func TestCopyOutputWithoutSearch(t *testing.T) {
+	m := &Model{outputLines: []string{"line"}}
+
+	m.copyOutput(true)
+	if m.statusMessage != "no search to copy the matches of (press /)" {
+		t.Errorf("Expected a status message without a search, got %q", m.statusMessage)
+	}
+}
+
+func TestPerformSearch(t *testing.T) {
+	m := &Model{outputLines: []string{"=== RUN   TestFoo", "panic: boom", "--- FAIL: TestFoo", "PANIC in handler"}}
+
+	// Plain searches are case insensitive and match literally
+	m.searchText = "panic"
+	m.performSearch()
+	if len(m.searchMatches) != 2 {
+		t.Errorf("Expected 2 matches, got %v", m.searchMatches)
+	}
+
+	m.searchRegex = true
+	m.searchText = "^panic:|FAIL"
+	m.performSearch()
+	if len(m.searchMatches) != 2 || m.searchMatches[0] != 1 || m.searchMatches[1] != 2 {
+		t.Errorf("Expected matches on lines 1 and 2, got %v", m.searchMatches)
+	}
+
+	m.searchText = "("
+	m.performSearch()
+	if m.searchErr == nil || len(m.searchMatches) != 0 {
+		t.Errorf("Expected an error for an invalid pattern, got %v", m.searchMatches)
+	}
+}
+
+func TestCheckBatchDone(t *testing.T) {
+	passed := &runner.TestItem{Info: runner.TestInfo{Name: "TestPassed"}}
+	tests := []*runner.TestItem{passed}
+
+	testRunner := runner.NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	testRunner.SetTestCommand("true")
+	m := &Model{tests: tests, filteredList: tests, runner: testRunner, batchSummary: true}
+	testRunner.SetTestList(&m.tests)
+
+	testRunner.QueueTest(passed)
+	if !testRunner.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	m.checkBatchDone()
+	if !strings.HasPrefix(m.statusMessage, "done in ") || !strings.HasSuffix(m.statusMessage, ": 1 passed, 0 failed, 0 skipped") {
+		t.Errorf("Unexpected summary %q", m.statusMessage)
+	}
+
+	// A batch is only reported once
+	m.statusMessage = ""
+	m.checkBatchDone()
+	if m.statusMessage != "" {
+		t.Errorf("Expected the batch to be reported once, got %q", m.statusMessage)
+	}
+}
+
+func TestFilterTestsByName(t *testing.T) {
+	tests := []runner.TestInfo{{Name: "TestParse"}, {Name: "TestParse/empty"}, {Name: "TestFormat"}, {Name: "BenchmarkParse"}}
+
+	if got := filterTestsByName(tests, nil); len(got) != len(tests) {
+		t.Errorf("Expected all tests without a pattern, got %v", got)
+	}
+
+	got := filterTestsByName(tests, regexp.MustCompile("^TestParse"))
+	if len(got) != 2 || got[0].Name != "TestParse" || got[1].Name != "TestParse/empty" {
+		t.Errorf("Expected TestParse and its subtest, got %v", got)
+	}
+}
+
+func TestInitialParallelism(t *testing.T) {
+	logDir := t.TempDir()
+	if err := saveState(logDir, sessionState{Parallel: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected int
+	}{
+		{"default", Options{NoPersist: true}, runtime.NumCPU()},
+		{"configured", Options{Parallel: 5, NoPersist: true}, 5},
+		{"clamped", Options{Parallel: 10000, NoPersist: true}, maxParallelism()},
+		{"saved state", Options{Parallel: 5}, 2},
+		{"set over saved state", Options{Parallel: 5, ParallelSet: true}, 5},
+	}
+
+	for _, tt := range tests {
+		tt.opts.LogDir = logDir
+		m, err := NewModel(t.TempDir(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.runner.GetMaxParallel(); got != tt.expected {
+			t.Errorf("%s: expected parallelism %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestInitialTimeout(t *testing.T) {
+	logDir := t.TempDir()
+	if err := saveState(logDir, sessionState{Timeout: 5 * time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected time.Duration
+	}{
+		{"default", Options{NoPersist: true}, runner.DefaultTestTimeout},
+		{"configured", Options{TestTimeout: time.Hour, NoPersist: true}, time.Hour},
+		{"saved state", Options{TestTimeout: time.Hour}, 5 * time.Minute},
+		{"set over saved state", Options{TestTimeout: time.Hour, TimeoutSet: true}, time.Hour},
+	}
+
+	for _, tt := range tests {
+		tt.opts.LogDir = logDir
+		m, err := NewModel(t.TempDir(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.runner.GetTestTimeout(); got != tt.expected {
+			t.Errorf("%s: expected timeout %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestConfirmLarge(t *testing.T) {
+	var tests []*runner.TestItem
+	for _, name := range []string{"TestA", "TestB", "TestC"} {
+		tests = append(tests, &runner.TestItem{Info: runner.TestInfo{Name: name}})
+	}
+
+	// Without parallelism, queued tests stay queued
+	testRunner := runner.NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	m := &Model{
+		tests:            tests,
+		filteredList:     tests,
+		runner:           testRunner,
+		confirmThreshold: 2,
+	}
+	testRunner.SetTestList(&m.tests)
+	queued := func() int { return testRunner.GetQueuedCount() }
+
+	// Above the threshold, only the prompt is shown
+	m.runVisibleTests()
+	if !m.confirmMode || m.confirmPrompt != "Run all 3 visible tests?" || queued() != 0 {
+		t.Fatalf("Expected a confirmation prompt without queued tests, got prompt %q and %d queued tests", m.confirmPrompt, queued())
+	}
+
+	// Declining discards the pending action
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune{'n'}}, {Type: tea.KeyEsc}} {
+		m.runVisibleTests()
+		m.handleConfirmKey(key)
+		if m.confirmMode || m.confirmAction != nil || queued() != 0 {
+			t.Fatalf("Expected %s to discard the action, got %d queued tests", key, queued())
+		}
+	}
+
+	// Confirming queues the tests
+	m.runVisibleTests()
+	m.handleConfirmKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if m.confirmMode || queued() != 3 {
+		t.Fatalf("Expected the tests to be queued after confirming, got %d queued tests", queued())
+	}
+	testRunner.StopAll(tests)
+
+	// At the threshold, and with a threshold of zero, it never asks
+	m.filteredList = tests[:2]
+	m.runVisibleTests()
+	if m.confirmMode || queued() != 2 {
+		t.Fatalf("Expected the tests to be queued without confirmation, got %d queued tests", queued())
+	}
+	testRunner.StopAll(tests)
+
+	m.filteredList = tests
+	m.confirmThreshold = 0
+	m.runVisibleTests()
+	if m.confirmMode || queued() != 3 {
+		t.Errorf("Expected the tests to be queued without confirmation, got %d queued tests", queued())
+	}
+	testRunner.StopAll(tests)
+}
+
+func TestJumpToBuildError(t *testing.T) {
+	logDir := t.TempDir()
+	lines := []string{"Warning: output before the build", "", "# pkg [pkg.test]", "./a_test.go:3:28: declared and not used: x"}
+	for range 40 {
+		lines = append(lines, "./a_test.go:4:1: too many errors")
+	}
+	lines = append(lines, "FAIL\tpkg [build failed]", "FAIL")
+	logFile := filepath.Join(logDir, "TestFoo.log")
+	if err := os.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &runner.TestItem{Info: runner.TestInfo{Name: "TestFoo"}, Status: runner.StatusFailed, BuildFailed: true, LogFile: logFile}
+	tests := []*runner.TestItem{item}
+	r := runner.NewTestRunner(".", logDir, 0, 0)
+	r.SetTestList(&tests)
+
+	m := &Model{
+		tests:        tests,
+		filteredList: tests,
+		runner:       r,
+		logDir:       logDir,
+		autoScroll:   true,
+		height:       10,
+	}
+
+	// The package line above the first compiler error is at the top
+	m.refreshOutput()
+	if m.outputScroll != 2 || m.autoScroll {
+		t.Errorf("Expected to scroll to the first compiler error, got scroll %d (auto-scroll %v)", m.outputScroll, m.autoScroll)
+	}
+
+	// Scrolling away isn't undone when the output is refreshed
+	m.outputScroll = 10
+	m.refreshOutput()
+	if m.outputScroll != 10 {
+		t.Errorf("Expected the scroll position to be kept, got %d", m.outputScroll)
+	}
+}
+
+func TestMoveItemSavesOrder(t *testing.T) {
+	m, err := NewModel("pkg/runner/testdata", Options{NoPersist: true, LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.hasSubtests) == 0 {
+		t.Fatal("Expected tests with subtests")
+	}
+
+	// Move a parent with hidden subtests up
+	m.cursor = slices.IndexFunc(m.filteredList, func(item *runner.TestItem) bool {
+		return item.Info.Package == "" && item.Info.Name == "TestTable"
+	})
+	if m.cursor <= 0 {
+		t.Fatalf("Expected TestTable below another test, got index %d", m.cursor)
+	}
+	neighbour := m.filteredList[m.cursor-1].Info
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	if m.filteredList[m.cursor].Info.Name != "TestTable" {
+		t.Fatalf("Expected the cursor to stay on TestTable, got %s", m.filteredList[m.cursor].Info.Name)
+	}
+
+	// The saved order has the parent and its subtests before the neighbour
+	var names []string
+	for _, test := range m.savedTests() {
+		if test.Package == "" {
+			names = append(names, test.Name)
+		}
+	}
+	table := slices.Index(names, "TestTable")
+	other := slices.Index(names, neighbour.Name)
+	if table < 0 || other < 0 || table > other {
+		t.Fatalf("Expected TestTable before %s in the saved order, got %v", neighbour.Name, names)
+	}
+	for _, name := range names[table+1 : other] {
+		if !strings.HasPrefix(name, "TestTable/") {
+			t.Errorf("Expected only subtests of TestTable between it and %s, got %v", neighbour.Name, names)
+			break
+		}
+	}
+}
+
+func TestMoveItemFlatList(t *testing.T) {
+	dir := t.TempDir()
+	src := "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\nfunc TestB(t *testing.T) {}\nfunc TestC(t *testing.T) {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "a_test.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel(dir, Options{NoPersist: true, LogDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.filteredList) != 3 || &m.filteredList[0] != &m.tests[0] {
+		t.Fatal("Expected the filtered list to be the list of all tests")
+	}
+
+	names := func() []string {
+		var names []string
+		for _, test := range m.savedTests() {
+			names = append(names, test.Name)
+		}
+		return names
+	}
+
+	m.cursor = 2
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	if got := names(); !slices.Equal(got, []string{"TestA", "TestC", "TestB"}) {
+		t.Errorf("Expected TestC moved up, got %v", got)
+	}
+	if m.cursor != 1 || m.filteredList[m.cursor].Info.Name != "TestC" {
+		t.Errorf("Expected the cursor on TestC at 1, got %d", m.cursor)
+	}
+
+	m.cursor = 0
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{']'}})
+	if got := names(); !slices.Equal(got, []string{"TestC", "TestA", "TestB"}) {
+		t.Errorf("Expected TestA moved down, got %v", got)
+	}
+	if m.cursor != 1 || m.filteredList[m.cursor].Info.Name != "TestA" {
+		t.Errorf("Expected the cursor on TestA at 1, got %d", m.cursor)
+	}
+}
+
+func TestUpdateStaleResults(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a_test.go")
+	if err := os.WriteFile(file, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	before := &runner.TestItem{Info: runner.TestInfo{Name: "TestBefore", File: file}, Status: runner.StatusPassed, FinishedAt: modified.Add(-time.Second)}
+	after := &runner.TestItem{Info: runner.TestInfo{Name: "TestAfter", File: file}, Status: runner.StatusFailed, FinishedAt: modified.Add(time.Second)}
+	idle := &runner.TestItem{Info: runner.TestInfo{Name: "TestIdle", File: file}, Status: runner.StatusIdle}
+	m := &Model{tests: []*runner.TestItem{before, after, idle}}
+
+	m.updateStaleResults()
+	if !before.Stale {
+		t.Error("Expected a test that finished before the change to be stale")
+	}
+	if after.Stale || idle.Stale {
+		t.Errorf("Expected only finished tests older than the change to be stale, got %v and %v", after.Stale, idle.Stale)
+	}
+}
+
+func TestRefreshCombinedOutput(t *testing.T) {
+	logDir := t.TempDir()
+	old := &runner.TestItem{Info: runner.TestInfo{Name: "TestOld"}, Selected: true}
+	oldLog := filepath.Join(logDir, "TestOld.20240101-120000.000.log")
+	if err := os.WriteFile(oldLog, []byte("old output\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	current := &runner.TestItem{Info: runner.TestInfo{Name: "TestCurrent"}, Selected: true}
+	tests := []*runner.TestItem{old, current}
+
+	r := runner.NewTestRunner(t.TempDir(), logDir, 1, 0)
+	r.SetTestCommand("echo current output")
+	r.SetTestList(&tests)
+	r.QueueTest(current)
+	if !r.WaitIdle(10 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	m := &Model{
+		tests:          tests,
+		filteredList:   tests,
+		runner:         r,
+		logDir:         logDir,
+		combinedOutput: true,
+	}
+	m.refreshOutput()
+	output := strings.Join(m.outputLines, "\n")
+	if !strings.Contains(output, "old output") || !strings.Contains(output, "current output") {
+		t.Fatalf("Expected the output of both tests, got:\n%s", output)
+	}
+
+	// The output of the current run comes from memory, and the historical
+	// log is kept, so it isn't read again
+	if _, ok := m.combinedLogs[oldLog]; !ok || len(m.combinedLogs) != 1 {
+		t.Errorf("Expected only the historical log to be read from disk, got %d logs", len(m.combinedLogs))
+	}
+	os.Remove(current.LogFile)
+	current.Output().Write([]byte("more output\n"))
+	m.refreshOutput()
+	output = strings.Join(m.outputLines, "\n")
+	if !strings.Contains(output, "old output") || !strings.Contains(output, "more output") {
+		t.Errorf("Expected the output from memory, got:\n%s", output)
+	}
+}
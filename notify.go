@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// notificationCommand returns the command that shows a desktop notification
+// on the given OS, or nil when the notifier isn't installed
+func notificationCommand(goos, title, body string, lookPath func(string) (string, error)) []string {
+	var args []string
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		args = []string{"osascript", "-e", script}
+	case "windows":
+		args = []string{"toast", "--title", title, "--message", body}
+	default:
+		args = []string{"notify-send", "--app-name=test-runner", title, body}
+	}
+	if _, err := lookPath(args[0]); err != nil {
+		return nil
+	}
+	return args
+}
+
+// appleScriptString quotes a string for use in AppleScript
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// notificationTitle returns the title of the notification, which includes
+// the name of the test directory to tell multiple instances apart
+func notificationTitle(testDir string) string {
+	if abs, err := filepath.Abs(testDir); err == nil {
+		testDir = abs
+	}
+	return "test-runner: " + filepath.Base(testDir)
+}
+
+// sendNotification shows a desktop notification. Errors are ignored, because
+// a missing notifier must not interrupt the test runner.
+func sendNotification(title, body string) {
+	args := notificationCommand(runtime.GOOS, title, body, exec.LookPath)
+	if args == nil {
+		return
+	}
+	_ = exec.Command(args[0], args[1:]...).Run()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNotificationCommand(t *testing.T) {
+	found := func(name string) (string, error) { return "/usr/bin/" + name, nil }
+	missing := func(name string) (string, error) { return "", errors.New("not found") }
+
+	got := notificationCommand("linux", "test-runner: foo", "3 passed, 1 failed", found)
+	expected := []string{"notify-send", "--app-name=test-runner", "test-runner: foo", "3 passed, 1 failed"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("notificationCommand(linux) = %q, expected %q", got, expected)
+	}
+
+	got = notificationCommand("darwin", `say "hi"`, "3 passed", found)
+	expected = []string{"osascript", "-e", `display notification "3 passed" with title "say \"hi\""`}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("notificationCommand(darwin) = %q, expected %q", got, expected)
+	}
+
+	if got := notificationCommand("linux", "title", "body", missing); got != nil {
+		t.Errorf("notificationCommand() without notifier = %q, expected nil", got)
+	}
+}
+
+func TestNotificationTitle(t *testing.T) {
+	if got := notificationTitle("/src/my-project"); got != "test-runner: my-project" {
+		t.Errorf("notificationTitle() = %q", got)
+	}
+}
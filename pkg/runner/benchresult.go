@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// benchResultRegexp matches a benchmark result line, e.g.
+// "BenchmarkFoo-8   1000000   1234 ns/op   56 B/op   2 allocs/op"
+var benchResultRegexp = regexp.MustCompile(`^(Benchmark\S*?)(?:-\d+)?\s+(\d+)\s+([0-9.]+) ns/op(?:\s+([0-9.]+) B/op)?(?:\s+([0-9]+) allocs/op)?`)
+
+// BenchResult is the parsed result of a benchmark run
+type BenchResult struct {
+	Iterations  int
+	NsPerOp     float64
+	BytesPerOp  float64 // Only set when memory statistics were reported
+	AllocsPerOp int     // Only set when memory statistics were reported
+	HasMem      bool    // Memory statistics were reported (-benchmem)
+}
+
+// String returns the result as it's reported by go test, without the
+// iterations
+func (b BenchResult) String() string {
+	s := FormatNsPerOp(b.NsPerOp)
+	if b.HasMem {
+		s += fmt.Sprintf(" · %s B/op · %d allocs/op", strconv.FormatFloat(b.BytesPerOp, 'f', -1, 64), b.AllocsPerOp)
+	}
+	return s
+}
+
+// FormatNsPerOp formats a duration per operation compactly, e.g. "1.23µs/op"
+func FormatNsPerOp(ns float64) string {
+	switch {
+	case ns >= 1e9:
+		return fmt.Sprintf("%.2fs/op", ns/1e9)
+	case ns >= 1e6:
+		return fmt.Sprintf("%.2fms/op", ns/1e6)
+	case ns >= 1e3:
+		return fmt.Sprintf("%.2fµs/op", ns/1e3)
+	default:
+		return fmt.Sprintf("%.4gns/op", ns)
+	}
+}
+
+// ParseBenchResult returns the last result of the named benchmark in the
+// output lines. Results of sub-benchmarks are ignored.
+func ParseBenchResult(lines []string, name string) (BenchResult, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		m := benchResultRegexp.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if m == nil || m[1] != name {
+			continue
+		}
+
+		var result BenchResult
+		result.Iterations, _ = strconv.Atoi(m[2])
+		result.NsPerOp, _ = strconv.ParseFloat(m[3], 64)
+		if m[4] != "" && m[5] != "" {
+			result.HasMem = true
+			result.BytesPerOp, _ = strconv.ParseFloat(m[4], 64)
+			result.AllocsPerOp, _ = strconv.Atoi(m[5])
+		}
+		return result, true
+	}
+	return BenchResult{}, false
+}
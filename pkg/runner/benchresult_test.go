@@ -0,0 +1,41 @@
+package runner
+
+import "testing"
+
+func TestParseBenchResult(t *testing.T) {
+	lines := []string{
+		"goos: linux",
+		"BenchmarkFoo",
+		"BenchmarkFoo-8   \t 1000000\t      1234 ns/op\t      56 B/op\t       2 allocs/op",
+		"BenchmarkFoo/sub-8   \t 10\t      99 ns/op",
+		"BenchmarkFooBar-8   \t 10\t      5 ns/op",
+		"BenchmarkFoo-8   \t 2000000\t      617.5 ns/op\t      48 B/op\t       1 allocs/op",
+		"PASS",
+	}
+
+	// The most recent result wins
+	result, ok := ParseBenchResult(lines, "BenchmarkFoo")
+	if !ok {
+		t.Fatal("expected a result")
+	}
+	want := BenchResult{Iterations: 2000000, NsPerOp: 617.5, BytesPerOp: 48, AllocsPerOp: 1, HasMem: true}
+	if result != want {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+	if got := result.String(); got != "617.5ns/op · 48 B/op · 1 allocs/op" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// Without -benchmem and without a GOMAXPROCS suffix
+	result, ok = ParseBenchResult([]string{"BenchmarkBar 100 2500000 ns/op"}, "BenchmarkBar")
+	if !ok || result.HasMem || result.NsPerOp != 2500000 || result.Iterations != 100 {
+		t.Errorf("got %+v, %v", result, ok)
+	}
+	if got := result.String(); got != "2.50ms/op" {
+		t.Errorf("String() = %q", got)
+	}
+
+	if _, ok := ParseBenchResult(lines, "BenchmarkBaz"); ok {
+		t.Error("expected no result for an unknown benchmark")
+	}
+}
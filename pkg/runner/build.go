@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// isBuildFailure checks if the output of go test reports that the package
+// failed to build (or its setup failed), so the test itself never ran
+func isBuildFailure(lines []string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "FAIL\t") && (strings.HasSuffix(line, " [build failed]") || strings.HasSuffix(line, " [setup failed]")) {
+			return true
+		}
+	}
+	return false
+}
+
+// failPackageBuild fails the queued tests of a package that failed to build,
+// as they would fail the same way. The output of the failed build is written
+// to their log files, so each of them shows the compiler errors. It's called
+// while the failed test still counts as running, so the batch can't finish
+// in between.
+func (r *TestRunner) failPackageBuild(failed *TestItem) {
+	output, _ := os.ReadFile(failed.LogFile)
+
+	r.mu.Lock()
+	if r.tests == nil {
+		r.mu.Unlock()
+		return
+	}
+	var items []*TestItem
+	now := time.Now()
+	for _, item := range *r.tests {
+		if item == failed || item.Info.Package != failed.Info.Package {
+			continue
+		}
+		item.mu.Lock()
+		if item.Status == StatusQueued {
+			item.Status = StatusFailed
+			item.BuildFailed = true
+			item.Priority = 0
+			item.repeat = 0
+			item.untilFail = false
+			item.verbose = false
+			item.StartedAt, item.FinishedAt = now, now
+			item.output = nil
+			item.Runs++
+			item.Failures++
+			items = append(items, item)
+		}
+		item.mu.Unlock()
+	}
+	r.mu.Unlock()
+
+	header := fmt.Sprintf("Not run: the package failed to build while running %s\n\n", failed.Info.Name)
+	for _, item := range items {
+		os.WriteFile(item.LogFile, append([]byte(header), output...), 0o644)
+		r.notifyFinished(item)
+	}
+}
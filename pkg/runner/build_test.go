@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBuildFailure(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		expected bool
+	}{
+		{[]string{"# pkg [pkg.test]", "./a_test.go:3:28: declared and not used: x", "FAIL\tpkg [build failed]", "FAIL"}, true},
+		{[]string{"FAIL\tpkg [setup failed]"}, true},
+		{[]string{"--- FAIL: TestFoo (0.00s)", "FAIL", "FAIL\tpkg\t0.012s"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isBuildFailure(tt.lines); got != tt.expected {
+			t.Errorf("isBuildFailure(%q) = %v, expected %v", tt.lines, got, tt.expected)
+		}
+	}
+}
+
+func TestBuildFailure(t *testing.T) {
+	// The script reports a compiler error, like go test does
+	dir := t.TempDir()
+	script := filepath.Join(dir, "build.sh")
+	counter := filepath.Join(dir, "runs")
+	src := "echo run >> " + counter + "\necho './a_test.go:3:28: declared and not used: x'\nprintf 'FAIL\\tpkg [build failed]\\n'\nexit 1\n"
+	if err := os.WriteFile(script, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := &TestItem{Info: TestInfo{Name: "TestFirst", Package: "pkg"}}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond", Package: "pkg"}}
+	other := &TestItem{Info: TestInfo{Name: "TestOther", Package: "other"}}
+	tests := []*TestItem{first, second, other}
+
+	r := NewTestRunner(dir, t.TempDir(), 1, 0)
+	r.SetTestCommand("sh " + script)
+	r.SetRetries(2)
+	r.SetSequential(true)
+	r.SetTestList(&tests)
+
+	for _, item := range tests {
+		r.QueueTest(item)
+	}
+	select {
+	case <-r.Idle():
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the tests to finish")
+	}
+
+	for _, item := range tests {
+		if !item.IsBuildFailed() {
+			t.Errorf("Expected %s to fail to build", item.Info.Name)
+		}
+		if runs, failures := item.FlakeRate(); runs != 1 || failures != 1 {
+			t.Errorf("Expected %s to fail once without retries, got %d/%d", item.Info.Name, failures, runs)
+		}
+	}
+
+	// The queued test of the same package isn't run, but gets the output
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := strings.Count(string(data), "run"); runs != 2 {
+		t.Errorf("Expected the script to run for both packages, got %d runs", runs)
+	}
+	_, logFile := second.LogState()
+	data, err = os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "declared and not used") {
+		t.Errorf("Expected the build output in the log of the queued test, got %q", data)
+	}
+}
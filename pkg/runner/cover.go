@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// coverageRegexp matches the coverage summary that go test prints
+var coverageRegexp = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+
+// CoverProfileFile returns the path of the coverage profile that belongs to
+// a log file
+func CoverProfileFile(logFile string) string {
+	return strings.TrimSuffix(logFile, ".log") + ".cover"
+}
+
+// withCover adds the flags to write a coverage profile to the go test
+// arguments, unless a coverage profile is already requested. Commands that
+// don't run go test are returned unchanged.
+func withCover(args []string, profile string) []string {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-coverprofile") || strings.HasPrefix(arg, "-test.coverprofile") {
+			return args
+		}
+	}
+
+	insertAt := -1
+	if len(args) > 1 && args[0] == "go" && args[1] == "test" {
+		insertAt = 2
+	} else if i := slices.Index(args, "--"); i >= 0 {
+		insertAt = i + 1
+	}
+	if insertAt < 0 {
+		return args
+	}
+	return slices.Insert(slices.Clone(args), insertAt, "-coverprofile="+profile, "-covermode=atomic")
+}
+
+// parseCoverage returns the percentage of the last coverage summary in the
+// output lines
+func parseCoverage(lines []string) (float64, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		m := coverageRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+			return pct, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithCover(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"go", "test", "-run", "^TestFoo$", "."}, []string{"go", "test", "-coverprofile=foo.cover", "-covermode=atomic", "-run", "^TestFoo$", "."}},
+		{[]string{"go", "test", "-coverprofile=c.out", "."}, []string{"go", "test", "-coverprofile=c.out", "."}},
+		{[]string{"make", "test"}, []string{"make", "test"}},
+	}
+
+	for _, tt := range tests {
+		if got := withCover(tt.args, "foo.cover"); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("withCover(%v) = %v, expected %v", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestParseCoverage(t *testing.T) {
+	lines := []string{"=== RUN   TestFoo", "--- PASS: TestFoo (0.00s)", "PASS", "coverage: 42.5% of statements", "ok  \texample.com/foo\t0.003s"}
+	if pct, ok := parseCoverage(lines); !ok || pct != 42.5 {
+		t.Errorf("parseCoverage() = %v, %v, expected 42.5, true", pct, ok)
+	}
+	if _, ok := parseCoverage(lines[:3]); ok {
+		t.Error("Expected no coverage without a summary")
+	}
+}
+
+func TestCoverProfileFile(t *testing.T) {
+	if got := CoverProfileFile("/logs/pkg_TestFoo.20240101-120000.000.log"); got != "/logs/pkg_TestFoo.20240101-120000.000.cover" {
+		t.Errorf("CoverProfileFile() = %q", got)
+	}
+}
@@ -0,0 +1,456 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// TestKind is the kind of a discovered test function
+type TestKind int
+
+const (
+	KindTest      TestKind = iota // func TestXxx(t *testing.T)
+	KindBenchmark                 // func BenchmarkXxx(b *testing.B)
+)
+
+// TestInfo holds information about a discovered test
+type TestInfo struct {
+	Name    string   // Function name (e.g., TestFoo)
+	Kind    TestKind // Test or benchmark
+	Package string   // Package path
+	Module  string   // Root of a nested module relative to the search directory (empty otherwise)
+	File    string   // Source file path
+	Line    int      // Line number where the test function starts
+	Doc     string   // First line of the doc comment of the test function
+}
+
+// TestSignature determines which function signatures count as tests
+type TestSignature int
+
+const (
+	// SignatureStrict only accepts func TestXxx(t *testing.T)
+	SignatureStrict TestSignature = iota
+	// SignatureTB also accepts func TestXxx(tb testing.TB)
+	SignatureTB
+	// SignatureRelaxed accepts any TestXxx function whose first parameter is
+	// *testing.T or testing.TB, regardless of further parameters
+	SignatureRelaxed
+)
+
+// String returns the name of the test signature
+func (s TestSignature) String() string {
+	switch s {
+	case SignatureStrict:
+		return "strict"
+	case SignatureTB:
+		return "tb"
+	case SignatureRelaxed:
+		return "relaxed"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTestSignature parses the name of a test signature. An empty name
+// results in the strict signature.
+func ParseTestSignature(name string) (TestSignature, error) {
+	switch name {
+	case "", "strict":
+		return SignatureStrict, nil
+	case "tb":
+		return SignatureTB, nil
+	case "relaxed":
+		return SignatureRelaxed, nil
+	default:
+		return SignatureStrict, fmt.Errorf("invalid test signature %q (expected strict, tb or relaxed)", name)
+	}
+}
+
+// DiscoverOptions holds the settings used to discover tests
+type DiscoverOptions struct {
+	Recursive bool            // Also discover tests in subdirectories
+	Signature TestSignature   // Function signatures that count as tests
+	Tags      []string        // Build tags; files whose constraints aren't satisfied are skipped
+	Cache     *DiscoveryCache // Tests of unchanged files (nil to parse all files)
+}
+
+// DiscoverTests finds all Go test functions in the given directory
+func DiscoverTests(dir string) ([]TestInfo, error) {
+	return DiscoverTestsRecursive(dir, true)
+}
+
+// DiscoverTestsRecursive finds all Go test functions with recursive option
+func DiscoverTestsRecursive(dir string, recursive bool) ([]TestInfo, error) {
+	return DiscoverTestsWithOptions(dir, DiscoverOptions{Recursive: recursive})
+}
+
+// DiscoverTestsWithOptions finds all Go test functions using the given options.
+// The test files are parsed concurrently and the tests are sorted by file and
+// line, so the result doesn't depend on the order in which files are parsed.
+func DiscoverTestsWithOptions(dir string, opts DiscoverOptions) ([]TestInfo, error) {
+	files, err := findTestFiles(dir, opts.Recursive)
+
+	// Build constraints are evaluated for the host with the given tags, the
+	// same way go test selects the files
+	buildCtx := build.Default
+	buildCtx.BuildTags = opts.Tags
+
+	tests := discoverFiles(files, &buildCtx, opts, runtime.NumCPU())
+	return tests, err
+}
+
+// discoverFiles parses the test files with a pool of workers and returns
+// their tests, sorted by file and line
+func discoverFiles(files []testFile, buildCtx *build.Context, opts DiscoverOptions, workers int) []TestInfo {
+	results := make([][]TestInfo, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = discoverFile(files[i], buildCtx, opts)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var tests []TestInfo
+	for _, fileTests := range results {
+		tests = append(tests, fileTests...)
+	}
+	slices.SortStableFunc(tests, func(a, b TestInfo) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+	return tests
+}
+
+// testFile is a candidate test file
+type testFile struct {
+	path    string
+	info    os.FileInfo
+	pkgPath string // Package path relative to the discovery directory
+	module  string // Module root relative to the discovery directory
+}
+
+// findTestFiles returns the test files in the directory and, when recursive,
+// its subdirectories. Subdirectories with a go.mod file are the root of a
+// nested module (e.g. in a go.work workspace), which is remembered for their
+// files, as their packages can only be tested from the module root.
+func findTestFiles(dir string, recursive bool) ([]testFile, error) {
+	var files []testFile
+	modules := make(map[string]string) // Module root of each directory
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories and non-test files
+		if info.IsDir() {
+			// The root dir is always searched, even when it's "."
+			if path == dir {
+				modules[path] = ""
+				return nil
+			}
+			// Skip vendor and hidden directories
+			if info.Name() == "vendor" || strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			// Skip subdirectories if not recursive
+			if !recursive {
+				return filepath.SkipDir
+			}
+			modules[path] = modules[filepath.Dir(path)]
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				if rel, err := filepath.Rel(dir, path); err == nil {
+					modules[path] = filepath.ToSlash(rel)
+				}
+			}
+			return nil
+		}
+
+		// Only process *_test.go files
+		if !strings.HasSuffix(info.Name(), "_test.go") {
+			return nil
+		}
+
+		// Get package directory relative to the search directory. It uses
+		// forward slashes on all platforms, as go test expects in a package
+		// path. External test packages (package foo_test) live in the same
+		// directory, so they share the package path.
+		pkgDir, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			pkgDir = filepath.Dir(path)
+		}
+		pkgDir = filepath.ToSlash(pkgDir)
+		if pkgDir == "." {
+			pkgDir = ""
+		}
+
+		files = append(files, testFile{path: path, info: info, pkgPath: pkgDir, module: modules[filepath.Dir(path)]})
+		return nil
+	})
+	return files, err
+}
+
+// discoverFile returns the tests in a test file. Files that aren't built with
+// the active tags or can't be parsed don't have tests.
+func discoverFile(file testFile, buildCtx *build.Context, opts DiscoverOptions) []TestInfo {
+	if match, err := buildCtx.MatchFile(filepath.Dir(file.path), file.info.Name()); err != nil || !match {
+		return nil
+	}
+
+	// Unchanged files don't need to be parsed again
+	tests, cached := opts.Cache.lookup(file.path, file.info, opts.Signature)
+	if !cached {
+		var err error
+		tests, err = parseTestFile(file.path, opts.Signature)
+		if err != nil {
+			return nil
+		}
+		opts.Cache.store(file.path, file.info, opts.Signature, tests)
+	}
+	for i := range tests {
+		tests[i].Package, tests[i].Module, tests[i].File = file.pkgPath, file.module, file.path
+	}
+	return tests
+}
+
+// parseTestFile returns the tests, benchmarks and subtests in a test file.
+// The package of the tests isn't set.
+func parseTestFile(path string, signature TestSignature) ([]TestInfo, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []TestInfo
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		// Check if it's a test or benchmark function
+		kind := KindTest
+		if isBenchmarkFunc(fn) {
+			kind = KindBenchmark
+		} else if !isTestFunc(fn, signature) {
+			continue
+		}
+
+		pos := fset.Position(fn.Pos())
+		parent := TestInfo{
+			Name: fn.Name.Name,
+			Kind: kind,
+			File: path,
+			Line: pos.Line,
+			Doc:  firstLine(fn.Doc.Text()),
+		}
+		tests = append(tests, parent)
+
+		// Subtests with literal names can be run individually
+		if kind == KindTest && fn.Body != nil {
+			tests = append(tests, findSubtests(fset, fn.Body, paramName(fn.Type), parent)...)
+		}
+	}
+	return tests, nil
+}
+
+// findSubtests finds the t.Run calls with a string literal name in the body
+// of a test, including nested subtests. The names are rewritten the same way
+// as go test does, so they can be used in a -run pattern.
+func findSubtests(fset *token.FileSet, body ast.Node, param string, parent TestInfo) []TestInfo {
+	var subtests []TestInfo
+	if param == "" {
+		return nil
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Run" {
+			return true
+		}
+		if ident, ok := sel.X.(*ast.Ident); !ok || ident.Name != param {
+			return true
+		}
+
+		// Dynamically named subtests can't be discovered
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		subtest := parent
+		subtest.Name = parent.Name + "/" + rewriteSubtestName(name)
+		subtest.Line = fset.Position(call.Pos()).Line
+		subtest.Doc = ""
+		subtests = append(subtests, subtest)
+
+		// Nested subtests use the parameter of the function literal
+		if fn, ok := call.Args[1].(*ast.FuncLit); ok {
+			subtests = append(subtests, findSubtests(fset, fn.Body, paramName(fn.Type), subtest)...)
+		}
+		return false
+	})
+
+	return subtests
+}
+
+// paramName returns the name of the first parameter of a function
+func paramName(fn *ast.FuncType) string {
+	if fn.Params == nil || len(fn.Params.List) == 0 || len(fn.Params.List[0].Names) == 0 {
+		return ""
+	}
+	return fn.Params.List[0].Names[0].Name
+}
+
+// rewriteSubtestName rewrites a subtest name like the testing package does:
+// spaces are replaced by underscores and non-printable characters escaped
+func rewriteSubtestName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		case !strconv.IsPrint(r):
+			s := strconv.QuoteRune(r)
+			b.WriteString(s[1 : len(s)-1])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// IsSubtest reports whether the test is a subtest of another test
+func (t TestInfo) IsSubtest() bool {
+	return strings.Contains(t.Name, "/")
+}
+
+// Parent returns the name of the top-level test of a subtest, or the name
+// of the test itself for top-level tests
+func (t TestInfo) Parent() string {
+	parent, _, _ := strings.Cut(t.Name, "/")
+	return parent
+}
+
+// ModulePackage returns the path of the package relative to the root of its
+// module, which is where go test runs
+func (t TestInfo) ModulePackage() string {
+	if t.Module == "" {
+		return t.Package
+	}
+	if t.Package == t.Module {
+		return ""
+	}
+	return strings.TrimPrefix(t.Package, t.Module+"/")
+}
+
+// RunPattern returns the -run pattern that matches exactly this test. Each
+// level of a subtest is matched separately.
+func (t TestInfo) RunPattern() string {
+	parts := strings.Split(t.Name, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// firstLine returns the first line of a text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// isTestFunc checks if a function declaration is a test function. By default
+// only the signature accepted by go test is allowed, but the signature can be
+// relaxed for codebases with nonstandard test shapes.
+func isTestFunc(fn *ast.FuncDecl, signature TestSignature) bool {
+	// Must be exported and start with "Test"
+	name := fn.Name.Name
+	if !strings.HasPrefix(name, "Test") {
+		return false
+	}
+
+	// Methods are never tests
+	if fn.Recv != nil || fn.Type.Params == nil || len(fn.Type.Params.List) == 0 {
+		return false
+	}
+
+	// Count the parameters, as a single field may declare multiple names
+	params := 0
+	for _, field := range fn.Type.Params.List {
+		params += max(len(field.Names), 1)
+	}
+	if params > 1 && signature != SignatureRelaxed {
+		return false
+	}
+
+	// Check the type of the first parameter
+	param := fn.Type.Params.List[0].Type
+	if starExpr, ok := param.(*ast.StarExpr); ok {
+		return isTestingType(starExpr.X, "T")
+	}
+	return signature != SignatureStrict && isTestingType(param, "TB")
+}
+
+// isBenchmarkFunc checks if a function declaration is a benchmark function
+func isBenchmarkFunc(fn *ast.FuncDecl) bool {
+	if !strings.HasPrefix(fn.Name.Name, "Benchmark") || fn.Recv != nil {
+		return false
+	}
+
+	// Must have exactly one parameter of type *testing.B
+	params := fn.Type.Params
+	if params == nil || len(params.List) != 1 || len(params.List[0].Names) > 1 {
+		return false
+	}
+
+	starExpr, ok := params.List[0].Type.(*ast.StarExpr)
+	return ok && isTestingType(starExpr.X, "B")
+}
+
+// isTestingType checks if an expression refers to the given type of the
+// testing package
+func isTestingType(expr ast.Expr, typeName string) bool {
+	selExpr, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	ident, ok := selExpr.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	return ident.Name == "testing" && selExpr.Sel.Name == typeName
+}
@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiscoverTests(t *testing.T) {
+	tests, err := DiscoverTests("testdata")
+	if err != nil {
+		t.Fatalf("DiscoverTests failed: %v", err)
+	}
+
+	if len(tests) == 0 {
+		t.Fatal("Expected to find tests, got none")
+	}
+
+	expectedTests := map[string]bool{
+		"TestQuickPass":   false,
+		"TestSlowPass":    false,
+		"TestFail":        false,
+		"TestAnotherPass": false,
+		"TestWithOutput":  false,
+		"BenchmarkQuick":  false,
+	}
+
+	for _, test := range tests {
+		t.Logf("Found test: %s (package: %s)", test.Name, test.Package)
+		if _, ok := expectedTests[test.Name]; ok {
+			expectedTests[test.Name] = true
+		}
+		if kind := test.Kind; (test.Name == "BenchmarkQuick") != (kind == KindBenchmark) {
+			t.Errorf("Unexpected kind %d for %s", kind, test.Name)
+		}
+		if test.Name == "TestQuickPass" && test.Doc != "TestQuickPass passes quickly." {
+			t.Errorf("Expected doc comment for TestQuickPass, got %q", test.Doc)
+		}
+	}
+
+	for name, found := range expectedTests {
+		if !found {
+			t.Errorf("Expected test %s was not found", name)
+		}
+	}
+}
+
+func TestDiscoverTestsWithTags(t *testing.T) {
+	cases := []struct {
+		tags     []string
+		expected []string
+	}{
+		{nil, []string{"TestAlways", "TestUnitOnly"}},
+		{[]string{"integration"}, []string{"TestAlways", "TestIntegration"}},
+	}
+
+	for _, c := range cases {
+		tests, err := DiscoverTestsWithOptions("testdata/tags", DiscoverOptions{Tags: c.tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, test := range tests {
+			names = append(names, test.Name)
+		}
+		slices.Sort(names)
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("tags %v: found %v, expected %v", c.tags, names, c.expected)
+		}
+	}
+}
+
+func TestIsTestFunc(t *testing.T) {
+	src := `package foo
+
+func TestStrict(t *testing.T) {}
+func TestTB(tb testing.TB) {}
+func TestExtra(t *testing.T, name string) {}
+func TestExtraTB(tb testing.TB, a, b int) {}
+func TestNoParams() {}
+func TestWrongType(t *testing.B) {}
+func helper(t *testing.T) {}
+`
+	expected := map[TestSignature]map[string]bool{
+		SignatureStrict:  {"TestStrict": true},
+		SignatureTB:      {"TestStrict": true, "TestTB": true},
+		SignatureRelaxed: {"TestStrict": true, "TestTB": true, "TestExtra": true, "TestExtraTB": true},
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "foo_test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for signature, accepted := range expected {
+		for _, decl := range file.Decls {
+			fn := decl.(*ast.FuncDecl)
+			if got := isTestFunc(fn, signature); got != accepted[fn.Name.Name] {
+				t.Errorf("%s: expected %s to be accepted: %v, got %v", signature, fn.Name.Name, accepted[fn.Name.Name], got)
+			}
+		}
+	}
+}
+
+func TestDiscoverSubtests(t *testing.T) {
+	tests, err := DiscoverTests("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subtests []string
+	for _, test := range tests {
+		if test.Parent() == "TestTable" && test.IsSubtest() {
+			subtests = append(subtests, test.Name)
+		}
+	}
+
+	// Dynamically named subtests are ignored
+	expected := []string{"TestTable/literal_case", "TestTable/literal_case/nested"}
+	if !reflect.DeepEqual(subtests, expected) {
+		t.Errorf("Expected subtests %v, got %v", expected, subtests)
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	if pattern := (TestInfo{Name: "TestFoo"}).RunPattern(); pattern != "^TestFoo$" {
+		t.Errorf("Expected ^TestFoo$, got %s", pattern)
+	}
+	if pattern := (TestInfo{Name: "TestFoo/a+b_(1)"}).RunPattern(); pattern != `^TestFoo$/^a\+b_\(1\)$` {
+		t.Errorf(`Expected ^TestFoo$/^a\+b_\(1\)$, got %s`, pattern)
+	}
+}
+
+func TestDiscoverTestsDeterministic(t *testing.T) {
+	files, err := findTestFiles("testdata", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildCtx := build.Default
+	sequential := discoverFiles(files, &buildCtx, DiscoverOptions{}, 1)
+	for range 10 {
+		if parallel := discoverFiles(files, &buildCtx, DiscoverOptions{}, 8); !reflect.DeepEqual(parallel, sequential) {
+			t.Fatalf("Expected the same tests when parsing in parallel, got %v instead of %v", testNames(parallel), testNames(sequential))
+		}
+	}
+	if !slices.IsSortedFunc(sequential, func(a, b TestInfo) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	}) {
+		t.Errorf("Expected the tests to be sorted by file and line, got %v", testNames(sequential))
+	}
+}
+
+func TestDiscoverTestsNonRecursive(t *testing.T) {
+	tests, err := DiscoverTestsRecursive("testdata", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) == 0 {
+		t.Fatal("Expected the tests in the root directory")
+	}
+	for _, test := range tests {
+		if test.Package != "" {
+			t.Errorf("Expected only tests in the root directory, got %s in %q", test.Name, test.Package)
+		}
+	}
+}
+
+func TestDiscoverTestsCurrentDir(t *testing.T) {
+	t.Chdir("testdata")
+	tests, err := DiscoverTests(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) == 0 {
+		t.Error("Expected to find tests in the current directory")
+	}
+}
+
+// BenchmarkDiscoverFiles parses a tree of 2000 test files with a single
+// worker and with a worker for each CPU
+func BenchmarkDiscoverFiles(b *testing.B) {
+	dir := b.TempDir()
+	modTime := time.Now().Add(-time.Hour)
+	for i := range 2000 {
+		path := filepath.Join(dir, fmt.Sprintf("pkg%d", i/10), fmt.Sprintf("file%d_test.go", i))
+		writeTestFile(b, path, modTime, "TestOne", "TestTwo", "TestThree", "TestFour", "TestFive")
+	}
+	files, err := findTestFiles(dir, true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buildCtx := build.Default
+
+	b.Run("Sequential", func(b *testing.B) {
+		for b.Loop() {
+			discoverFiles(files, &buildCtx, DiscoverOptions{}, 1)
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for b.Loop() {
+			discoverFiles(files, &buildCtx, DiscoverOptions{}, runtime.NumCPU())
+		}
+	})
+}
+
+func TestDiscoverModules(t *testing.T) {
+	tests, err := DiscoverTests("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][3]string{
+		"TestModuleA":   {"multimod/moda", "multimod/moda", ""},
+		"TestModuleB":   {"multimod/modb/sub", "multimod/modb", "sub"},
+		"TestQuickPass": {"", "", ""},
+	}
+	for _, test := range tests {
+		e, ok := expected[test.Name]
+		if !ok {
+			continue
+		}
+		delete(expected, test.Name)
+		if got := [3]string{test.Package, test.Module, test.ModulePackage()}; got != e {
+			t.Errorf("Expected %s to have package, module and module package %q, got %q", test.Name, e, got)
+		}
+	}
+	for name := range expected {
+		t.Errorf("Expected test %s was not found", name)
+	}
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DiscoveryCacheFile is the name of the discovery cache in the log directory
+const DiscoveryCacheFile = "discovery-cache.json"
+
+// racyWindow is how long after its last modification a file isn't cached.
+// A file that changes again within the resolution of the modification time
+// (without changing its size) would otherwise keep its outdated entry.
+const racyWindow = 2 * time.Second
+
+// DiscoveryCache remembers the tests found in each test file, so files that
+// didn't change since they were parsed aren't parsed again. A file is parsed
+// again when its modification time or size changed. It's safe for concurrent
+// use.
+type DiscoveryCache struct {
+	path    string
+	entries map[string]discoveryEntry
+	changed bool
+	mu      sync.Mutex
+}
+
+// discoveryEntry holds the tests found in a test file. The package, module and
+// file of the tests aren't stored, as they depend on the discovery directory.
+type discoveryEntry struct {
+	ModTime   int64         `json:"modTime"` // Modification time in nanoseconds since the epoch
+	Size      int64         `json:"size"`
+	Signature TestSignature `json:"signature"`
+	Tests     []TestInfo    `json:"tests,omitempty"`
+}
+
+// LoadDiscoveryCache loads the discovery cache from a file. A missing or
+// corrupt file results in an empty cache, as everything can be parsed again.
+func LoadDiscoveryCache(path string) *DiscoveryCache {
+	c := &DiscoveryCache{path: path, entries: make(map[string]discoveryEntry)}
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &c.entries) != nil {
+			c.entries = make(map[string]discoveryEntry)
+		}
+	}
+	return c
+}
+
+// Save writes the cache to its file when it changed. Entries of files that
+// don't exist anymore are dropped.
+func (c *DiscoveryCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+			c.changed = true
+		}
+	}
+	if !c.changed {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash never leaves a partial file
+	tmpFile := c.path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, c.path); err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
+
+// lookup returns the cached tests of a file, if the file didn't change since
+// it was parsed with the same signature. A nil cache has no entries.
+func (c *DiscoveryCache) lookup(path string, info os.FileInfo, signature TestSignature) ([]TestInfo, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() || entry.Signature != signature {
+		return nil, false
+	}
+	return append([]TestInfo(nil), entry.Tests...), true
+}
+
+// store remembers the tests found in a file. Files that were modified very
+// recently aren't stored, as a change in the same instant can't be detected.
+func (c *DiscoveryCache) store(path string, info os.FileInfo, signature TestSignature, tests []TestInfo) {
+	if c == nil || time.Since(info.ModTime()) < racyWindow {
+		return
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	entry := discoveryEntry{
+		ModTime:   info.ModTime().UnixNano(),
+		Size:      info.Size(),
+		Signature: signature,
+	}
+	for _, t := range tests {
+		t.Package, t.Module, t.File = "", "", ""
+		entry.Tests = append(entry.Tests, t)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	c.changed = true
+}
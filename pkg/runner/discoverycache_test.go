@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes a test file with the given tests and a modification
+// time outside of the racy window, so it can be cached
+func writeTestFile(t testing.TB, path string, modTime time.Time, names ...string) {
+	t.Helper()
+	src := "package foo\n\nimport \"testing\"\n"
+	for _, name := range names {
+		src += fmt.Sprintf("\nfunc %s(t *testing.T) {\n\tt.Run(\"sub\", func(t *testing.T) {})\n}\n", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testNames(tests []TestInfo) []string {
+	var names []string
+	for _, test := range tests {
+		names = append(names, test.Package+"/"+test.Name)
+	}
+	return names
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), DiscoveryCacheFile)
+	path := filepath.Join(dir, "pkg", "foo_test.go")
+	modTime := time.Now().Add(-time.Hour)
+	writeTestFile(t, path, modTime, "TestAaa")
+
+	discover := func() []string {
+		t.Helper()
+		cache := LoadDiscoveryCache(cacheFile)
+		tests, err := DiscoverTestsWithOptions(dir, DiscoverOptions{Recursive: true, Cache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return testNames(tests)
+	}
+
+	if got := fmt.Sprint(discover()); got != "[pkg/TestAaa pkg/TestAaa/sub]" {
+		t.Fatalf("Unexpected tests %s", got)
+	}
+
+	// A file with the same size and modification time isn't parsed again
+	writeTestFile(t, path, modTime, "TestBbb")
+	if got := fmt.Sprint(discover()); got != "[pkg/TestAaa pkg/TestAaa/sub]" {
+		t.Errorf("Expected the cached tests, got %s", got)
+	}
+
+	// A changed modification time or size invalidates the entry
+	writeTestFile(t, path, modTime.Add(time.Second), "TestBbb")
+	if got := fmt.Sprint(discover()); got != "[pkg/TestBbb pkg/TestBbb/sub]" {
+		t.Errorf("Expected the file to be parsed again after it was modified, got %s", got)
+	}
+	writeTestFile(t, path, modTime.Add(time.Second), "TestCccc")
+	if got := fmt.Sprint(discover()); got != "[pkg/TestCccc pkg/TestCccc/sub]" {
+		t.Errorf("Expected the file to be parsed again after its size changed, got %s", got)
+	}
+
+	// A file that was just modified is always parsed
+	writeTestFile(t, path, time.Now(), "TestDddd")
+	discover()
+	writeTestFile(t, path, time.Now(), "TestEeee")
+	if got := fmt.Sprint(discover()); got != "[pkg/TestEeee pkg/TestEeee/sub]" {
+		t.Errorf("Expected a recently modified file to be parsed, got %s", got)
+	}
+
+	// Entries of deleted files are dropped
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	discover()
+	if entries := len(LoadDiscoveryCache(cacheFile).entries); entries != 0 {
+		t.Errorf("Expected no entries after the file was deleted, got %d", entries)
+	}
+}
+
+func TestDiscoveryCacheCorrupt(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), DiscoveryCacheFile)
+	if err := os.WriteFile(cacheFile, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if entries := len(LoadDiscoveryCache(cacheFile).entries); entries != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", entries)
+	}
+}
+
+// BenchmarkDiscoveryCache discovers the tests in a tree of 2000 test files
+// with and without a warm cache
+func BenchmarkDiscoveryCache(b *testing.B) {
+	dir := b.TempDir()
+	modTime := time.Now().Add(-time.Hour)
+	for i := range 2000 {
+		path := filepath.Join(dir, fmt.Sprintf("pkg%d", i/10), fmt.Sprintf("file%d_test.go", i))
+		writeTestFile(b, path, modTime, "TestOne", "TestTwo", "TestThree", "TestFour", "TestFive")
+	}
+
+	b.Run("NoCache", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := DiscoverTestsWithOptions(dir, DiscoverOptions{Recursive: true}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Cache", func(b *testing.B) {
+		cache := LoadDiscoveryCache(filepath.Join(b.TempDir(), DiscoveryCacheFile))
+		if _, err := DiscoverTestsWithOptions(dir, DiscoverOptions{Recursive: true, Cache: cache}); err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			if _, err := DiscoverTestsWithOptions(dir, DiscoverOptions{Recursive: true, Cache: cache}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PackageEnvFile is the name of the file in a package directory with the
+// environment variables that are set for the tests of that package
+const PackageEnvFile = ".test-runner.env"
+
+// SetEnv sets the environment variables (KEY=VALUE) that are set for all
+// tests, in addition to the inherited environment
+func (r *TestRunner) SetEnv(env []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.env = slices.Clone(env)
+}
+
+// GetEnv returns the environment variables that are set for all tests
+func (r *TestRunner) GetEnv() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.env)
+}
+
+// ParseEnv checks that an environment variable is in KEY=VALUE form
+func ParseEnv(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid environment variable %q (expected KEY=VALUE)", s)
+	}
+	return key, value, nil
+}
+
+// readEnvFile reads the environment variables in a package environment file.
+// Empty lines and lines starting with # are skipped, and values may be
+// quoted. A missing file has no variables.
+func readEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, err := ParseEnv(strings.TrimPrefix(line, "export "))
+		if err != nil {
+			return env, fmt.Errorf("%s:%d: %w", path, lineNr, err)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, scanner.Err()
+}
+
+// testEnv returns the environment variables that are set for the tests of a
+// package: the global variables followed by the variables in the environment
+// file of the package, so the latter take precedence
+func testEnv(testDir, pkg string, global []string) ([]string, error) {
+	pkgEnv, err := readEnvFile(filepath.Join(testDir, pkg, PackageEnvFile))
+	return append(slices.Clone(global), pkgEnv...), err
+}
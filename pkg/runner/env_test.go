@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), PackageEnvFile)
+	src := "# Settings for the integration tests\n\nINTEGRATION=1\nexport NAME = \"quoted value\"\nEMPTY=\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := readEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"INTEGRATION=1", "NAME=quoted value", "EMPTY="}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+
+	if err := os.WriteFile(path, []byte("VALID=1\ninvalid line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readEnvFile(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+
+	if env, err := readEnvFile(filepath.Join(t.TempDir(), PackageEnvFile)); env != nil || err != nil {
+		t.Errorf("Expected no variables without a file, got %v, %v", env, err)
+	}
+}
+
+func TestRunWithEnv(t *testing.T) {
+	testDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(testDir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pkgEnv := "PKG_ONLY=pkg\nOVERRIDDEN=pkg\n"
+	if err := os.WriteFile(filepath.Join(testDir, "pkg", PackageEnvFile), []byte(pkgEnv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("INHERITED", "inherited")
+	t.Setenv("OVERRIDDEN", "inherited")
+	t.Setenv("GLOBAL", "inherited")
+
+	item := &TestItem{Info: TestInfo{Name: "TestEnv", Package: "pkg"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(testDir, t.TempDir(), 1, 0)
+	r.SetTestCommand("env")
+	r.SetEnv([]string{"GLOBAL=global", "OVERRIDDEN=global"})
+	r.SetTestList(&tests)
+
+	r.QueueTest(item)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	_, logFile := item.LogState()
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			vars[key] = value
+		}
+	}
+
+	expected := map[string]string{
+		"INHERITED":  "inherited",
+		"GLOBAL":     "global",
+		"PKG_ONLY":   "pkg",
+		"OVERRIDDEN": "pkg",
+	}
+	for key, value := range expected {
+		if vars[key] != value {
+			t.Errorf("Expected %s=%s in the environment of the test, got %q", key, value, vars[key])
+		}
+	}
+}
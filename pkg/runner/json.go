@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+)
+
+// testEvent is an event emitted by go test -json (see go doc test2json)
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// withJSON adds the -json flag to go test arguments, so the result of the
+// test can be determined from the events. Other commands are returned
+// unchanged, as their output format is unknown.
+func withJSON(args []string) ([]string, bool) {
+	if len(args) < 2 || args[0] != "go" || args[1] != "test" {
+		return args, false
+	}
+	if slices.Contains(args, "-json") {
+		return args, true
+	}
+	return slices.Insert(slices.Clone(args), 2, "-json"), true
+}
+
+// copyTestEvents decodes the go test -json events from r and writes their
+// human-readable output to w. It returns the final action (pass, fail or
+// skip) of the given test, or an empty string when the test didn't report a
+// result (e.g. when the package failed to build). Lines that aren't JSON are
+// written as-is.
+func copyTestEvents(r io.Reader, w io.Writer, testName string) string {
+	var result string
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var ev testEvent
+			if json.Unmarshal(line, &ev) != nil {
+				w.Write(line)
+			} else {
+				if ev.Output != "" {
+					io.WriteString(w, ev.Output)
+				}
+				if ev.Test == testName {
+					switch ev.Action {
+					case "pass", "fail", "skip":
+						result = ev.Action
+					}
+				}
+			}
+		}
+		if err != nil {
+			return result
+		}
+	}
+}
+
+// copyPackageEvents decodes the go test -json events of a run of several tests
+// from r. The human-readable output of each test (including its subtests) is
+// written to its writer and all output is written to w. It returns the final
+// action of each test that reported a result.
+func copyPackageEvents(r io.Reader, w io.Writer, tests map[string]io.Writer) map[string]string {
+	results := make(map[string]string)
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var ev testEvent
+			if json.Unmarshal(line, &ev) != nil {
+				w.Write(line)
+			} else {
+				name, _, _ := strings.Cut(ev.Test, "/")
+				if ev.Output != "" {
+					io.WriteString(w, ev.Output)
+					if tw, ok := tests[name]; ok {
+						io.WriteString(tw, ev.Output)
+					}
+				}
+				if _, ok := tests[name]; ok && ev.Test == name {
+					switch ev.Action {
+					case "pass", "fail", "skip":
+						results[name] = ev.Action
+					}
+				}
+			}
+		}
+		if err != nil {
+			return results
+		}
+	}
+}
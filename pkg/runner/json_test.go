@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCopyTestEvents(t *testing.T) {
+	events := `{"Action":"start","Package":"example.com/foo"}
+{"Action":"run","Package":"example.com/foo","Test":"TestFoo"}
+{"Action":"output","Package":"example.com/foo","Test":"TestFoo","Output":"=== RUN   TestFoo\n"}
+{"Action":"run","Package":"example.com/foo","Test":"TestFoo/case"}
+{"Action":"fail","Package":"example.com/foo","Test":"TestFoo/case"}
+{"Action":"output","Package":"example.com/foo","Test":"TestFoo","Output":"--- PASS: TestFoo (0.00s)\n"}
+{"Action":"pass","Package":"example.com/foo","Test":"TestFoo"}
+not json
+{"Action":"output","Package":"example.com/foo","Output":"ok  \texample.com/foo\t0.01s\n"}
+`
+
+	var log strings.Builder
+	result := copyTestEvents(strings.NewReader(events), &log, "TestFoo")
+	if result != "pass" {
+		t.Errorf("Expected the result of TestFoo itself, got %q", result)
+	}
+
+	expected := "=== RUN   TestFoo\n--- PASS: TestFoo (0.00s)\nnot json\nok  \texample.com/foo\t0.01s\n"
+	if log.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, log.String())
+	}
+
+	// A build failure doesn't report a test result
+	result = copyTestEvents(strings.NewReader("# example.com/foo\nfoo_test.go:3:1: syntax error\n"), &log, "TestFoo")
+	if result != "" {
+		t.Errorf("Expected no result for a build failure, got %q", result)
+	}
+}
+
+func TestWithJSON(t *testing.T) {
+	args, ok := withJSON([]string{"go", "test", "-v", "."})
+	if !ok || !reflect.DeepEqual(args, []string{"go", "test", "-json", "-v", "."}) {
+		t.Errorf("Expected -json to be added, got %v", args)
+	}
+
+	args, ok = withJSON([]string{"gotestsum", "--", "-run", "^TestFoo$"})
+	if ok || !reflect.DeepEqual(args, []string{"gotestsum", "--", "-run", "^TestFoo$"}) {
+		t.Errorf("Expected other commands to be unchanged, got %v", args)
+	}
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// logCounterSuffix matches the counter that is added to log file names when
+// multiple logs share the same timestamp
+var logCounterSuffix = regexp.MustCompile(`-\d+$`)
+
+// ParseLogFileName extracts the test and the timestamp from a log file name
+// that was created using the given timestamp layout. The package is returned
+// in its sanitized form, as the original can't be recovered from the name.
+// Subtests get their slashes back.
+func ParseLogFileName(name, layout string) (TestInfo, time.Time, bool) {
+	base, ok := strings.CutSuffix(name, ".log")
+	if !ok {
+		return TestInfo{}, time.Time{}, false
+	}
+
+	// Find the timestamp at the end of the name, with or without a counter
+	prefix := ""
+	var timestamp time.Time
+	tsLen := len(time.Now().Format(layout))
+	for _, candidate := range []string{base, logCounterSuffix.ReplaceAllString(base, "")} {
+		i := len(candidate) - tsLen - 1
+		if i <= 0 || candidate[i] != '.' {
+			continue
+		}
+		if ts, err := time.ParseInLocation(layout, candidate[i+1:], time.Local); err == nil {
+			prefix, timestamp = candidate[:i], ts
+			break
+		}
+	}
+
+	// Fall back to the first dot, as test names never contain one
+	if prefix == "" {
+		var found bool
+		if prefix, _, found = strings.Cut(base, "."); !found || prefix == "" {
+			return TestInfo{}, time.Time{}, false
+		}
+	}
+
+	testName, pkg, _ := strings.Cut(prefix, "@")
+	testName = strings.ReplaceAll(testName, string(subtestSeparator), "/")
+	return TestInfo{Name: testName, Package: pkg}, timestamp, true
+}
+
+// DiscoverLogTests returns the tests that have log files in the log
+// directory, sorted by package and name
+func DiscoverLogTests(logDir, layout string) ([]TestInfo, error) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[TestInfo]bool)
+	var tests []TestInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		// The combined logs of package runs don't belong to a test
+		info, _, ok := ParseLogFileName(entry.Name(), layout)
+		if !ok || seen[info] || info.Name == packageLogName {
+			continue
+		}
+		seen[info] = true
+		tests = append(tests, info)
+	}
+
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Package != tests[j].Package {
+			return tests[i].Package < tests[j].Package
+		}
+		return tests[i].Name < tests[j].Name
+	})
+
+	return tests, nil
+}
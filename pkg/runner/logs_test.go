@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLogFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     TestInfo
+		expected time.Time
+	}{
+		{"TestFoo.20240101-120000.000.log", TestInfo{Name: "TestFoo"}, time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)},
+		{"TestFoo@sub_pkg.20240101-120000.000-2.log", TestInfo{Name: "TestFoo", Package: "sub_pkg"}, time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)},
+		{"TestFoo~case_one@pkg.20240101-120000.000.log", TestInfo{Name: "TestFoo/case_one", Package: "pkg"}, time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)},
+		{"TestFoo@gopkg.in_v2.20240101-120000.500.log", TestInfo{Name: "TestFoo", Package: "gopkg.in_v2"}, time.Date(2024, 1, 1, 12, 0, 0, 500*int(time.Millisecond), time.Local)},
+	}
+
+	for _, tt := range tests {
+		info, ts, ok := ParseLogFileName(tt.name, DefaultLogTimeFormat)
+		if !ok {
+			t.Errorf("%s: expected the name to be parsed", tt.name)
+			continue
+		}
+		if info != tt.info {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.info, info)
+		}
+		if !ts.Equal(tt.expected) {
+			t.Errorf("%s: expected timestamp %v, got %v", tt.name, tt.expected, ts)
+		}
+	}
+
+	if _, _, ok := ParseLogFileName("TestFoo.baseline.bench", DefaultLogTimeFormat); ok {
+		t.Error("Expected files that aren't logs to be ignored")
+	}
+}
+
+func TestDiscoverLogTests(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"TestFoo@pkgb.20240101-120000.000.log",
+		"TestFoo@pkgb.20240101-130000.000.log",
+		"TestBar.20240101-120000.000.log",
+		"state.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests, err := DiscoverLogTests(dir, DefaultLogTimeFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TestInfo{{Name: "TestBar"}, {Name: "TestFoo", Package: "pkgb"}}
+	if !reflect.DeepEqual(tests, expected) {
+		t.Errorf("Expected %v, got %v", expected, tests)
+	}
+}
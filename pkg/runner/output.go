@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// DefaultOutputLines is the default number of output lines kept in memory
+// for each test
+const DefaultOutputLines = 50000
+
+// OutputBuffer keeps the most recent output lines of a test run in memory,
+// so the output can be shown without re-reading the log file. The buffer
+// grows with the output, and when it's full, the oldest lines are dropped.
+// It's safe for concurrent use.
+type OutputBuffer struct {
+	mu       sync.Mutex
+	lines    []string // Ring buffer with the complete lines
+	maxLines int      // Number of lines at which the ring buffer is full
+	start    int      // Index of the oldest line in the ring buffer
+	partial  []byte   // Output after the last newline
+	dropped  int      // Number of lines dropped because the buffer was full
+	size     int64    // Total number of bytes written
+	version  uint64   // Incremented on every write
+}
+
+// NewOutputBuffer creates a buffer that keeps at most maxLines lines. A
+// non-positive maxLines uses DefaultOutputLines.
+func NewOutputBuffer(maxLines int) *OutputBuffer {
+	if maxLines <= 0 {
+		maxLines = DefaultOutputLines
+	}
+	return &OutputBuffer{maxLines: maxLines}
+}
+
+// Write adds output to the buffer, splitting it into lines
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.size += int64(len(p))
+	b.version++
+
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			b.partial = append(b.partial, data...)
+			return len(p), nil
+		}
+		line := string(append(b.partial, data[:i]...))
+		b.partial = b.partial[:0]
+		b.add(strings.TrimSuffix(line, "\r"))
+		data = data[i+1:]
+	}
+}
+
+// add appends a line, dropping the oldest line when the buffer is full. Until
+// then, nothing is dropped, so the oldest line is the first one.
+func (b *OutputBuffer) add(line string) {
+	if len(b.lines) < b.maxLines {
+		// Grow like append does, but never beyond the maximum
+		if len(b.lines) == cap(b.lines) {
+			grown := make([]string, len(b.lines), min(max(2*cap(b.lines), 64), b.maxLines))
+			copy(grown, b.lines)
+			b.lines = grown
+		}
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.start] = line
+	b.start = (b.start + 1) % len(b.lines)
+	b.dropped++
+}
+
+// Lines returns a copy of the buffered lines, including output that isn't
+// terminated by a newline yet
+func (b *OutputBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]string, 0, len(b.lines)+1)
+	for i := range b.lines {
+		lines = append(lines, b.lines[(b.start+i)%len(b.lines)])
+	}
+	if len(b.partial) > 0 {
+		lines = append(lines, strings.TrimSuffix(string(b.partial), "\r"))
+	}
+	return lines
+}
+
+// Trim releases the memory that was reserved for lines that weren't written.
+// It's called once the run finished, as no lines are added anymore.
+func (b *OutputBuffer) Trim() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cap(b.lines) > len(b.lines) {
+		lines := make([]string, len(b.lines))
+		copy(lines, b.lines)
+		b.lines = lines
+	}
+	if len(b.partial) == 0 {
+		b.partial = nil
+	}
+}
+
+// Dropped returns the number of lines that were dropped because the buffer
+// was full
+func (b *OutputBuffer) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Size returns the total number of bytes written to the buffer
+func (b *OutputBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Version returns a number that changes whenever output is written, so
+// callers can skip copying the lines when nothing changed
+func (b *OutputBuffer) Version() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.version
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	b := NewOutputBuffer(3)
+	b.Write([]byte("one\ntw"))
+	b.Write([]byte("o\r\nthree\n"))
+
+	if got := b.Lines(); !slices.Equal(got, []string{"one", "two", "three"}) {
+		t.Errorf("Lines() = %q", got)
+	}
+	if b.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", b.Dropped())
+	}
+
+	// The oldest lines are dropped when the buffer is full
+	v := b.Version()
+	b.Write([]byte("four\nfive\nsix"))
+	if got := b.Lines(); !slices.Equal(got, []string{"three", "four", "five", "six"}) {
+		t.Errorf("Lines() = %q", got)
+	}
+	if b.Dropped() != 2 {
+		t.Errorf("Dropped() = %d, want 2", b.Dropped())
+	}
+	if b.Version() == v {
+		t.Error("Version() didn't change after a write")
+	}
+	if b.Size() != 28 {
+		t.Errorf("Size() = %d, want 28", b.Size())
+	}
+}
+
+func TestOutputBufferGrows(t *testing.T) {
+	// Memory for the lines is only reserved as output is written
+	b := NewOutputBuffer(0)
+	b.Write([]byte("one\n"))
+	if n := cap(b.lines); n > 64 {
+		t.Errorf("Expected a small buffer for a single line, got capacity %d", n)
+	}
+	b.Trim()
+	if n := cap(b.lines); n != 1 {
+		t.Errorf("Expected capacity 1 after trimming, got %d", n)
+	}
+
+	// The buffer never grows beyond the maximum, also when it wraps around
+	b = NewOutputBuffer(100)
+	var expected []string
+	for i := range 250 {
+		line := fmt.Sprint(i)
+		b.Write([]byte(line + "\n"))
+		expected = append(expected, line)
+	}
+	if got := b.Lines(); !slices.Equal(got, expected[150:]) {
+		t.Errorf("Lines() = %q", got)
+	}
+	if n := cap(b.lines); n != 100 {
+		t.Errorf("Expected capacity 100, got %d", n)
+	}
+	if b.Dropped() != 150 {
+		t.Errorf("Dropped() = %d, want 150", b.Dropped())
+	}
+}
@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// packageLogName is the name used for the combined log of the tests of a
+// package that ran together. Tests start with Test, so it can't clash with
+// the log of a test.
+const packageLogName = "package"
+
+// SetPackageRuns enables or disables running the queued tests of a package in
+// a single go test invocation, so the package is only built once. Each test
+// still gets its own log and result. Subtests and benchmarks always run on
+// their own. A package run is a single go test process, so it takes up one
+// slot of the parallelism.
+func (r *TestRunner) SetPackageRuns(packageRuns bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packageRuns = packageRuns
+}
+
+// IsPackageRuns returns whether the queued tests of a package run together
+func (r *TestRunner) IsPackageRuns() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.packageRuns
+}
+
+// canRunWithPackage checks if a test can run together with the other tests of
+// its package. A -run pattern can't combine subtests of different tests, and
+// benchmarks use another command.
+func canRunWithPackage(info TestInfo) bool {
+	return info.Kind == KindTest && !info.IsSubtest()
+}
+
+// queuedPackageTests returns the other queued tests that can run together
+// with the given test, in list order. The caller must hold r.mu.
+func (r *TestRunner) queuedPackageTests(first *TestItem) []*TestItem {
+	if !canRunWithPackage(first.Info) {
+		return nil
+	}
+
+	var items []*TestItem
+	for _, item := range *r.tests {
+		if item == first || item.Info.Package != first.Info.Package || !canRunWithPackage(item.Info) {
+			continue
+		}
+		item.mu.Lock()
+		if item.Status == StatusQueued {
+			items = append(items, item)
+		}
+		item.mu.Unlock()
+	}
+	return items
+}
+
+// runPackage runs several tests of a package in a single go test invocation.
+// The JSON events are split up into the logs of the tests, and the complete
+// output (including the output of the package itself) is written to a
+// combined log.
+func (r *TestRunner) runPackage(ctx context.Context, items []*TestItem) {
+	r.notifyUpdate()
+
+	first := items[0]
+	r.mu.Lock()
+	combinedLog := uniqueLogFile(r.logDir, TestInfo{Name: packageLogName, Package: first.Info.Package}, time.Now().Format(r.logTimeFormat))
+	maxLines := r.outputLines
+	r.mu.Unlock()
+
+	combined, err := os.Create(combinedLog)
+	if err != nil {
+		for _, item := range items {
+			item.failStart()
+			r.notifyFinished(item)
+		}
+		r.testFinished()
+		return
+	}
+	defer combined.Close()
+	combinedOutput := NewOutputBuffer(maxLines)
+	out := io.MultiWriter(combined, combinedOutput)
+
+	// Each test streams its own output to its log and to memory
+	writers := make(map[string]io.Writer, len(items))
+	outputs := make([]*OutputBuffer, len(items))
+	names := make([]string, len(items))
+	verbose := false
+	for i, item := range items {
+		output := NewOutputBuffer(maxLines)
+		var w io.Writer = output
+		if logFile, err := os.Create(item.LogFile); err == nil {
+			defer logFile.Close()
+			w = io.MultiWriter(logFile, output)
+		}
+		fmt.Fprintf(w, "Run together with %d other tests of the package; the combined output is in %s\n\n", len(items)-1, combinedLog)
+
+		// The verbose override applies to the whole run
+		item.mu.Lock()
+		item.output = output
+		verbose = verbose || item.verbose
+		item.verbose = false
+		item.mu.Unlock()
+
+		writers[item.Info.Name] = w
+		outputs[i] = output
+		names[i] = regexp.QuoteMeta(item.Info.Name)
+	}
+
+	pattern := "^(" + strings.Join(names, "|") + ")$"
+	inv := r.newInvocation(ctx, first.Info, pattern, combinedLog, verbose, out)
+	var results map[string]string
+	if inv.useJSON {
+		results, err = runWithPackageEvents(inv.cmd, out, writers)
+	} else {
+		// Without events, the output can't be split up
+		all := []io.Writer{out}
+		for _, w := range writers {
+			all = append(all, w)
+		}
+		inv.cmd.Stdout = io.MultiWriter(all...)
+		inv.cmd.Stderr = inv.cmd.Stdout
+		err = inv.cmd.Run()
+	}
+
+	// Tests without a result didn't run (e.g. when the package didn't build
+	// or another test crashed), so the combined output explains why
+	if inv.useJSON && len(results) < len(items) {
+		if data, readErr := os.ReadFile(combinedLog); readErr == nil {
+			for _, item := range items {
+				if _, ok := results[item.Info.Name]; !ok {
+					writers[item.Info.Name].Write(data)
+				}
+			}
+		}
+	}
+
+	// The output is complete, so the memory reserved for more is released
+	for _, output := range outputs {
+		output.Trim()
+	}
+
+	// Stopping or restarting one of the tests cancels the whole run. The other
+	// tests keep the result they already had, and the tests that didn't
+	// finish yet are queued again without a result.
+	cancelled := ctx.Err() != nil
+	lines := combinedOutput.Lines()
+	requeue := make([]bool, len(items))
+	var buildFailed *TestItem
+	for i, item := range items {
+		itemCtx := ctx
+		if cancelled && !item.cancelledByUser() {
+			itemCtx = context.Background()
+			if _, ok := results[item.Info.Name]; !ok {
+				item.mu.Lock()
+				item.restart = true
+				item.mu.Unlock()
+			}
+		}
+
+		var failed bool
+		requeue[i], failed = r.finishRun(itemCtx, item, inv, results[item.Info.Name], err, lines)
+		if failed && buildFailed == nil {
+			buildFailed = item
+		}
+	}
+	if buildFailed != nil {
+		r.failPackageBuild(buildFailed)
+	}
+
+	// Re-queue before finishing, so the runner never looks idle in between
+	for i, item := range items {
+		if requeue[i] {
+			r.queue(item)
+		} else {
+			r.notifyFinished(item)
+		}
+	}
+
+	r.testFinished()
+	for _, item := range items {
+		r.pruneLogs(item)
+	}
+	r.pruneCombinedLogs(first.Info.Package, combinedLog)
+}
+
+// cancelledByUser reports whether the run of a test was cancelled because the
+// test itself was stopped or restarted
+func (t *TestItem) cancelledByUser() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped || t.restart
+}
+
+// pruneCombinedLogs deletes the combined logs of a package that exceed the
+// retention
+func (r *TestRunner) pruneCombinedLogs(pkg, current string) {
+	r.mu.Lock()
+	keep, maxAge := r.logKeep, r.logMaxAge
+	r.mu.Unlock()
+	if keep == 0 && maxAge == 0 {
+		return
+	}
+	pruneLogs(r.logDir, TestInfo{Name: packageLogName, Package: pkg}, current, keep, maxAge, time.Now())
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyPackageEvents(t *testing.T) {
+	input := `{"Action":"start","Package":"pkg"}
+{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Package":"pkg","Test":"TestA/sub","Output":"=== RUN   TestA/sub\n"}
+{"Action":"pass","Package":"pkg","Test":"TestA/sub"}
+{"Action":"fail","Package":"pkg","Test":"TestA"}
+{"Action":"output","Package":"pkg","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"skip","Package":"pkg","Test":"TestB"}
+{"Action":"output","Package":"pkg","Test":"TestOther","Output":"=== RUN   TestOther\n"}
+{"Action":"output","Package":"pkg","Output":"FAIL\n"}
+`
+	var combined, a, b bytes.Buffer
+	results := copyPackageEvents(strings.NewReader(input), &combined, map[string]io.Writer{"TestA": &a, "TestB": &b, "TestC": io.Discard})
+
+	if len(results) != 2 || results["TestA"] != "fail" || results["TestB"] != "skip" {
+		t.Errorf("Unexpected results %v", results)
+	}
+	if expected := "=== RUN   TestA\n=== RUN   TestA/sub\n"; a.String() != expected {
+		t.Errorf("Expected the output of TestA and its subtest, got %q", a.String())
+	}
+	if expected := "=== RUN   TestB\n"; b.String() != expected {
+		t.Errorf("Expected the output of TestB, got %q", b.String())
+	}
+	if expected := "=== RUN   TestA\n=== RUN   TestA/sub\n=== RUN   TestB\n=== RUN   TestOther\nFAIL\n"; combined.String() != expected {
+		t.Errorf("Expected all output in the combined log, got %q", combined.String())
+	}
+}
+
+func TestRunPackage(t *testing.T) {
+	var items []*TestItem
+	for _, name := range []string{"TestQuickPass", "TestFail", "TestSkipped"} {
+		items = append(items, &TestItem{Info: TestInfo{Name: name}})
+	}
+	subtest := &TestItem{Info: TestInfo{Name: "TestTable/literal_case"}}
+	items = append(items, subtest)
+
+	// Queue the tests before any of them starts, so they run together
+	logDir := t.TempDir()
+	r := NewTestRunner("testdata", logDir, 0, time.Minute)
+	r.SetPackageRuns(true)
+	r.SetTestList(&items)
+	for _, item := range items {
+		r.QueueTest(item)
+	}
+	r.SetMaxParallel(1)
+	if !r.WaitIdle(time.Minute) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	expected := map[string]TestStatus{
+		"TestQuickPass":          StatusPassed,
+		"TestFail":               StatusFailed,
+		"TestSkipped":            StatusSkipped,
+		"TestTable/literal_case": StatusPassed,
+	}
+	for _, item := range items {
+		status, logFile := item.LogState()
+		data, _ := os.ReadFile(logFile)
+		if status != expected[item.Info.Name] {
+			t.Errorf("Expected %s to be %s, got %s:\n%s", item.Info.Name, expected[item.Info.Name], status, data)
+		}
+		if !strings.Contains(string(data), "=== RUN   "+item.Info.Name) {
+			t.Errorf("Expected the output of %s in its log, got:\n%s", item.Info.Name, data)
+		}
+	}
+
+	// The log of a test only has its own output
+	_, logFile := items[0].LogState()
+	if data, _ := os.ReadFile(logFile); strings.Contains(string(data), "TestFail") {
+		t.Errorf("Expected only the output of TestQuickPass in its log, got:\n%s", data)
+	}
+
+	// The tests ran in a single invocation with a combined log, and the
+	// subtest ran on its own
+	logs, _ := filepath.Glob(filepath.Join(logDir, packageLogName+".*.log"))
+	if len(logs) != 1 {
+		t.Fatalf("Expected a combined log, got %v", logs)
+	}
+	data, _ := os.ReadFile(logs[0])
+	for _, name := range []string{"TestQuickPass", "TestFail", "TestSkipped"} {
+		if !strings.Contains(string(data), "=== RUN   "+name) {
+			t.Errorf("Expected the output of %s in the combined log, got:\n%s", name, data)
+		}
+	}
+	if strings.Contains(string(data), "TestTable") {
+		t.Errorf("Expected the subtest to run on its own, got:\n%s", data)
+	}
+}
+
+func TestStopPackageRunTest(t *testing.T) {
+	var items []*TestItem
+	for _, name := range []string{"TestQuickPass", "TestSlowPass", "TestWithOutput"} {
+		items = append(items, &TestItem{Info: TestInfo{Name: name}})
+	}
+	quick, slow, other := items[0], items[1], items[2]
+
+	r := NewTestRunner("testdata", t.TempDir(), 0, time.Minute)
+	r.SetPackageRuns(true)
+	r.SetTestList(&items)
+	for _, item := range items {
+		r.QueueTest(item)
+	}
+	r.SetMaxParallel(1)
+
+	// Stop the slow test once it runs, after the quick test finished
+	deadline := time.Now().Add(time.Minute)
+	for {
+		if output := slow.Output(); output != nil && strings.Contains(strings.Join(output.Lines(), "\n"), "takes 2 seconds") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the slow test to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.StopTest(slow)
+	if !r.WaitIdle(time.Minute) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	// Only the stopped test fails, and the test that didn't run yet ran
+	// again on its own without counting the cancelled run
+	expected := map[*TestItem]TestStatus{quick: StatusPassed, slow: StatusFailed, other: StatusPassed}
+	for item, status := range expected {
+		if got, logFile := item.LogState(); got != status {
+			data, _ := os.ReadFile(logFile)
+			t.Errorf("Expected %s to be %s, got %s:\n%s", item.Info.Name, status, got, data)
+		}
+	}
+	if runs, _ := other.FlakeRate(); runs != 1 {
+		t.Errorf("Expected a single run of %s, got %d", other.Info.Name, runs)
+	}
+}
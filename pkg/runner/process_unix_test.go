@@ -0,0 +1,70 @@
+//go:build !windows
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStopTestKillsChildren(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	script := filepath.Join(dir, "test.sh")
+	if err := os.WriteFile(script, []byte("sleep 30 &\necho $! > "+pidFile+"\nwait\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &TestItem{Info: TestInfo{Name: "TestChild"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(dir, t.TempDir(), 1, 0)
+	r.SetTestCommand("sh " + script)
+	r.SetTestList(&tests)
+	r.QueueTest(item)
+
+	// Wait for the child to be spawned
+	var pid int
+	deadline := time.Now().Add(5 * time.Second)
+	for pid == 0 {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the test to spawn a child process")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r.StopTest(item)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	// The child is gone once signalling it fails (or it's only a zombie
+	// that wasn't reaped yet)
+	deadline = time.Now().Add(5 * time.Second)
+	for syscall.Kill(pid, 0) == nil && !isZombie(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatal("Expected the child process to be killed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// isZombie reports whether a process has exited, but wasn't reaped yet
+func isZombie(pid int) bool {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is enclosed in parentheses
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
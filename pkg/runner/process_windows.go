@@ -0,0 +1,23 @@
+//go:build windows
+
+package runner
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup makes cancelling the command kill its whole process tree,
+// so the compiled test binary and any processes it spawned don't outlive the
+// go command
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+		if err != nil {
+			// Fall back to killing only the go command
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = processWaitDelay
+}
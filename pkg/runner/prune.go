@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SetLogRetention sets how many logs are kept for each test (zero to keep
+// all logs) and how old logs may get (zero to keep them forever). Older logs
+// are deleted when the test finishes.
+func (r *TestRunner) SetLogRetention(keep int, maxAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logKeep = max(keep, 0)
+	r.logMaxAge = max(maxAge, 0)
+}
+
+// pruneLogs deletes the logs of a finished test that exceed the retention
+func (r *TestRunner) pruneLogs(item *TestItem) {
+	r.mu.Lock()
+	keep, maxAge := r.logKeep, r.logMaxAge
+	r.mu.Unlock()
+	if keep == 0 && maxAge == 0 {
+		return
+	}
+
+	item.mu.Lock()
+	info, current := item.Info, item.LogFile
+	item.mu.Unlock()
+
+	pruneLogs(r.logDir, info, current, keep, maxAge, time.Now())
+}
+
+// pruneLogs keeps the most recent logs of a test and deletes the logs older
+// than the maximum age, together with their coverage profiles. The current
+// log is never deleted. Errors are ignored, as the logs are deleted again
+// when the test finishes the next time.
+func pruneLogs(logDir string, info TestInfo, current string, keep int, maxAge time.Duration, now time.Time) {
+	matches, err := filepath.Glob(filepath.Join(logDir, LogFilePrefix(info)+".*.log"))
+	if err != nil {
+		return
+	}
+
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var logs []logFile
+	for _, match := range matches {
+		if stat, err := os.Stat(match); err == nil {
+			logs = append(logs, logFile{match, stat.ModTime()})
+		}
+	}
+
+	// Newest first, so the logs to keep come first
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].modTime.After(logs[j].modTime)
+	})
+
+	for i, log := range logs {
+		if log.path == current {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(log.modTime) > maxAge
+		if tooMany || tooOld {
+			os.Remove(log.path)
+			os.Remove(CoverProfileFile(log.path))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneLogs(t *testing.T) {
+	logDir := t.TempDir()
+	info := TestInfo{Name: "TestFoo"}
+	now := time.Now()
+
+	// Logs of TestFoo from 1 to 5 hours old and a log of another test
+	var logs []string
+	for i := 1; i <= 5; i++ {
+		logs = append(logs, createLog(t, logDir, fmt.Sprintf("TestFoo.run%d.log", i), now.Add(-time.Duration(i)*time.Hour)))
+	}
+	other := createLog(t, logDir, "TestFooBar.run1.log", now.Add(-10*time.Hour))
+	if err := os.WriteFile(CoverProfileFile(logs[4]), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest log is current, so it's kept regardless of the retention
+	pruneLogs(logDir, info, logs[4], 2, 0, now)
+	assertExists(t, logs[0], logs[1], logs[4], other)
+	assertRemoved(t, logs[2], logs[3])
+
+	pruneLogs(logDir, info, "", 0, 90*time.Minute, now)
+	assertExists(t, logs[0], other)
+	assertRemoved(t, logs[1], logs[4], CoverProfileFile(logs[4]))
+}
+
+func createLog(t *testing.T, logDir, name string, modTime time.Time) string {
+	t.Helper()
+	path := filepath.Join(logDir, name)
+	if err := os.WriteFile(path, []byte("ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func assertExists(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be kept", filepath.Base(path))
+		}
+	}
+}
+
+func assertRemoved(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("Expected %s to be deleted", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,1674 @@
+// Package runner discovers Go tests and runs them with a configurable amount
+// of parallelism, writing the output of each test to a log file.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TestStatus represents the current state of a test
+type TestStatus int
+
+const (
+	StatusIdle TestStatus = iota
+	StatusQueued
+	StatusRunning
+	StatusPassed
+	StatusFailed
+	StatusSkipped
+)
+
+// String returns the name of the status
+func (s TestStatus) String() string {
+	switch s {
+	case StatusIdle:
+		return "idle"
+	case StatusQueued:
+		return "queued"
+	case StatusRunning:
+		return "running"
+	case StatusPassed:
+		return "passed"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// Finished reports whether the status is the result of a finished run
+func (s TestStatus) Finished() bool {
+	return s == StatusPassed || s == StatusFailed || s == StatusSkipped
+}
+
+// DefaultTestTimeout is the timeout used when no test timeout is set
+var DefaultTestTimeout = 30 * time.Minute
+
+// DefaultLogTimeFormat is the timestamp format used in log file names
+const DefaultLogTimeFormat = "20060102-150405.000"
+
+// DefaultTestCommand is the command template used to run a single test
+const DefaultTestCommand = "go test " + managedTestArgs
+
+// managedTestArgs are the arguments that select the test to run. They are
+// appended to command templates that end with "--" and don't have {pkg},
+// {test} or {run} placeholders, such as "gotestsum --format testname --".
+const managedTestArgs = "-timeout {timeout} -v -run {test} {pkg}"
+
+// processWaitDelay is how long to wait for the output of a cancelled test to
+// be closed, as processes that escaped the kill may keep it open
+const processWaitDelay = 2 * time.Second
+
+// BenchmarkCommand is the command template used to run a single benchmark.
+// Tests are skipped using -run ^$, so only the benchmark runs.
+const BenchmarkCommand = "go test -timeout {timeout} -v -run ^$ -bench {test} -benchmem {pkg}"
+
+// TestItem represents a test in the list with its current state
+type TestItem struct {
+	Info         TestInfo
+	Status       TestStatus
+	Selected     bool
+	LogFile      string
+	Stale        bool // Sources changed after the last run finished
+	BuildFailed  bool // The last run failed because the package didn't build
+	QueuedAt     time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	LastDuration time.Duration // Duration of the last completed run
+	Batch        int           // Batch in which the test was last queued
+	Priority     int           // Queued tests with a higher priority start first
+	Runs         int           // Number of completed runs since the tally was reset
+	Failures     int           // Number of failed runs since the tally was reset
+	Attempt      int           // Attempt of the current run when failed runs are retried (1 for the first run)
+	Coverage     float64       // Percentage of statements covered by the last run
+	CoverProfile string        // Coverage profile of the last run (empty without coverage)
+	Bench        *BenchResult  // Most recent result of a benchmark (nil if there is none)
+	repeat       int           // Number of times the test is re-queued after finishing
+	untilFail    bool          // Re-queue the test after each run until it fails
+	restart      bool          // Re-queue the test once the cancelled run has stopped
+	stopped      bool          // The run was cancelled by stopping the test
+	verbose      bool          // Force verbose output for the next run
+	output       *OutputBuffer // In-memory output of the current run
+	cancel       context.CancelFunc
+	mu           sync.Mutex
+}
+
+// Duration returns the appropriate duration based on status
+func (t *TestItem) Duration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.Status {
+	case StatusQueued:
+		return time.Since(t.QueuedAt)
+	case StatusRunning:
+		return time.Since(t.StartedAt)
+	case StatusPassed, StatusFailed, StatusSkipped:
+		return t.FinishedAt.Sub(t.StartedAt)
+	default:
+		return 0
+	}
+}
+
+// LogState returns the status and log file of the test
+func (t *TestItem) LogState() (TestStatus, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.Status, t.LogFile
+}
+
+// Output returns the in-memory output of the current log file, or nil when
+// the current run hasn't started yet or the output is only on disk
+func (t *TestItem) Output() *OutputBuffer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.output
+}
+
+// FlakeRate returns the number of runs and failures since the tally was reset
+func (t *TestItem) FlakeRate() (runs, failures int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.Runs, t.Failures
+}
+
+// IsUntilFail reports whether the test is run repeatedly until it fails
+func (t *TestItem) IsUntilFail() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.untilFail
+}
+
+// SetStale marks a finished test as stale when the sources of its package
+// were modified after it finished
+func (t *TestItem) SetStale(modified time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Stale = t.Status.Finished() && modified.After(t.FinishedAt)
+}
+
+// IsBuildFailed reports whether the last run failed because the package
+// didn't build
+func (t *TestItem) IsBuildFailed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.Status == StatusFailed && t.BuildFailed
+}
+
+// IsFlaky reports whether the test both passed and failed since the tally
+// was reset
+func (t *TestItem) IsFlaky() bool {
+	runs, failures := t.FlakeRate()
+	return failures > 0 && failures < runs
+}
+
+// TestRunner manages test execution with parallelism control
+type TestRunner struct {
+	testDir       string
+	logDir        string
+	maxParallel   int
+	sequential    bool     // Run one test at a time in the order they're shown
+	fastFirst     bool     // Start the queued tests with the shortest last duration first
+	packageRuns   bool     // Run the queued tests of a package in a single go test invocation
+	race          bool     // Run tests with the race detector
+	cover         bool     // Collect a coverage profile for each run
+	retries       int      // Number of times a failed run is retried
+	testFlags     []string // Extra flags appended to the test command
+	tags          []string // Build tags passed to go test
+	env           []string // Environment variables set for all tests (KEY=VALUE)
+	goos          string   // Target GOOS (empty for the host)
+	goarch        string   // Target GOARCH (empty for the host)
+	testTimeout   time.Duration
+	testCommand   string
+	running       int
+	logTimeFormat string
+	logKeep       int           // Number of logs kept per test (zero to keep all)
+	logMaxAge     time.Duration // Maximum age of logs (zero to keep them forever)
+	outputLines   int           // Maximum number of output lines kept in memory per test
+	batch         int           // Current batch, incremented when tests are queued while idle
+	batchStarted  time.Time     // When the current batch was queued
+	batchFinished time.Time     // When the last test of the current batch finished (zero while running)
+	tests         *[]*TestItem  // Reference to the test list
+	idle          chan struct{} // Closed when the current batch has finished (nil while idle)
+	mu            sync.Mutex
+	onUpdate      func()
+	onFinished    func(*TestItem)
+
+	// Position of the tests in the order they're shown (sequential mode only)
+	sequence map[*TestItem]int
+}
+
+// NewTestRunner creates a new test runner
+func NewTestRunner(testDir string, logDir string, maxParallel int, testTimeout time.Duration) *TestRunner {
+	if testTimeout <= 0 {
+		testTimeout = DefaultTestTimeout
+	}
+	return &TestRunner{
+		testDir:       testDir,
+		logDir:        logDir,
+		maxParallel:   maxParallel,
+		testTimeout:   testTimeout,
+		testCommand:   DefaultTestCommand,
+		logTimeFormat: DefaultLogTimeFormat,
+		outputLines:   DefaultOutputLines,
+	}
+}
+
+// SetOutputLines sets the maximum number of output lines kept in memory for
+// each test. Older lines are only available in the log file.
+func (r *TestRunner) SetOutputLines(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n <= 0 {
+		n = DefaultOutputLines
+	}
+	r.outputLines = n
+}
+
+// SetLogTimeFormat sets the timestamp format used in log file names
+func (r *TestRunner) SetLogTimeFormat(format string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if format == "" {
+		format = DefaultLogTimeFormat
+	}
+	r.logTimeFormat = format
+}
+
+// LogTimeFormat returns the timestamp format used in log file names
+func (r *TestRunner) LogTimeFormat() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logTimeFormat
+}
+
+// SetTestCommand sets the command template used to run tests
+func (r *TestRunner) SetTestCommand(tmpl string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = DefaultTestCommand
+	}
+	r.testCommand = tmpl
+}
+
+// TestSnapshot is a point-in-time copy of the state of a test
+type TestSnapshot struct {
+	Info         TestInfo
+	Status       TestStatus
+	Duration     time.Duration
+	LastDuration time.Duration // Duration of the last completed run
+	Attempt      int           // Attempt of the current or last run (zero if it never ran)
+	Coverage     float64       // Percentage of statements covered by the last run
+	BuildFailed  bool          // The last run failed because the package didn't build
+	LogFile      string
+}
+
+// Snapshot returns a copy of the state of all tests in the test list
+func (r *TestRunner) Snapshot() []TestSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tests == nil {
+		return nil
+	}
+
+	snapshots := make([]TestSnapshot, 0, len(*r.tests))
+	for _, item := range *r.tests {
+		duration := item.Duration()
+		item.mu.Lock()
+		snapshots = append(snapshots, TestSnapshot{
+			Info:         item.Info,
+			Status:       item.Status,
+			Duration:     duration,
+			LastDuration: item.LastDuration,
+			Attempt:      item.Attempt,
+			Coverage:     item.Coverage,
+			BuildFailed:  item.BuildFailed,
+			LogFile:      item.LogFile,
+		})
+		item.mu.Unlock()
+	}
+	return snapshots
+}
+
+// SetUpdateCallback sets the callback for status updates
+func (r *TestRunner) SetUpdateCallback(cb func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onUpdate = cb
+}
+
+// SetFinishedCallback sets the callback that's called when a test has
+// finished and isn't retried or repeated anymore
+func (r *TestRunner) SetFinishedCallback(cb func(*TestItem)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onFinished = cb
+}
+
+// SetTestList sets the reference to the test list
+func (r *TestRunner) SetTestList(tests *[]*TestItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tests = tests
+}
+
+// UpdateTests replaces the tests in the test list and updates the info of the
+// tests that were discovered again. Queued and running tests keep their info,
+// as it's used to run them. The lock is held, so no tests are started from a
+// partly updated list.
+func (r *TestRunner) UpdateTests(tests []*TestItem, infos map[*TestItem]TestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for item, info := range infos {
+		item.mu.Lock()
+		if item.Status != StatusQueued && item.Status != StatusRunning {
+			item.Info = info
+		}
+		item.mu.Unlock()
+	}
+	if r.tests != nil {
+		*r.tests = tests
+	}
+}
+
+// SetMaxParallel updates the max parallel limit
+func (r *TestRunner) SetMaxParallel(n int) {
+	r.mu.Lock()
+	r.maxParallel = n
+	r.mu.Unlock()
+
+	// Try to start more tests if parallelism increased
+	r.tryStartNext()
+}
+
+// GetMaxParallel returns the current max parallel limit
+func (r *TestRunner) GetMaxParallel() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxParallel
+}
+
+// SetSequential enables or disables strictly sequential execution
+func (r *TestRunner) SetSequential(sequential bool) {
+	r.mu.Lock()
+	r.sequential = sequential
+	r.mu.Unlock()
+
+	// Try to start more tests if sequential mode was disabled
+	r.tryStartNext()
+}
+
+// SetSequence sets the order in which queued tests run in sequential mode,
+// which is the order the tests are shown in. Tests that aren't in the
+// sequence run after the others, in list order.
+func (r *TestRunner) SetSequence(items []*TestItem) {
+	sequence := make(map[*TestItem]int, len(items))
+	for i, item := range items {
+		sequence[item] = i
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sequence = sequence
+}
+
+// IsSequential returns whether tests are run strictly sequentially
+func (r *TestRunner) IsSequential() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sequential
+}
+
+// SetFastFirst enables or disables starting the queued tests that took the
+// shortest time in their last run first
+func (r *TestRunner) SetFastFirst(fastFirst bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fastFirst = fastFirst
+}
+
+// IsFastFirst returns whether the fastest queued tests are started first
+func (r *TestRunner) IsFastFirst() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fastFirst
+}
+
+// SetRace enables or disables the race detector for tests that are started
+// from now on
+func (r *TestRunner) SetRace(race bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.race = race
+}
+
+// IsRace returns whether tests are run with the race detector
+func (r *TestRunner) IsRace() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.race
+}
+
+// SetTestTimeout sets the timeout of tests that are started from now on.
+// Tests that are already running keep their timeout. A zero or negative
+// timeout uses DefaultTestTimeout.
+func (r *TestRunner) SetTestTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+	r.testTimeout = timeout
+}
+
+// GetTestTimeout returns the timeout of tests that are started from now on
+func (r *TestRunner) GetTestTimeout() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.testTimeout
+}
+
+// SetTestFlags sets extra flags that are appended to the test command of
+// tests that are started from now on. They come last, so they take
+// precedence over the flags of the command template.
+func (r *TestRunner) SetTestFlags(flags []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.testFlags = slices.Clone(flags)
+}
+
+// GetTestFlags returns the extra flags that are appended to the test command
+func (r *TestRunner) GetTestFlags() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.testFlags)
+}
+
+// SetTags sets the build tags that are passed to go test with -tags. They
+// should match the tags used to discover the tests.
+func (r *TestRunner) SetTags(tags []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tags = slices.Clone(tags)
+}
+
+// GetTags returns the build tags that are passed to go test
+func (r *TestRunner) GetTags() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.tags)
+}
+
+// SetRetries sets the number of times a failed run is retried before the
+// test is reported as failed
+func (r *TestRunner) SetRetries(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries = max(n, 0)
+}
+
+// GetRetries returns the number of times a failed run is retried
+func (r *TestRunner) GetRetries() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.retries
+}
+
+// SetCover enables or disables coverage collection for tests that are
+// started from now on
+func (r *TestRunner) SetCover(cover bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cover = cover
+}
+
+// IsCover returns whether a coverage profile is collected for each run
+func (r *TestRunner) IsCover() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cover
+}
+
+// SetTargetPlatform sets the GOOS and GOARCH used to build tests. Empty
+// values use the host platform. Tests for another platform are only built,
+// not executed.
+func (r *TestRunner) SetTargetPlatform(goos, goarch string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.goos = goos
+	r.goarch = goarch
+}
+
+// GetCrossPlatform returns the target platform (e.g. "windows/arm64") if
+// tests are built for another platform, or an empty string otherwise
+func (r *TestRunner) GetCrossPlatform() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !isCrossPlatform(r.goos, r.goarch) {
+		return ""
+	}
+	return targetOS(r.goos) + "/" + targetArch(r.goarch)
+}
+
+// GetRunningCount returns the number of running tests
+func (r *TestRunner) GetRunningCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// CurrentBatch returns the batch of the most recently queued tests
+func (r *TestRunner) CurrentBatch() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batch
+}
+
+// BatchElapsed returns the wall time of the current batch and whether all of
+// its tests have finished. While the batch runs, it's the time since the batch
+// was queued.
+func (r *TestRunner) BatchElapsed() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.batchStarted.IsZero() {
+		return 0, false
+	}
+	if r.running > 0 || r.queuedCount() > 0 {
+		return time.Since(r.batchStarted), false
+	}
+
+	// Removing the last queued tests also ends the batch
+	if r.batchFinished.IsZero() {
+		r.batchFinished = time.Now()
+	}
+	return r.batchFinished.Sub(r.batchStarted), true
+}
+
+// BatchProgress returns the number of finished tests of the current batch and
+// the number of tests in it. Tests that are queued while the batch runs join
+// it, so they extend the batch. Tests that are removed from the queue leave
+// the batch, and a test that is retried or repeated counts as finished once
+// its last run finished.
+func (r *TestRunner) BatchProgress() (done, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tests == nil {
+		return 0, 0
+	}
+	for _, item := range *r.tests {
+		item.mu.Lock()
+		if item.Batch == r.batch {
+			switch {
+			case item.Status.Finished():
+				done++
+				total++
+			case item.Status == StatusQueued || item.Status == StatusRunning:
+				total++
+			}
+		}
+		item.mu.Unlock()
+	}
+	return done, total
+}
+
+// GetQueuedCount returns the number of queued tests
+func (r *TestRunner) GetQueuedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queuedCount()
+}
+
+// queuedCount returns the number of queued tests. The caller must hold r.mu.
+func (r *TestRunner) queuedCount() int {
+	if r.tests == nil {
+		return 0
+	}
+	count := 0
+	for _, item := range *r.tests {
+		item.mu.Lock()
+		if item.Status == StatusQueued {
+			count++
+		}
+		item.mu.Unlock()
+	}
+	return count
+}
+
+// QueueTest marks a test as queued for execution
+func (r *TestRunner) QueueTest(item *TestItem) {
+	r.QueueRepeated(item, 1)
+}
+
+// queue marks a test as queued for execution without resetting its tally
+func (r *TestRunner) queue(item *TestItem) {
+	r.mu.Lock()
+	logTimeFormat := r.logTimeFormat
+
+	// Tests queued while others are still queued or running are part of the
+	// same batch, otherwise a new batch starts
+	if r.running == 0 && r.queuedCount() == 0 {
+		r.batch++
+		r.batchStarted = time.Now()
+		r.batchFinished = time.Time{}
+		r.idle = make(chan struct{})
+	}
+	batch := r.batch
+	r.mu.Unlock()
+
+	item.mu.Lock()
+	if item.Status == StatusRunning || item.Status == StatusQueued {
+		item.mu.Unlock()
+		return
+	}
+
+	item.Status = StatusQueued
+	item.Stale = false
+	item.Batch = batch
+	item.QueuedAt = time.Now()
+
+	// Create log file path in log directory
+	item.LogFile = uniqueLogFile(r.logDir, item.Info, time.Now().Format(logTimeFormat))
+	item.output = nil
+	item.mu.Unlock()
+
+	r.notifyUpdate()
+	r.tryStartNext()
+}
+
+// PrioritizeTests moves tests to the front of the queue, queuing the tests
+// that aren't queued yet. The tests keep their order among themselves and go
+// ahead of the tests that were prioritized before.
+func (r *TestRunner) PrioritizeTests(items []*TestItem) {
+	r.mu.Lock()
+	priority := 1
+	if r.tests != nil {
+		for _, item := range *r.tests {
+			item.mu.Lock()
+			if item.Status == StatusQueued {
+				priority = max(priority, item.Priority+1)
+			}
+			item.mu.Unlock()
+		}
+	}
+	r.mu.Unlock()
+
+	// The priority is set before queuing, so a test that is queued now
+	// can't be passed by a test that was queued before
+	for _, item := range items {
+		item.mu.Lock()
+		if item.Status != StatusRunning {
+			item.Priority = priority
+		}
+		item.mu.Unlock()
+		r.QueueTest(item)
+	}
+}
+
+// QueueRepeated resets the run tally and queues a test to run the given
+// number of times in a row
+func (r *TestRunner) QueueRepeated(item *TestItem, times int) {
+	item.mu.Lock()
+	if item.Status == StatusRunning || item.Status == StatusQueued {
+		item.mu.Unlock()
+		return
+	}
+	item.Runs = 0
+	item.Failures = 0
+	item.Attempt = 1
+	item.repeat = max(times-1, 0)
+	item.untilFail = false
+	item.mu.Unlock()
+
+	r.queue(item)
+}
+
+// QueueUntilFail resets the run tally and queues a test to run again after
+// each run until a run fails (or is skipped). Failed runs aren't retried, so
+// the first failure stops it.
+func (r *TestRunner) QueueUntilFail(item *TestItem) {
+	item.mu.Lock()
+	if item.Status == StatusRunning || item.Status == StatusQueued {
+		item.mu.Unlock()
+		return
+	}
+	item.Runs = 0
+	item.Failures = 0
+	item.Attempt = 1
+	item.repeat = 0
+	item.untilFail = true
+	item.mu.Unlock()
+
+	r.queue(item)
+}
+
+// QueueVerbose queues a test once with verbose output, regardless of the
+// test command, so a failure can be debugged with the full output
+func (r *TestRunner) QueueVerbose(item *TestItem) {
+	item.mu.Lock()
+	if item.Status == StatusRunning || item.Status == StatusQueued {
+		item.mu.Unlock()
+		return
+	}
+	item.verbose = true
+	item.mu.Unlock()
+
+	r.QueueTest(item)
+}
+
+// StopTest stops a running or queued test
+func (r *TestRunner) StopTest(item *TestItem) {
+	item.mu.Lock()
+
+	// Stopping a test also cancels any pending repeats, restarts and overrides
+	item.repeat = 0
+	item.untilFail = false
+	item.restart = false
+	item.verbose = false
+
+	switch item.Status {
+	case StatusQueued:
+		// Just reset status to idle
+		item.Status = StatusIdle
+		item.Priority = 0
+		item.mu.Unlock()
+
+		// Removing the last queued test may finish the batch
+		r.mu.Lock()
+		r.signalIdle()
+		r.mu.Unlock()
+		r.notifyUpdate()
+		return
+
+	case StatusRunning:
+		// Cancel the running test
+		if item.cancel != nil {
+			item.stopped = true
+			item.cancel()
+		}
+	}
+	item.mu.Unlock()
+}
+
+// StopAll stops all the given tests. Queued tests are removed from the queue
+// first, so no new tests are started while the running tests are cancelled.
+func (r *TestRunner) StopAll(items []*TestItem) {
+	for _, status := range []TestStatus{StatusQueued, StatusRunning} {
+		for _, item := range items {
+			item.mu.Lock()
+			matches := item.Status == status
+			item.mu.Unlock()
+			if matches {
+				r.StopTest(item)
+			}
+		}
+	}
+}
+
+// StopEverything stops all tests in the list: queued tests are removed from
+// the queue and running tests are cancelled. The queue is emptied while
+// holding the lock, so no queued test can be started in the meantime. It
+// returns the number of tests that were queued and running.
+func (r *TestRunner) StopEverything() (queued, running int) {
+	r.mu.Lock()
+	if r.tests == nil {
+		r.mu.Unlock()
+		return 0, 0
+	}
+	for _, item := range *r.tests {
+		item.mu.Lock()
+		item.repeat = 0
+		item.untilFail = false
+		item.restart = false
+		item.verbose = false
+		switch item.Status {
+		case StatusQueued:
+			item.Status = StatusIdle
+			item.Priority = 0
+			queued++
+		case StatusRunning:
+			// The running count drops once the cancelled run has stopped
+			if item.cancel != nil {
+				item.stopped = true
+				item.cancel()
+			}
+			running++
+		}
+		item.mu.Unlock()
+	}
+	r.signalIdle()
+	r.mu.Unlock()
+
+	r.notifyUpdate()
+	return queued, running
+}
+
+// Shutdown stops all tests and waits until the processes of the running tests
+// have exited and their log files are closed. It returns the error of the
+// context when tests are still running after it's done.
+func (r *TestRunner) Shutdown(ctx context.Context) error {
+	r.StopEverything()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for r.GetRunningCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Idle returns a channel that's closed once no tests are queued or running
+// anymore. When the runner is idle already, the channel is closed.
+func (r *TestRunner) Idle() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.idle == nil {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	return r.idle
+}
+
+// signalIdle closes the idle channel when no tests are queued or running
+// anymore. The caller must hold r.mu.
+func (r *TestRunner) signalIdle() {
+	if r.idle != nil && r.running == 0 && r.queuedCount() == 0 {
+		close(r.idle)
+		r.idle = nil
+	}
+}
+
+// WaitIdle waits until no tests are running anymore. It returns false when
+// tests are still running after the timeout.
+func (r *TestRunner) WaitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for r.GetRunningCount() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// RestartTest cancels a running test and queues it again once the cancelled
+// process has stopped. Tests that aren't running are simply queued.
+func (r *TestRunner) RestartTest(item *TestItem) {
+	item.mu.Lock()
+	if item.Status != StatusRunning {
+		item.mu.Unlock()
+		r.QueueTest(item)
+		return
+	}
+
+	item.restart = true
+	if item.cancel != nil {
+		item.cancel()
+	}
+	item.mu.Unlock()
+}
+
+// tryStartNext attempts to start the next queued test from the list
+func (r *TestRunner) tryStartNext() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tests == nil {
+		return
+	}
+
+	// In sequential mode only a single test may run at a time
+	limit := r.maxParallel
+	if r.sequential {
+		limit = min(limit, 1)
+	}
+
+	// Start queued tests until the limit is reached
+	for r.running < limit {
+		nextItem := r.nextQueuedItem()
+		if nextItem == nil {
+			break
+		}
+
+		// The other queued tests of the package may run along with it
+		items := []*TestItem{nextItem}
+		if r.packageRuns {
+			items = append(items, r.queuedPackageTests(nextItem)...)
+		}
+
+		// Mark the tests as running right away, so they aren't picked again.
+		// Tests that run together are stopped together.
+		ctx, cancel := context.WithCancel(context.Background())
+		markRunning(items, cancel)
+
+		r.running++
+		if len(items) > 1 {
+			go r.runPackage(ctx, items)
+		} else {
+			go r.runTest(ctx, nextItem)
+		}
+	}
+}
+
+// markRunning marks tests as running with the function that cancels their run
+func markRunning(items []*TestItem, cancel context.CancelFunc) {
+	for _, item := range items {
+		item.mu.Lock()
+		item.Status = StatusRunning
+		item.StartedAt = time.Now()
+		item.Priority = 0
+		item.cancel = cancel
+		item.stopped = false
+		item.mu.Unlock()
+	}
+}
+
+// queuedTest is a queued test with the properties that determine when it
+// starts
+type queuedTest struct {
+	item     *TestItem
+	queuedAt time.Time
+	priority int
+	expected time.Duration // Expected duration of the run (fast-first mode only)
+	position int           // Position in the sequence (sequential mode only)
+}
+
+// nextQueuedItem returns the queued test that should start next. Tests with
+// the highest priority go first. Among those, tests are started in the order
+// they were queued, so tests near the bottom of the list don't starve. In
+// sequential mode the order of the sequence is used instead. In fast-first mode, the
+// tests with the shortest expected duration go before that. The caller must
+// hold r.mu.
+func (r *TestRunner) nextQueuedItem() *TestItem {
+	var queued []queuedTest
+	var known []time.Duration
+	for i, item := range *r.tests {
+		position, ok := r.sequence[item]
+		if !ok {
+			position = len(r.sequence) + i
+		}
+
+		item.mu.Lock()
+		if item.LastDuration > 0 {
+			known = append(known, item.LastDuration)
+		}
+		if item.Status == StatusQueued {
+			queued = append(queued, queuedTest{
+				item:     item,
+				queuedAt: item.QueuedAt,
+				priority: item.Priority,
+				expected: item.LastDuration,
+				position: position,
+			})
+		}
+		item.mu.Unlock()
+	}
+
+	// Tests that never completed a run are expected to take the median
+	// duration of all tests, so they're neither postponed nor preferred
+	if r.fastFirst && len(known) > 0 {
+		slices.Sort(known)
+		median := known[len(known)/2]
+		for i := range queued {
+			if queued[i].expected == 0 {
+				queued[i].expected = median
+			}
+		}
+	}
+
+	var next *queuedTest
+	for i := range queued {
+		if next == nil || r.startsBefore(queued[i], *next) {
+			next = &queued[i]
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	return next.item
+}
+
+// startsBefore returns whether queued test a should start before b, where a
+// comes after b in the list. The caller must hold r.mu.
+func (r *TestRunner) startsBefore(a, b queuedTest) bool {
+	switch {
+	case a.priority != b.priority:
+		return a.priority > b.priority
+	case r.fastFirst && a.expected != b.expected:
+		return a.expected < b.expected
+	case r.sequential:
+		return a.position < b.position
+	default:
+		return a.queuedAt.Before(b.queuedAt)
+	}
+}
+
+// runTest executes a single test
+func (r *TestRunner) runTest(ctx context.Context, item *TestItem) {
+	r.notifyUpdate()
+
+	// Create log file
+	logFile, err := os.Create(item.LogFile)
+	if err != nil {
+		item.failStart()
+		r.notifyFinished(item)
+		r.testFinished()
+		return
+	}
+	defer logFile.Close()
+
+	// Stream the output to memory as well, so it can be shown without
+	// re-reading the log file
+	r.mu.Lock()
+	output := NewOutputBuffer(r.outputLines)
+	r.mu.Unlock()
+	item.mu.Lock()
+	item.output = output
+	item.mu.Unlock()
+	out := io.MultiWriter(logFile, output)
+
+	// The verbose override only applies to a single run
+	item.mu.Lock()
+	verbose := item.verbose
+	item.verbose = false
+	item.mu.Unlock()
+
+	inv := r.newInvocation(ctx, item.Info, item.Info.RunPattern(), item.LogFile, verbose, out)
+	result := ""
+	if inv.useJSON {
+		err = runWithTestEvents(inv.cmd, out, item.Info.Name, &result)
+	} else {
+		err = inv.cmd.Run()
+	}
+	output.Trim()
+
+	requeue, buildFailed := r.finishRun(ctx, item, inv, result, err, output.Lines())
+	if buildFailed {
+		r.failPackageBuild(item)
+	}
+
+	// Re-queue before finishing, so the runner never looks idle in between
+	if requeue {
+		r.queue(item)
+	} else {
+		r.notifyFinished(item)
+	}
+
+	r.testFinished()
+	r.pruneLogs(item)
+}
+
+// failStart marks a test as failed when its run couldn't be started. Pending
+// repeats, retries and restarts are dropped, so they don't apply to the next
+// run.
+func (t *TestItem) failStart() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Status = StatusFailed
+	t.FinishedAt = time.Now()
+	t.Attempt = 1
+	t.repeat = 0
+	t.untilFail = false
+	t.restart = false
+	t.cancel = nil
+}
+
+// invocation is a prepared go test command and the settings that determine
+// how its result is interpreted
+type invocation struct {
+	cmd          *exec.Cmd
+	useJSON      bool   // The command reports JSON events
+	crossCompile bool   // The tests are only built for another platform
+	coverProfile string // Coverage profile that is written (empty without coverage)
+	retries      int
+}
+
+// newInvocation prepares the command that runs the tests matching the pattern
+// in the package of info with the active settings. The log file determines
+// the name of the coverage profile. Warnings are written to out, which also
+// receives the output of the command.
+func (r *TestRunner) newInvocation(ctx context.Context, info TestInfo, pattern, logFile string, verbose bool, out io.Writer) invocation {
+	// Determine the package path for go test, which runs in the root of the
+	// module of the package
+	pkgPath := PackagePath(info.ModulePackage())
+
+	r.mu.Lock()
+	args := expandTestCommand(r.commandTemplate(info), pkgPath, pattern, r.testTimeout)
+	goos, goarch, race, cover, retries := r.goos, r.goarch, r.race, r.cover, r.retries
+	testFlags, tags, globalEnv := r.testFlags, r.tags, r.env
+	r.mu.Unlock()
+
+	if race {
+		args = withRace(args)
+	}
+	coverProfile := ""
+	if cover {
+		coverProfile = CoverProfileFile(logFile)
+		args = withCover(args, coverProfile)
+	}
+	if verbose {
+		args = withVerbose(args)
+	}
+
+	// Tests for another platform can't be executed, so only build them
+	crossCompile := isCrossPlatform(goos, goarch)
+	if crossCompile {
+		args = []string{"go", "test", "-c", "-o", os.DevNull, pkgPath}
+		fmt.Fprintf(out, "Build check for GOOS=%s GOARCH=%s: tests are compiled but not executed\n\n", targetOS(goos), targetArch(goarch))
+	}
+	args = withTags(args, tags)
+
+	// Extra flags come last, so they override the flags of the template
+	args = append(args, testFlags...)
+
+	// Decode the result of go test from its JSON events, so the status
+	// reflects the test itself rather than only the exit code
+	useJSON := false
+	if !crossCompile {
+		args, useJSON = withJSON(args)
+	}
+
+	// Later variables take precedence, so the variables of the package
+	// override the global variables, which override the inherited ones
+	env, err := testEnv(r.testDir, info.Package, globalEnv)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: %v\n\n", err)
+	}
+	if goos != "" || goarch != "" {
+		env = append(env, "GOOS="+targetOS(goos), "GOARCH="+targetArch(goarch))
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Join(r.testDir, info.Module)
+	setProcessGroup(cmd)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return invocation{
+		cmd:          cmd,
+		useJSON:      useJSON,
+		crossCompile: crossCompile,
+		coverProfile: coverProfile,
+		retries:      retries,
+	}
+}
+
+// finishRun updates a test after its run ended. The result is the final
+// action reported for the test (empty when there is none), err is the error
+// of the command and lines are its output. It returns whether the test needs
+// to be queued again (when it's restarted, retried or repeated) and whether
+// the run failed because the package didn't build.
+func (r *TestRunner) finishRun(ctx context.Context, item *TestItem, inv invocation, result string, err error, lines []string) (requeue, buildFailed bool) {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+
+	// A restarted test is queued again and the cancelled run isn't counted
+	if item.restart {
+		item.restart = false
+		item.Status = StatusIdle
+		item.cancel = nil
+		return true, false
+	}
+
+	item.FinishedAt = time.Now()
+	item.LastDuration = item.FinishedAt.Sub(item.StartedAt)
+	item.BuildFailed = false
+	if ctx.Err() == context.Canceled {
+		item.Status = StatusFailed
+		item.repeat = 0 // Cancelled tests are never repeated
+		item.untilFail = false
+	} else if result == "fail" {
+		item.Status = StatusFailed
+	} else if result == "pass" {
+		item.Status = StatusPassed
+	} else if result == "skip" {
+		item.Status = StatusSkipped
+	} else if err != nil {
+		// Without a test result (e.g. a build failure) the exit code decides
+		item.Status = StatusFailed
+		item.BuildFailed = inv.crossCompile || isBuildFailure(lines)
+	} else if !inv.crossCompile && loggedSkip(lines, item.Info.Name) {
+		item.Status = StatusSkipped
+	} else {
+		item.Status = StatusPassed
+	}
+	item.Coverage, item.CoverProfile = 0, ""
+	if inv.coverProfile != "" && !inv.crossCompile {
+		if pct, ok := parseCoverage(lines); ok {
+			item.Coverage, item.CoverProfile = pct, inv.coverProfile
+		}
+	}
+	if item.Info.Kind == KindBenchmark {
+		if bench, ok := ParseBenchResult(lines, item.Info.Name); ok {
+			item.Bench = &bench
+		}
+	}
+	// A run that was stopped by the user says nothing about the test, so it
+	// isn't counted (a timeout is)
+	if ctx.Err() != context.Canceled {
+		item.Runs++
+		if item.Status == StatusFailed {
+			item.Failures++
+		}
+	}
+
+	// Failed runs are retried, unless the test was cancelled or didn't
+	// build. A test that passes on a retry is reported as flaky, as its tally
+	// has a failure.
+	attempt := max(item.Attempt, 1)
+	buildFailed = item.BuildFailed
+	retry := item.Status == StatusFailed && ctx.Err() == nil && attempt <= inv.retries && !item.untilFail && !buildFailed
+	if retry {
+		item.Attempt = attempt + 1
+	}
+	if item.Status != StatusPassed {
+		item.untilFail = false
+	}
+	repeat := !retry && (item.repeat > 0 || item.untilFail)
+	if repeat {
+		item.repeat = max(item.repeat-1, 0)
+		item.Attempt = 1
+	}
+	item.cancel = nil
+	return retry || repeat, buildFailed
+}
+
+// loggedSkip checks if the verbose output reports that the test was skipped.
+// It's used for commands that don't report JSON events.
+func loggedSkip(lines []string, testName string) bool {
+	marker := "--- SKIP: " + testName + " ("
+	return slices.ContainsFunc(lines, func(line string) bool {
+		return strings.Contains(line, marker)
+	})
+}
+
+// runWithTestEvents runs a go test -json command, writing the human-readable
+// output to the log file and storing the result of the test
+func runWithTestEvents(cmd *exec.Cmd, logFile io.Writer, testName string, result *string) error {
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// All output must be read before waiting for the command
+	*result = copyTestEvents(stdout, logFile, testName)
+	return cmd.Wait()
+}
+
+// runWithPackageEvents runs a go test -json command of several tests, writing
+// the output of each test to its writer and all output to the combined log.
+// It returns the results of the tests.
+func runWithPackageEvents(cmd *exec.Cmd, combined io.Writer, tests map[string]io.Writer) (map[string]string, error) {
+	cmd.Stdout = nil
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// All output must be read before waiting for the command
+	results := copyPackageEvents(stdout, combined, tests)
+	return results, cmd.Wait()
+}
+
+// targetOS returns the GOOS to build for
+func targetOS(goos string) string {
+	if goos == "" {
+		return runtime.GOOS
+	}
+	return goos
+}
+
+// targetArch returns the GOARCH to build for
+func targetArch(goarch string) string {
+	if goarch == "" {
+		return runtime.GOARCH
+	}
+	return goarch
+}
+
+// isCrossPlatform reports whether the target platform differs from the host
+func isCrossPlatform(goos, goarch string) bool {
+	return targetOS(goos) != runtime.GOOS || targetArch(goarch) != runtime.GOARCH
+}
+
+// PackagePath returns the path of a package relative to the test directory as
+// it's passed to go test, e.g. "./sub/pkg" or "." for the test directory
+// itself
+func PackagePath(pkg string) string {
+	if pkg == "" {
+		return "."
+	}
+	return "./" + filepath.ToSlash(pkg)
+}
+
+// subtestSeparator replaces the slash between a test and its subtests in log
+// file names. It can't appear in a Go identifier, so the logs of subtest
+// TestFoo/Bar can't be mistaken for the logs of TestFoo_Bar.
+const subtestSeparator = '~'
+
+// LogFilePrefix returns the log file name prefix for a test. The package is
+// included, so same-named tests in different packages don't share logs.
+// Characters that aren't safe in file names are replaced.
+func LogFilePrefix(info TestInfo) string {
+	prefix := strings.Map(func(r rune) rune {
+		if r == '/' {
+			return subtestSeparator
+		}
+		return safeFileNameRune(r)
+	}, info.Name)
+	if info.Package != "" {
+		prefix += "@" + strings.Map(safeFileNameRune, filepath.ToSlash(info.Package))
+	}
+	return prefix
+}
+
+// safeFileNameRune replaces a character that isn't safe in file names (or
+// is used as the subtest separator) with an underscore
+func safeFileNameRune(r rune) rune {
+	if r < ' ' || r == subtestSeparator || strings.ContainsRune(`/\:*?"<>|[] `, r) {
+		return '_'
+	}
+	return r
+}
+
+// uniqueLogFile returns a log file path for the test that doesn't exist yet.
+// A counter is added when a log file with the same timestamp already exists.
+func uniqueLogFile(logDir string, info TestInfo, timestamp string) string {
+	prefix := LogFilePrefix(info)
+	logFile := filepath.Join(logDir, fmt.Sprintf("%s.%s.log", prefix, timestamp))
+	for i := 1; ; i++ {
+		if _, err := os.Stat(logFile); os.IsNotExist(err) {
+			return logFile
+		}
+		logFile = filepath.Join(logDir, fmt.Sprintf("%s.%s-%d.log", prefix, timestamp, i))
+	}
+}
+
+// commandTemplate returns the command template used to run a test. The
+// caller must hold r.mu.
+func (r *TestRunner) commandTemplate(info TestInfo) string {
+	if info.Kind == KindBenchmark {
+		return BenchmarkCommand
+	}
+	return r.testCommand
+}
+
+// expandTestCommand splits the command template into arguments like a shell
+// does (so quoted arguments stay together) and substitutes the {pkg}, {test}
+// (or {run}) and {timeout} placeholders. A
+// template of a wrapper that ends with "--" and has no placeholders for the
+// package and test is a prefix, to which the go test arguments are appended.
+func expandTestCommand(tmpl string, pkgPath string, testPattern string, timeout time.Duration) []string {
+	replacer := strings.NewReplacer(
+		"{pkg}", pkgPath,
+		"{test}", testPattern,
+		"{run}", testPattern,
+		"{timeout}", timeout.String(),
+	)
+
+	fields := splitWords(tmpl)
+	if len(fields) == 0 {
+		fields = strings.Fields(DefaultTestCommand)
+	} else if fields[len(fields)-1] == "--" && !strings.Contains(tmpl, "{pkg}") && !strings.Contains(tmpl, "{test}") && !strings.Contains(tmpl, "{run}") {
+		fields = append(fields, strings.Fields(managedTestArgs)...)
+	}
+
+	args := make([]string, len(fields))
+	for i, field := range fields {
+		args[i] = replacer.Replace(field)
+	}
+	return args
+}
+
+// ReproduceCommand returns a shell command that runs the test from the root
+// of its module with the active settings, so it can be pasted into a CI job
+// or a bug report. Tests outside a module use the test directory instead.
+func (r *TestRunner) ReproduceCommand(info TestInfo) string {
+	r.mu.Lock()
+	testCommand, timeout := r.commandTemplate(info), r.testTimeout
+	goos, goarch, race := r.goos, r.goarch, r.race
+	testFlags, tags, globalEnv := r.testFlags, r.tags, r.env
+	r.mu.Unlock()
+
+	// Determine the package path relative to the module root
+	pkgDir, _ := filepath.Abs(filepath.Join(r.testDir, info.Package))
+	pkgPath := "."
+	if root := FindModuleRoot(pkgDir); root != "" {
+		if rel, err := filepath.Rel(root, pkgDir); err == nil && rel != "." {
+			pkgPath = "./" + filepath.ToSlash(rel)
+		}
+	} else {
+		pkgPath = PackagePath(info.Package)
+	}
+
+	var env []string
+	vars, _ := testEnv(r.testDir, info.Package, globalEnv)
+	for _, v := range vars {
+		key, value, _ := strings.Cut(v, "=")
+		env = append(env, key+"="+shellQuote(value))
+	}
+
+	args := expandTestCommand(testCommand, pkgPath, info.RunPattern(), timeout)
+	if race {
+		args = withRace(args)
+	}
+	if isCrossPlatform(goos, goarch) {
+		env = append(env, "GOOS="+targetOS(goos), "GOARCH="+targetArch(goarch))
+		args = []string{"go", "test", "-c", "-o", os.DevNull, pkgPath}
+	} else if len(args) > 1 && args[0] == "go" && args[1] == "test" {
+		// Never report a cached result when reproducing a failure
+		args = append([]string{"go", "test", "-count=1"}, args[2:]...)
+	}
+	args = withTags(args, tags)
+	args = append(args, testFlags...)
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(append(env, quoted...), " ")
+}
+
+// FindModuleRoot returns the directory with the go.mod file that contains
+// the given absolute directory, or an empty string if there is none
+func FindModuleRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// shellQuote quotes an argument for a POSIX shell when needed
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$^*?[]{}()<>|&;#~!`") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// splitWords splits a command line into arguments like a POSIX shell does,
+// without expanding anything. Single quotes keep everything literally, double
+// quotes keep whitespace, and a backslash escapes the next character (within
+// double quotes only ", \, $ and `). An unterminated quote extends to the end.
+func splitWords(s string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, c := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", c) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case unicode.IsSpace(c):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if escaped {
+		word.WriteRune('\\')
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// withVerbose adds the -v flag to the go test arguments, unless it's already
+// present. Arguments after "--" are passed to go test by wrappers such as
+// gotestsum.
+func withVerbose(args []string) []string {
+	return withTestFlag(args, "-v")
+}
+
+// withRace adds the -race flag to the go test arguments, unless it's already
+// present
+func withRace(args []string) []string {
+	return withTestFlag(args, "-race")
+}
+
+// withTestFlag adds a boolean flag to the go test arguments, unless it's
+// already present. Commands that don't run go test are returned unchanged.
+func withTestFlag(args []string, flag string) []string {
+	testFlag := "-test." + flag[1:]
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") || arg == testFlag || strings.HasPrefix(arg, testFlag+"=") {
+			return args
+		}
+	}
+
+	insertAt := -1
+	if len(args) > 1 && args[0] == "go" && args[1] == "test" {
+		insertAt = 2
+	} else if i := slices.Index(args, "--"); i >= 0 {
+		insertAt = i + 1
+	}
+	if insertAt < 0 {
+		return args
+	}
+	return slices.Insert(slices.Clone(args), insertAt, flag)
+}
+
+// withTags adds the -tags flag with the build tags to the go test arguments,
+// unless there are no tags or the arguments already specify them
+func withTags(args []string, tags []string) []string {
+	if len(tags) == 0 {
+		return args
+	}
+	for _, arg := range args {
+		if arg == "-tags" || strings.HasPrefix(arg, "-tags=") {
+			return args
+		}
+	}
+	return withTestFlag(args, "-tags="+strings.Join(tags, ","))
+}
+
+// testFinished is called when a test completes
+func (r *TestRunner) testFinished() {
+	r.mu.Lock()
+	r.running--
+	if r.running == 0 && r.queuedCount() == 0 {
+		r.batchFinished = time.Now()
+	}
+	r.signalIdle()
+	r.mu.Unlock()
+
+	r.notifyUpdate()
+	r.tryStartNext()
+}
+
+// notifyUpdate calls the update callback if set
+func (r *TestRunner) notifyUpdate() {
+	r.mu.Lock()
+	cb := r.onUpdate
+	r.mu.Unlock()
+
+	if cb != nil {
+		cb()
+	}
+}
+
+// notifyFinished calls the finished callback if set
+func (r *TestRunner) notifyFinished(item *TestItem) {
+	r.mu.Lock()
+	cb := r.onFinished
+	r.mu.Unlock()
+
+	if cb != nil {
+		cb(item)
+	}
+}
+
+// FindMostRecentLogFile finds the most recent log file for a test in the log
+// directory and returns its path and modification time
+func FindMostRecentLogFile(logDir string, info TestInfo) (string, time.Time) {
+	pattern := filepath.Join(logDir, LogFilePrefix(info)+".*.log")
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		return "", time.Time{}
+	}
+
+	var mostRecent string
+	var mostRecentTime time.Time
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(mostRecentTime) {
+			mostRecentTime = info.ModTime()
+			mostRecent = match
+		}
+	}
+
+	return mostRecent, mostRecentTime
+}
+
+// RunBenchmark runs a benchmark the given number of times and writes the
+// output to the output file. Benchmarks are run with -run '^$', so no tests
+// are executed.
+func (r *TestRunner) RunBenchmark(ctx context.Context, info TestInfo, count int, output string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	pkgPath := PackagePath(info.ModulePackage())
+
+	r.mu.Lock()
+	timeout, tags := r.testTimeout, r.tags
+	r.mu.Unlock()
+
+	args := []string{"go", "test", "-timeout", timeout.String(), "-run", "^$",
+		"-bench", fmt.Sprintf("^%s$", info.Name), "-count", fmt.Sprintf("%d", count), pkgPath}
+	args = withTags(args, tags)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Join(r.testDir, info.Module)
+	setProcessGroup(cmd)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
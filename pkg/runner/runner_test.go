@@ -0,0 +1,909 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExpandTestCommand(t *testing.T) {
+	args := expandTestCommand(DefaultTestCommand, "./pkg", "^TestFoo$", 5*time.Minute)
+	expected := []string{"go", "test", "-timeout", "5m0s", "-v", "-run", "^TestFoo$", "./pkg"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = expandTestCommand("gotestsum --format testname -- -run={test} {pkg}", ".", "^TestBar$", time.Minute)
+	expected = []string{"gotestsum", "--format", "testname", "--", "-run=^TestBar$", "."}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = expandTestCommand("mytest {pkg} -timeout={timeout} -run {run}", "./pkg", "^TestBaz$", time.Minute)
+	expected = []string{"mytest", "./pkg", "-timeout=1m0s", "-run", "^TestBaz$"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	// A template of a wrapper without placeholders is a prefix of the go
+	// test arguments, but other commands are left alone
+	args = expandTestCommand("gotestsum --format testname --", "./pkg", "^TestFoo$", time.Minute)
+	expected = []string{"gotestsum", "--format", "testname", "--", "-timeout", "1m0s", "-v", "-run", "^TestFoo$", "./pkg"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = expandTestCommand("make test", "./pkg", "^TestFoo$", time.Minute)
+	expected = []string{"make", "test"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	// Quoted arguments stay together
+	args = expandTestCommand(`sh -c "go test -run '{test}' {pkg}"`, "./pkg", "^TestFoo$", time.Minute)
+	expected = []string{"sh", "-c", "go test -run '^TestFoo$' ./pkg"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = expandTestCommand(`make test ARGS="-v -count=1 -run {test}"`, "./pkg", "^TestFoo$", time.Minute)
+	expected = []string{"make", "test", "ARGS=-v -count=1 -run ^TestFoo$"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := map[string][]string{
+		"":                      nil,
+		"  go  test\t-v ":       {"go", "test", "-v"},
+		`a "b c" 'd e'`:         {"a", "b c", "d e"},
+		`x="a b"c`:              {"x=a bc"},
+		`'it''s' "say \"hi\""`:  {"its", `say "hi"`},
+		`a\ b "\n" '\n'`:        {"a b", `\n`, `\n`},
+		`"" ''`:                 {"", ""},
+		`unterminated "quote x`: {"unterminated", "quote x"},
+	}
+
+	for input, expected := range tests {
+		if got := splitWords(input); !reflect.DeepEqual(got, expected) {
+			t.Errorf("splitWords(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestNextQueuedItem(t *testing.T) {
+	now := time.Now()
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}, Status: StatusQueued, QueuedAt: now.Add(2 * time.Second)}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}, Status: StatusIdle}
+	third := &TestItem{Info: TestInfo{Name: "TestThird"}, Status: StatusQueued, QueuedAt: now}
+	tests := []*TestItem{first, second, third}
+
+	r := NewTestRunner(".", ".", 1, 0)
+	r.SetTestList(&tests)
+
+	if next := r.nextQueuedItem(); next != third {
+		t.Errorf("Expected the earliest queued test, got %s", next.Info.Name)
+	}
+
+	r.sequential = true
+	if next := r.nextQueuedItem(); next != first {
+		t.Errorf("Expected the first queued test in list order, got %s", next.Info.Name)
+	}
+}
+
+func TestSequentialOrder(t *testing.T) {
+	var tests []*TestItem
+	for _, name := range []string{"TestA", "TestB", "TestC", "TestD"} {
+		tests = append(tests, &TestItem{Info: TestInfo{Name: name}})
+	}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+	r.SetSequential(true)
+
+	// The shown order differs from the list order, and TestA isn't shown
+	r.SetSequence([]*TestItem{tests[2], tests[3], tests[1]})
+
+	var mu sync.Mutex
+	var finished []*TestItem
+	r.SetFinishedCallback(func(item *TestItem) {
+		mu.Lock()
+		defer mu.Unlock()
+		finished = append(finished, item)
+	})
+
+	for _, item := range tests {
+		r.QueueTest(item)
+	}
+	r.SetMaxParallel(4)
+	if !r.WaitIdle(10 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for _, item := range finished {
+		names = append(names, item.Info.Name)
+	}
+	if strings.Join(names, ",") != "TestC,TestD,TestB,TestA" {
+		t.Fatalf("Expected the tests to run in the shown order, got %v", names)
+	}
+
+	// Each test only started after the previous one finished
+	for i := 1; i < len(finished); i++ {
+		if finished[i].StartedAt.Before(finished[i-1].FinishedAt) {
+			t.Errorf("Expected %s to start after %s finished", finished[i].Info.Name, finished[i-1].Info.Name)
+		}
+	}
+}
+
+func TestPrioritizeTests(t *testing.T) {
+	now := time.Now()
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}, Status: StatusQueued, QueuedAt: now}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}, Status: StatusQueued, QueuedAt: now.Add(time.Second)}
+	third := &TestItem{Info: TestInfo{Name: "TestThird"}, Status: StatusQueued, QueuedAt: now.Add(2 * time.Second)}
+	idle := &TestItem{Info: TestInfo{Name: "TestIdle"}, Status: StatusIdle}
+	tests := []*TestItem{first, second, third, idle}
+
+	// No tests are started without parallelism
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	r.SetTestList(&tests)
+
+	r.PrioritizeTests([]*TestItem{third})
+	if next := r.nextQueuedItem(); next != third {
+		t.Errorf("Expected the prioritized test, got %s", next.Info.Name)
+	}
+
+	// A test that wasn't queued is queued and goes ahead of the tests that
+	// were prioritized before
+	r.PrioritizeTests([]*TestItem{idle})
+	if idle.Status != StatusQueued {
+		t.Fatalf("Expected the prioritized test to be queued, got %s", idle.Status)
+	}
+	if next := r.nextQueuedItem(); next != idle {
+		t.Errorf("Expected the last prioritized test, got %s", next.Info.Name)
+	}
+
+	// Tests with the same priority start in the order they were queued
+	r.StopTest(idle)
+	r.StopTest(third)
+	if next := r.nextQueuedItem(); next != first {
+		t.Errorf("Expected the earliest queued test, got %s", next.Info.Name)
+	}
+}
+
+func TestFastFirst(t *testing.T) {
+	now := time.Now()
+	slow := &TestItem{Info: TestInfo{Name: "TestSlow"}, Status: StatusQueued, QueuedAt: now, LastDuration: time.Minute}
+	unknown := &TestItem{Info: TestInfo{Name: "TestUnknown"}, Status: StatusQueued, QueuedAt: now.Add(time.Second)}
+	medium := &TestItem{Info: TestInfo{Name: "TestMedium"}, Status: StatusQueued, QueuedAt: now.Add(2 * time.Second), LastDuration: 10 * time.Second}
+	fast := &TestItem{Info: TestInfo{Name: "TestFast"}, Status: StatusQueued, QueuedAt: now.Add(3 * time.Second), LastDuration: time.Second}
+	tests := []*TestItem{slow, unknown, medium, fast}
+
+	r := NewTestRunner(".", ".", 1, 0)
+	r.SetTestList(&tests)
+	if next := r.nextQueuedItem(); next != slow {
+		t.Errorf("Expected the earliest queued test without fast-first mode, got %s", next.Info.Name)
+	}
+
+	// A test without a recorded duration is expected to take the median
+	// time, so it starts after the fast test but before the slow test
+	r.SetFastFirst(true)
+	var order []string
+	for range tests {
+		next := r.nextQueuedItem()
+		order = append(order, next.Info.Name)
+		next.Status = StatusPassed
+	}
+	if got := strings.Join(order, ","); got != "TestFast,TestUnknown,TestMedium,TestSlow" {
+		t.Errorf("Expected the fastest tests first, got %s", got)
+	}
+}
+
+func TestLogFilePrefix(t *testing.T) {
+	if prefix := LogFilePrefix(TestInfo{Name: "TestFoo/case one"}); prefix != "TestFoo~case_one" {
+		t.Errorf("Expected TestFoo~case_one, got %s", prefix)
+	}
+	if prefix := LogFilePrefix(TestInfo{Name: "TestFoo", Package: "sub/pkg"}); prefix != "TestFoo@sub_pkg" {
+		t.Errorf("Expected TestFoo@sub_pkg, got %s", prefix)
+	}
+
+	// A subtest doesn't share its logs with a test with an underscore in its
+	// name, and the separator can't be faked by a subtest name
+	prefixes := make(map[string]string)
+	for _, name := range []string{"TestFoo/Bar", "TestFoo_Bar", "TestFoo/Bar/Baz", "TestFoo/Bar~Baz"} {
+		prefix := LogFilePrefix(TestInfo{Name: name, Package: "pkg"})
+		if other, ok := prefixes[prefix]; ok {
+			t.Errorf("Expected different prefixes for %s and %s, got %s", other, name, prefix)
+		}
+		prefixes[prefix] = name
+	}
+}
+
+func TestUniqueLogFile(t *testing.T) {
+	dir := t.TempDir()
+
+	first := uniqueLogFile(dir, TestInfo{Name: "TestFoo"}, "20240101-120000.000")
+	if err := os.WriteFile(first, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second := uniqueLogFile(dir, TestInfo{Name: "TestFoo"}, "20240101-120000.000")
+	if second == first {
+		t.Errorf("Expected a different log file, got %s twice", first)
+	}
+}
+
+func TestSameTestNameInDifferentPackages(t *testing.T) {
+	tests, err := DiscoverTests("testdata")
+	if err != nil {
+		t.Fatalf("DiscoverTests failed: %v", err)
+	}
+
+	packages := make(map[string]TestInfo)
+	for _, test := range tests {
+		if test.Name == "TestFoo" {
+			packages[test.Package] = test
+		}
+	}
+	pkgA, okA := packages["pkga"]
+	pkgB, okB := packages["pkgb"]
+	if !okA || !okB {
+		t.Fatalf("Expected TestFoo in pkga and pkgb, got %v", packages)
+	}
+
+	// Only create a log file for the test in pkga
+	logDir := t.TempDir()
+	logFile := uniqueLogFile(logDir, pkgA, "20240101-120000.000")
+	if err := os.WriteFile(logFile, []byte("ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _ := FindMostRecentLogFile(logDir, pkgA); found != logFile {
+		t.Errorf("Expected log file %s for pkga, got %q", logFile, found)
+	}
+	if found, _ := FindMostRecentLogFile(logDir, pkgB); found != "" {
+		t.Errorf("Expected no log file for pkgb, got %s", found)
+	}
+}
+
+func TestRestartTest(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestSlow"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+
+	r.QueueTest(item)
+	status, firstLog := item.LogState()
+	if status != StatusRunning {
+		t.Fatalf("Expected the test to be running, got %s", status)
+	}
+
+	r.RestartTest(item)
+
+	// Wait for the cancelled run to stop and the test to start again
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, logFile := item.LogState()
+		if status == StatusRunning && logFile != firstLog {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the test to be restarted, got %s", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if runs, failures := item.FlakeRate(); runs != 0 || failures != 0 {
+		t.Errorf("Expected the cancelled run not to be counted, got %d/%d", failures, runs)
+	}
+
+	r.StopTest(item)
+}
+
+func TestStopAll(t *testing.T) {
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}}
+	tests := []*TestItem{first, second}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+
+	r.QueueTest(first)
+	r.QueueTest(second)
+	r.StopAll(tests)
+
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected all tests to stop")
+	}
+	if status, _ := second.LogState(); status != StatusIdle {
+		t.Errorf("Expected the queued test not to be started, got %s", status)
+	}
+}
+
+func TestStopEverything(t *testing.T) {
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}}
+	third := &TestItem{Info: TestInfo{Name: "TestThird"}}
+	tests := []*TestItem{first, second, third}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+
+	r.QueueTest(first)
+	r.QueueTest(second)
+	r.QueueRepeated(third, 3)
+	queued, running := r.StopEverything()
+	if queued != 2 || running != 1 {
+		t.Errorf("Expected 2 queued and 1 running test, got %d and %d", queued, running)
+	}
+
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected all tests to stop")
+	}
+	if count := r.GetRunningCount(); count != 0 {
+		t.Errorf("Expected no running tests, got %d", count)
+	}
+	for _, item := range []*TestItem{second, third} {
+		if status, _ := item.LogState(); status != StatusIdle {
+			t.Errorf("Expected %s not to be started, got %s", item.Info.Name, status)
+		}
+	}
+
+	// Stopping again is a no-op
+	if queued, running := r.StopEverything(); queued != 0 || running != 0 {
+		t.Errorf("Expected nothing to stop, got %d queued and %d running tests", queued, running)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestSleep"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+	r.QueueTest(item)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the running test to stop, got %v", err)
+	}
+	if count := r.GetRunningCount(); count != 0 {
+		t.Errorf("Expected no running tests, got %d", count)
+	}
+	if status, _ := item.LogState(); status != StatusFailed {
+		t.Errorf("Expected the cancelled test to fail, got %s", status)
+	}
+}
+
+func TestUpdateTests(t *testing.T) {
+	idle := &TestItem{Info: TestInfo{Name: "TestIdle", Line: 1}}
+	queued := &TestItem{Info: TestInfo{Name: "TestQueued", Line: 2}}
+	removed := &TestItem{Info: TestInfo{Name: "TestRemoved"}}
+	tests := []*TestItem{idle, queued, removed}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	r.SetTestList(&tests)
+	r.QueueTest(queued)
+
+	added := &TestItem{Info: TestInfo{Name: "TestAdded"}}
+	r.UpdateTests([]*TestItem{queued, idle, added}, map[*TestItem]TestInfo{
+		idle:   {Name: "TestIdle", Line: 10},
+		queued: {Name: "TestQueued", Line: 20},
+	})
+
+	if len(tests) != 3 || tests[0] != queued || tests[2] != added {
+		t.Fatalf("Expected the list to be replaced, got %d tests", len(tests))
+	}
+	if idle.Info.Line != 10 {
+		t.Errorf("Expected the info of the idle test to be updated, got line %d", idle.Info.Line)
+	}
+	if queued.Info.Line != 2 {
+		t.Errorf("Expected the queued test to keep its info, got line %d", queued.Info.Line)
+	}
+	r.StopTest(queued)
+}
+
+func TestQueueRepeated(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestPass"}, Runs: 7, Failures: 2}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+
+	// The tally is reset, so it only counts the repeated runs
+	r.QueueRepeated(item, 3)
+	if !r.WaitIdle(10 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+	if runs, failures := item.FlakeRate(); runs != 3 || failures != 0 {
+		t.Errorf("Expected 3 runs without failures, got %d runs with %d failures", runs, failures)
+	}
+}
+
+func TestStoppedRunNotCounted(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestSlow"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+
+	r.QueueTest(item)
+	deadline := time.Now().Add(5 * time.Second)
+	for status, _ := item.LogState(); status != StatusRunning; status, _ = item.LogState() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the test to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A stopped run doesn't count as a failure, so it can't make the test
+	// look flaky
+	r.StopTest(item)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the test to stop")
+	}
+	if runs, failures := item.FlakeRate(); runs != 0 || failures != 0 {
+		t.Errorf("Expected the stopped run not to be counted, got %d/%d", failures, runs)
+	}
+}
+
+func TestLogFileError(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestPass"}}
+	tests := []*TestItem{item}
+
+	// The log file can't be created in a directory that doesn't exist
+	r := NewTestRunner(t.TempDir(), filepath.Join(t.TempDir(), "missing"), 1, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+
+	r.QueueRepeated(item, 3)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if item.Status != StatusFailed {
+		t.Errorf("Expected the test to fail, got %s", item.Status)
+	}
+	if item.cancel != nil || item.restart || item.repeat != 0 || item.Attempt != 1 {
+		t.Errorf("Expected the run state to be reset, got cancel %v, restart %v, repeat %d and attempt %d", item.cancel != nil, item.restart, item.repeat, item.Attempt)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}}
+	tests := []*TestItem{first, second}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("sleep 30")
+	r.SetTestList(&tests)
+
+	// Tests queued while others are running join the same batch
+	r.QueueTest(first)
+	r.QueueTest(second)
+	if first.Batch != 1 || second.Batch != 1 {
+		t.Errorf("Expected both tests in batch 1, got %d and %d", first.Batch, second.Batch)
+	}
+
+	r.StopAll(tests)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected all tests to stop")
+	}
+
+	// Queuing while idle starts a new batch
+	r.QueueTest(second)
+	if second.Batch != 2 || r.CurrentBatch() != 2 {
+		t.Errorf("Expected a new batch, got %d (current %d)", second.Batch, r.CurrentBatch())
+	}
+
+	r.StopAll(tests)
+	r.WaitIdle(5 * time.Second)
+}
+
+func TestReproduceCommand(t *testing.T) {
+	r := NewTestRunner("testdata", ".", 1, time.Minute)
+
+	cmd := r.ReproduceCommand(TestInfo{Name: "TestFoo", Package: "pkga"})
+	expected := "go test -count=1 -timeout 1m0s -v -run '^TestFoo$' ./pkg/runner/testdata/pkga"
+	if cmd != expected {
+		t.Errorf("Expected %q, got %q", expected, cmd)
+	}
+
+	// Extra flags come last, so they override the flags of the template
+	r.SetTestFlags([]string{"-count=3", "-tags=integration"})
+	cmd = r.ReproduceCommand(TestInfo{Name: "TestFoo", Package: "pkga"})
+	expected = "go test -count=1 -timeout 1m0s -v -run '^TestFoo$' ./pkg/runner/testdata/pkga -count=3 -tags=integration"
+	if cmd != expected {
+		t.Errorf("Expected %q, got %q", expected, cmd)
+	}
+	r.SetTestFlags(nil)
+
+	r.SetTargetPlatform("windows", "arm64")
+	cmd = r.ReproduceCommand(TestInfo{Name: "TestFoo", Package: "pkga"})
+	expected = "GOOS=windows GOARCH=arm64 go test -c -o " + os.DevNull + " ./pkg/runner/testdata/pkga"
+	if cmd != expected {
+		t.Errorf("Expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	for pkg, expected := range map[string]string{"": ".", "pkga": "./pkga", "nested/ext": "./nested/ext"} {
+		if got := PackagePath(pkg); got != expected {
+			t.Errorf("PackagePath(%q) = %q, expected %q", pkg, got, expected)
+		}
+	}
+}
+
+func TestRunExternalTestPackage(t *testing.T) {
+	tests, err := DiscoverTests("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []*TestItem
+	for _, test := range tests {
+		if test.Name == "TestExternal" && test.Package != "nested/ext" {
+			t.Errorf("Expected TestExternal in package nested/ext, got %q", test.Package)
+		}
+		if test.Name == "TestExternal" || (test.Name == "TestQuickPass" && test.Package == "") {
+			items = append(items, &TestItem{Info: test})
+		}
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected TestExternal and TestQuickPass, got %d tests", len(items))
+	}
+
+	r := NewTestRunner("testdata", t.TempDir(), 2, time.Minute)
+	r.SetTestList(&items)
+	for _, item := range items {
+		r.QueueTest(item)
+	}
+	if !r.WaitIdle(time.Minute) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	for _, item := range items {
+		if status, logFile := item.LogState(); status != StatusPassed {
+			data, _ := os.ReadFile(logFile)
+			t.Errorf("Expected %s in %q to pass, got %s:\n%s", item.Info.Name, item.Info.Package, status, data)
+		}
+	}
+}
+
+func TestRunNestedModules(t *testing.T) {
+	tests, err := DiscoverTests("testdata/multimod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []*TestItem
+	for _, test := range tests {
+		items = append(items, &TestItem{Info: test})
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected a test in each module, got %d tests", len(items))
+	}
+
+	// Each module is tested from its own root, as the packages aren't part
+	// of the module of the search directory
+	r := NewTestRunner("testdata/multimod", t.TempDir(), 2, time.Minute)
+	r.SetTestList(&items)
+	for _, item := range items {
+		r.QueueTest(item)
+	}
+	if !r.WaitIdle(time.Minute) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	for _, item := range items {
+		if status, logFile := item.LogState(); status != StatusPassed {
+			data, _ := os.ReadFile(logFile)
+			t.Errorf("Expected %s in module %q to pass, got %s:\n%s", item.Info.Name, item.Info.Module, status, data)
+		}
+	}
+}
+
+func TestBatchElapsed(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestPass"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+	if _, done := r.BatchElapsed(); done {
+		t.Error("Expected no finished batch before queuing tests")
+	}
+
+	r.QueueTest(item)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+	elapsed, done := r.BatchElapsed()
+	if !done || elapsed <= 0 {
+		t.Errorf("Expected a finished batch with a duration, got %s (done: %v)", elapsed, done)
+	}
+	if again, _ := r.BatchElapsed(); again != elapsed {
+		t.Errorf("Expected the duration of a finished batch to stay %s, got %s", elapsed, again)
+	}
+}
+
+func TestBatchProgress(t *testing.T) {
+	first := &TestItem{Info: TestInfo{Name: "TestFirst"}}
+	second := &TestItem{Info: TestInfo{Name: "TestSecond"}}
+	third := &TestItem{Info: TestInfo{Name: "TestThird"}}
+	tests := []*TestItem{first, second, third}
+
+	// Nothing starts until the parallelism is raised
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+	checkProgress := func(expectedDone, expectedTotal int) {
+		t.Helper()
+		if done, total := r.BatchProgress(); done != expectedDone || total != expectedTotal {
+			t.Errorf("Expected progress %d/%d, got %d/%d", expectedDone, expectedTotal, done, total)
+		}
+	}
+	checkProgress(0, 0)
+
+	r.QueueTest(first)
+	r.QueueTest(second)
+	checkProgress(0, 2)
+
+	// Removing a test from the queue removes it from the batch, and queuing
+	// more tests extends the batch
+	r.StopTest(second)
+	checkProgress(0, 1)
+	r.QueueTest(second)
+	r.QueueTest(third)
+	checkProgress(0, 3)
+
+	r.SetMaxParallel(1)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+	checkProgress(3, 3)
+
+	// A new batch starts from scratch
+	r.SetMaxParallel(0)
+	r.QueueTest(first)
+	checkProgress(0, 1)
+	r.StopTest(first)
+}
+
+func TestIdle(t *testing.T) {
+	pass := &TestItem{Info: TestInfo{Name: "TestPass"}}
+	fail := &TestItem{Info: TestInfo{Name: "TestFail"}}
+	tests := []*TestItem{pass, fail}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 2, 0)
+	r.SetTestCommand("test {test} = ^TestPass$")
+	r.SetRetries(1)
+	r.SetTestList(&tests)
+
+	var mu sync.Mutex
+	var finished []string
+	r.SetFinishedCallback(func(item *TestItem) {
+		mu.Lock()
+		defer mu.Unlock()
+		finished = append(finished, item.Info.Name)
+	})
+
+	select {
+	case <-r.Idle():
+	default:
+		t.Fatal("Expected an idle runner before queuing tests")
+	}
+
+	r.QueueTest(pass)
+	r.QueueTest(fail)
+	select {
+	case <-r.Idle():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	// Retried runs aren't reported as finished
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(finished)
+	if strings.Join(finished, ",") != "TestFail,TestPass" {
+		t.Errorf("Expected both tests to finish once, got %v", finished)
+	}
+}
+
+func TestIdleAfterStop(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestPass"}}
+	tests := []*TestItem{item}
+
+	// Without parallelism, queued tests never start
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 0, 0)
+	r.SetTestList(&tests)
+
+	r.QueueTest(item)
+	idle := r.Idle()
+	select {
+	case <-idle:
+		t.Fatal("Expected the runner not to be idle with a queued test")
+	default:
+	}
+
+	r.StopTest(item)
+	select {
+	case <-idle:
+	case <-time.After(time.Second):
+		t.Fatal("Expected removing the last queued test to finish the batch")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestFail"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("false")
+	r.SetRetries(2)
+	r.SetTestList(&tests)
+
+	r.QueueTest(item)
+	if !r.WaitIdle(5 * time.Second) {
+		t.Fatal("Expected the runner to become idle")
+	}
+
+	if status, _ := item.LogState(); status != StatusFailed {
+		t.Errorf("Expected the test to fail, got %s", status)
+	}
+	if runs, failures := item.FlakeRate(); runs != 3 || failures != 3 {
+		t.Errorf("Expected 3 failed runs, got %d/%d", failures, runs)
+	}
+}
+
+func TestQueueUntilFail(t *testing.T) {
+	// The script passes twice and fails on the third run
+	dir := t.TempDir()
+	script := filepath.Join(dir, "flaky.sh")
+	counter := filepath.Join(dir, "runs")
+	src := "echo run >> " + counter + "\ntest $(wc -l < " + counter + ") -lt 3\n"
+	if err := os.WriteFile(script, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &TestItem{Info: TestInfo{Name: "TestFlaky"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(dir, t.TempDir(), 1, 0)
+	r.SetTestCommand("sh " + script)
+	r.SetRetries(2)
+	r.SetTestList(&tests)
+
+	r.QueueUntilFail(item)
+	select {
+	case <-r.Idle():
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the test to stop after failing")
+	}
+
+	// The failure isn't retried
+	if runs, failures := item.FlakeRate(); runs != 3 || failures != 1 {
+		t.Errorf("Expected 1 failure in 3 runs, got %d/%d", failures, runs)
+	}
+	if item.IsUntilFail() {
+		t.Error("Expected the test not to run until it fails anymore")
+	}
+}
+
+func TestStopUntilFail(t *testing.T) {
+	item := &TestItem{Info: TestInfo{Name: "TestPass"}}
+	tests := []*TestItem{item}
+
+	r := NewTestRunner(t.TempDir(), t.TempDir(), 1, 0)
+	r.SetTestCommand("true")
+	r.SetTestList(&tests)
+
+	r.QueueUntilFail(item)
+	deadline := time.Now().Add(5 * time.Second)
+	for runs, _ := item.FlakeRate(); runs < 2; runs, _ = item.FlakeRate() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the test to run repeatedly")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r.StopTest(item)
+	select {
+	case <-r.Idle():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the test to stop")
+	}
+	if item.IsUntilFail() {
+		t.Error("Expected the test not to run until it fails anymore")
+	}
+}
+
+func TestWithVerbose(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"go", "test", "-run", "^TestFoo$", "."}, []string{"go", "test", "-v", "-run", "^TestFoo$", "."}},
+		{[]string{"go", "test", "-v", "."}, []string{"go", "test", "-v", "."}},
+		{[]string{"go", "test", "-test.v=true", "."}, []string{"go", "test", "-test.v=true", "."}},
+		{[]string{"go", "test", "-test.vet=off", "."}, []string{"go", "test", "-v", "-test.vet=off", "."}},
+		{[]string{"gotestsum", "--", "-run", "^TestFoo$"}, []string{"gotestsum", "--", "-v", "-run", "^TestFoo$"}},
+		{[]string{"make", "test"}, []string{"make", "test"}},
+	}
+
+	for _, tt := range tests {
+		if got := withVerbose(tt.args); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("withVerbose(%v) = %v, expected %v", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestWithRace(t *testing.T) {
+	args := expandTestCommand(DefaultTestCommand, "./pkg", "^TestFoo$", time.Minute)
+	expected := []string{"go", "test", "-race", "-timeout", "1m0s", "-v", "-run", "^TestFoo$", "./pkg"}
+	if got := withRace(args); !reflect.DeepEqual(got, expected) {
+		t.Errorf("withRace(%v) = %v, expected %v", args, got, expected)
+	}
+	if got := withRace(expected); !reflect.DeepEqual(got, expected) {
+		t.Errorf("withRace(%v) = %v, expected it unchanged", expected, got)
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	args := expandTestCommand(DefaultTestCommand, "./pkg", "^TestFoo$", time.Minute)
+	expected := []string{"go", "test", "-tags=integration,e2e", "-timeout", "1m0s", "-v", "-run", "^TestFoo$", "./pkg"}
+	if got := withTags(args, []string{"integration", "e2e"}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("withTags(%v) = %v, expected %v", args, got, expected)
+	}
+	if got := withTags(args, nil); !reflect.DeepEqual(got, args) {
+		t.Errorf("withTags without tags = %v, expected it unchanged", got)
+	}
+	custom := []string{"go", "test", "-tags", "custom", "./pkg"}
+	if got := withTags(custom, []string{"integration"}); !reflect.DeepEqual(got, custom) {
+		t.Errorf("withTags(%v) = %v, expected it unchanged", custom, got)
+	}
+}
+
+func TestLoggedSkip(t *testing.T) {
+	lines := []string{"=== RUN   TestFoo", "    foo_test.go:5: not supported", "--- SKIP: TestFoo (0.00s)", "PASS"}
+
+	if !loggedSkip(lines, "TestFoo") {
+		t.Error("Expected TestFoo to be skipped")
+	}
+	if loggedSkip(lines, "TestFo") {
+		t.Error("Expected only the exact test name to match")
+	}
+}
+
+func TestSetTestTimeout(t *testing.T) {
+	r := NewTestRunner(".", t.TempDir(), 1, time.Minute)
+
+	r.SetTestTimeout(5 * time.Minute)
+	if got := r.GetTestTimeout(); got != 5*time.Minute {
+		t.Errorf("Expected a timeout of 5m, got %s", got)
+	}
+
+	r.SetTestTimeout(0)
+	if got := r.GetTestTimeout(); got != DefaultTestTimeout {
+		t.Errorf("Expected the default timeout, got %s", got)
+	}
+}
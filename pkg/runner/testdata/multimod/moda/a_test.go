@@ -0,0 +1,5 @@
+package moda
+
+import "testing"
+
+func TestModuleA(t *testing.T) {}
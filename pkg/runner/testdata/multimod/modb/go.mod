@@ -0,0 +1,3 @@
+module example.com/modb
+
+go 1.21
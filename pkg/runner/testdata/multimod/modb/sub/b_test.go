@@ -0,0 +1,5 @@
+package sub
+
+import "testing"
+
+func TestModuleB(t *testing.T) {}
@@ -0,0 +1,6 @@
+package ext
+
+// Answer returns the answer
+func Answer() int {
+	return 42
+}
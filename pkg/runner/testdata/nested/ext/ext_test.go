@@ -0,0 +1,13 @@
+package ext_test
+
+import (
+	"testing"
+
+	"github.com/ramondeklein/test-runner/pkg/runner/testdata/nested/ext"
+)
+
+func TestExternal(t *testing.T) {
+	if ext.Answer() != 42 {
+		t.Fatal("unexpected answer")
+	}
+}
@@ -0,0 +1,9 @@
+package pkga
+
+import (
+	"testing"
+)
+
+func TestFoo(t *testing.T) {
+	t.Log("TestFoo in pkga")
+}
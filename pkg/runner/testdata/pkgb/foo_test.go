@@ -0,0 +1,9 @@
+package pkgb
+
+import (
+	"testing"
+)
+
+func TestFoo(t *testing.T) {
+	t.Log("TestFoo in pkgb")
+}
@@ -1,10 +1,12 @@
 package testdata
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
 
+// TestQuickPass passes quickly.
 func TestQuickPass(t *testing.T) {
 	t.Log("This test passes quickly")
 }
@@ -30,3 +32,25 @@ func TestWithOutput(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 	}
 }
+
+func TestSkipped(t *testing.T) {
+	t.Skip("intentionally skipped")
+}
+
+func BenchmarkQuick(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = fmt.Sprintf("%d", i)
+	}
+}
+
+func TestTable(t *testing.T) {
+	for _, name := range []string{"first case", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Log(name)
+		})
+	}
+
+	t.Run("literal case", func(t *testing.T) {
+		t.Run("nested", func(t *testing.T) {})
+	})
+}
@@ -0,0 +1,7 @@
+//go:build integration
+
+package tags
+
+import "testing"
+
+func TestIntegration(t *testing.T) {}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// renderPreview renders the tests that running the selection would queue,
+// so there are no surprises when filtering and selecting interact
+func (m *Model) renderPreview(width, height int) string {
+	items := m.selectedOrCurrent()
+
+	// Selected tests that are hidden by the filter aren't run
+	hidden := 0
+	visible := make(map[*runner.TestItem]bool, len(m.filteredList))
+	for _, t := range m.filteredList {
+		visible[t] = true
+	}
+	for _, t := range m.tests {
+		if t.Selected && !visible[t] {
+			hidden++
+		}
+	}
+
+	lineWidth := max(width-4, 0)
+	header := "Dry run: g would run 1 test"
+	if len(items) != 1 {
+		header = fmt.Sprintf("Dry run: g would run %d tests", len(items))
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render(ansi.Truncate(header, lineWidth, "…")),
+		strings.Repeat("─", lineWidth),
+	}
+	if hidden > 0 {
+		note := fmt.Sprintf("%d selected tests are hidden by the filter and won't run", hidden)
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render(ansi.Truncate(note, lineWidth, "…")))
+	}
+
+	room := max(height-2-len(lines), 1)
+	for i, item := range items {
+		if i == room-1 && len(items) > room {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(items)-i))
+			break
+		}
+
+		name := item.Info.Name
+		if item.Info.Package != "" {
+			name = item.Info.Package + "/" + name
+		}
+
+		// Running and queued tests aren't queued again
+		if item.Status == runner.StatusRunning || item.Status == runner.StatusQueued {
+			name += fmt.Sprintf(" (already %s)", item.Status)
+		}
+		lines = append(lines, ansi.Truncate(name, lineWidth, "…"))
+	}
+
+	return strings.Join(lines, "\n")
+}
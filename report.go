@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// reportFile is the name of the JSON report in the log directory
+const reportFile = "report.json"
+
+// testReport is the result of a single test in the JSON report
+type testReport struct {
+	Name        string  `json:"name"`
+	Package     string  `json:"package"`
+	File        string  `json:"file"`
+	Line        int     `json:"line"`
+	Status      string  `json:"status"`
+	BuildFailed bool    `json:"buildFailed,omitempty"`
+	DurationMs  int64   `json:"durationMs"`
+	Attempts    int     `json:"attempts"`
+	Coverage    float64 `json:"coverage"`
+	LogFile     string  `json:"logFile"`
+}
+
+// buildJSONReport converts the tests into the JSON report. The duration is
+// the duration of the last completed run, even when the test is running
+// again.
+func buildJSONReport(tests []runner.TestSnapshot) []testReport {
+	report := make([]testReport, 0, len(tests))
+	for _, t := range tests {
+		report = append(report, testReport{
+			Name:        t.Info.Name,
+			Package:     t.Info.Package,
+			File:        t.Info.File,
+			Line:        t.Info.Line,
+			Status:      t.Status.String(),
+			BuildFailed: t.BuildFailed,
+			DurationMs:  t.LastDuration.Milliseconds(),
+			Attempts:    t.Attempt,
+			Coverage:    t.Coverage,
+			LogFile:     t.LogFile,
+		})
+	}
+	return report
+}
+
+// marshalJSONReport returns the JSON report of all tests
+func marshalJSONReport(r *runner.TestRunner) ([]byte, error) {
+	data, err := json.MarshalIndent(buildJSONReport(r.Snapshot()), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// exportJSONReport writes the JSON report to the log directory
+func (m *Model) exportJSONReport() {
+	path := filepath.Join(m.logDir, reportFile)
+	data, err := marshalJSONReport(m.runner)
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		m.setStatusMessage(fmt.Sprintf("report failed: %v", err))
+		return
+	}
+	m.setStatusMessage("report written to " + path)
+}
+
+// runReport runs the selected tests (or all tests when none are selected)
+// without the TUI and prints a report in the given format to stdout. It
+// returns the process exit code.
+func runReport(testDir string, opts Options, format string) int {
+	if format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown report format %q (supported: json)\n", format)
+		return 1
+	}
+
+	m, err := NewModel(testDir, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	items := m.selectedTests()
+	if len(items) == 0 {
+		items = m.tests
+	}
+	for _, item := range items {
+		m.runner.QueueTest(item)
+	}
+
+	// Wait until all queued tests have completed
+	<-m.runner.Idle()
+
+	data, err := marshalJSONReport(m.runner)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+
+	for _, item := range items {
+		if status, _ := item.LogState(); status == runner.StatusFailed {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestBuildJSONReport(t *testing.T) {
+	report := buildJSONReport([]runner.TestSnapshot{
+		{
+			Info:         runner.TestInfo{Name: "TestFoo", Package: "sub", File: "sub/foo_test.go", Line: 12},
+			Status:       runner.StatusRunning,
+			Duration:     5 * time.Second,
+			LastDuration: 1500 * time.Millisecond,
+			Attempt:      2,
+			Coverage:     75.5,
+			LogFile:      "/logs/TestFoo.log",
+		},
+	})
+
+	expected := testReport{
+		Name:       "TestFoo",
+		Package:    "sub",
+		File:       "sub/foo_test.go",
+		Line:       12,
+		Status:     "running",
+		DurationMs: 1500,
+		Attempts:   2,
+		Coverage:   75.5,
+		LogFile:    "/logs/TestFoo.log",
+	}
+	if len(report) != 1 || report[0] != expected {
+		t.Errorf("buildJSONReport() = %+v, expected %+v", report, expected)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// reviewBlockedKeys are the left pane keys that run tests or depend on the
+// test sources, so they aren't available in review mode
+var reviewBlockedKeys = map[string]bool{
+	"g": true, "t": true, "^": true, "R": true, "V": true, "K": true, "U": true, "G": true, "F": true, "X": true, "ctrl+x": true,
+	"b": true, "B": true, "r": true, "e": true, "E": true, "o": true, "Q": true, "I": true, "!": true,
+	"+": true, "=": true, "-": true, "_": true, "ctrl+r": true, "w": true, "C": true, "c": true, ">": true, "<": true, "T": true, "(": true, ")": true,
+}
+
+// newReviewModel creates a model that browses the logs in a log directory
+// without discovering or running any tests. The tests are inferred from the
+// log file names.
+func newReviewModel(logDir string, opts Options) (*Model, error) {
+	testRunner := runner.NewTestRunner(logDir, logDir, 1, opts.TestTimeout)
+	testRunner.SetLogTimeFormat(opts.LogTimeFormat)
+
+	tests, err := runner.DiscoverLogTests(logDir, testRunner.LogTimeFormat())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	items := make([]*runner.TestItem, len(tests))
+	for i, t := range tests {
+		item := &runner.TestItem{
+			Info:   t,
+			Status: runner.StatusIdle,
+		}
+
+		// Show the result of the most recent run
+		if logFile, modTime := runner.FindMostRecentLogFile(logDir, t); logFile != "" {
+			item.Status = logResultStatus(logFile)
+			item.StartedAt = modTime
+			item.FinishedAt = modTime
+		}
+		items[i] = item
+	}
+
+	m := &Model{
+		tests:            items,
+		filteredList:     items,
+		runner:           testRunner,
+		testDir:          logDir,
+		logDir:           logDir,
+		autoScroll:       true,
+		reviewMode:       true,
+		showFinishedAgo:  true, // The duration isn't known from the logs
+		flakeRuns:        defaultFlakeRuns,
+		confirmThreshold: defaultConfirmThreshold,
+		sortMode:         SortByName,
+	}
+
+	m.applyFilter()
+	testRunner.SetTestList(&m.tests)
+
+	return m, nil
+}
+
+// logResultStatus determines whether the test in a log file passed, failed
+// or was skipped, based on the output of go test. Logs without a result
+// (e.g. from a cancelled run) are reported as idle.
+func logResultStatus(logFile string) runner.TestStatus {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return runner.StatusIdle
+	}
+	defer f.Close()
+
+	status := runner.StatusIdle
+	skipped := false
+	for _, line := range readLines(f) {
+		switch {
+		case strings.HasPrefix(line, "--- FAIL"), strings.HasPrefix(line, "FAIL"):
+			return runner.StatusFailed
+		case strings.HasPrefix(line, "--- SKIP"):
+			skipped = true
+		case line == "PASS", strings.HasPrefix(line, "ok "):
+			status = runner.StatusPassed
+		}
+	}
+
+	if status == runner.StatusPassed && skipped {
+		return runner.StatusSkipped
+	}
+	return status
+}
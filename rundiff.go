@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// maxDiffEdits is the maximum number of added and removed lines for which a
+// line diff is computed. Beyond it, the differing lines are shown as removed
+// and added as a whole, so very different logs don't stall the UI.
+const maxDiffEdits = 2000
+
+// diffLine is a line in the diff of the output of two runs
+type diffLine struct {
+	kind DiffKind // DiffNone for unchanged lines
+	text string
+}
+
+// diffLines returns a line diff that turns the lines before into the lines
+// after. The lines that both start and end with are skipped before diffing,
+// so logs that only differ in the middle are cheap to compare.
+func diffLines(before, after []string) (lines []diffLine, complete bool) {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	for _, line := range before[:prefix] {
+		lines = append(lines, diffLine{DiffNone, line})
+	}
+	middle, complete := myersDiff(before[prefix:len(before)-suffix], after[prefix:len(after)-suffix])
+	lines = append(lines, middle...)
+	for _, line := range before[len(before)-suffix:] {
+		lines = append(lines, diffLine{DiffNone, line})
+	}
+	return lines, complete
+}
+
+// myersDiff computes a shortest line diff using Myers' algorithm. When more
+// than maxDiffEdits edits are needed, all lines before are reported as
+// removed and all lines after as added, and complete is false.
+func myersDiff(before, after []string) ([]diffLine, bool) {
+	n, m := len(before), len(after)
+	maxEdits := min(n+m, maxDiffEdits)
+
+	// trace[d][k+d] is the furthest x on diagonal k (x-y) after d edits
+	var trace [][]int
+	found := false
+	for d := 0; d <= maxEdits && !found; d++ {
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && trace[d-1][k-1+d-1] < trace[d-1][k+1+d-1]):
+				x = trace[d-1][k+1+d-1] // Add a line of after
+			default:
+				x = trace[d-1][k-1+d-1] + 1 // Remove a line of before
+			}
+			y := x - k
+			for x < n && y < m && before[x] == after[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+		trace = append(trace, v)
+	}
+
+	if !found {
+		lines := make([]diffLine, 0, n+m)
+		for _, line := range before {
+			lines = append(lines, diffLine{DiffRemoved, line})
+		}
+		for _, line := range after {
+			lines = append(lines, diffLine{DiffAdded, line})
+		}
+		return lines, false
+	}
+
+	// Walk back from the end to collect the edits in reverse order
+	var lines []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+		prevX, prevY := 0, 0
+		if d > 0 {
+			prev := trace[d-1]
+			prevK := k - 1
+			if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+				prevK = k + 1
+			}
+			prevX = prev[prevK+d-1]
+			prevY = prevX - prevK
+		}
+
+		for x > prevX && y > prevY {
+			lines = append(lines, diffLine{DiffNone, before[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, diffLine{DiffAdded, after[y-1]})
+			} else {
+				lines = append(lines, diffLine{DiffRemoved, before[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(lines)
+	return lines, true
+}
+
+// markDiffBaseline marks the log in the output pane as the baseline that
+// other runs are compared with
+func (m *Model) markDiffBaseline() {
+	if m.currentLogFile == "" || (m.combinedOutput && len(m.selectedTests()) > 0) {
+		m.setStatusMessage("no log to mark as the diff baseline")
+		return
+	}
+	m.diffBaseline = m.currentLogFile
+	m.setStatusMessage("marked " + filepath.Base(m.diffBaseline) + " as the diff baseline (u to compare)")
+}
+
+// toggleRunDiff switches between the output and the diff with the baseline
+func (m *Model) toggleRunDiff() {
+	if !m.runDiff && m.diffBaseline == "" {
+		m.setStatusMessage("mark a baseline run with m first")
+		return
+	}
+	m.runDiff = !m.runDiff
+	m.runDiffKey = ""
+	m.currentLogFile = ""
+	m.resetOutputScroll()
+	m.refreshOutput()
+}
+
+// refreshRunDiff shows the diff between the output of the baseline run and
+// the run of the test under the cursor
+func (m *Model) refreshRunDiff(item *runner.TestItem) {
+	_, logFile := item.LogState()
+	if logFile == "" {
+		logFile, _ = runner.FindMostRecentLogFile(m.logDir, item.Info)
+	}
+
+	// The diff is only computed again when one of the logs changed
+	baseStat, baseErr := os.Stat(m.diffBaseline)
+	logStat, logErr := os.Stat(logFile)
+	key := logFile
+	if baseErr == nil && logErr == nil {
+		key = fmt.Sprintf("%s\n%d\n%d", logFile, baseStat.Size(), logStat.Size())
+	}
+	if key == m.runDiffKey {
+		return
+	}
+	m.runDiffKey = key
+	m.currentLogFile = logFile
+	m.currentLogTimestamp = time.Time{}
+	m.viewingHistorical = false
+
+	var lines []string
+	var kinds []DiffKind
+	switch {
+	case baseErr != nil:
+		lines = []string{"(the baseline log can't be read: " + baseErr.Error() + ")"}
+	case logErr != nil:
+		lines = []string{"(this test has no log to compare with the baseline)"}
+	case logFile == m.diffBaseline:
+		lines = []string{"(this is the baseline run; run the test again or select another test to compare)"}
+	default:
+		m.currentLogSize = logStat.Size()
+		before, _ := readLogLines(m.diffBaseline)
+		after, _ := readLogLines(logFile)
+		diff, complete := diffLines(before, after)
+
+		lines = append(lines,
+			fmt.Sprintf("--- %s (%s)", filepath.Base(m.diffBaseline), baseStat.ModTime().Format("2006-01-02 15:04:05")),
+			fmt.Sprintf("+++ %s (%s)", filepath.Base(logFile), logStat.ModTime().Format("2006-01-02 15:04:05")))
+		kinds = append(kinds, DiffHeader, DiffHeader)
+		if !complete {
+			lines = append(lines, fmt.Sprintf("@@ more than %d lines differ, so the differing lines are shown as a whole @@", maxDiffEdits))
+			kinds = append(kinds, DiffHunk)
+		}
+
+		for _, line := range diff {
+			prefix := "  "
+			switch line.kind {
+			case DiffAdded:
+				prefix = "+ "
+			case DiffRemoved:
+				prefix = "- "
+			}
+			lines = append(lines, prefix+line.text)
+			kinds = append(kinds, line.kind)
+		}
+	}
+
+	m.setOutputLines(lines)
+	m.diffKinds = kinds
+}
+
+// readLogLines reads all lines of a log file
+func readLogLines(logFile string) ([]string, error) {
+	file, err := os.Open(logFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readLines(file), nil
+}
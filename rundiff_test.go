@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatDiff renders a diff with a +, - or space in front of each line
+func formatDiff(lines []diffLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		switch line.kind {
+		case DiffAdded:
+			b.WriteString("+")
+		case DiffRemoved:
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line.text + "\n")
+	}
+	return b.String()
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		before, after []string
+		expected      string
+	}{
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, " a\n b\n c\n"},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, " a\n-b\n+x\n c\n"},
+		{[]string{"=== RUN", "ok", "PASS"}, []string{"=== RUN", "ok", "boom", "FAIL"}, " === RUN\n ok\n-PASS\n+boom\n+FAIL\n"},
+		{nil, []string{"a"}, "+a\n"},
+		{[]string{"a", "b"}, nil, "-a\n-b\n"},
+		{[]string{"x", "a", "b", "c", "y"}, []string{"a", "b", "z", "c"}, "-x\n a\n b\n+z\n c\n-y\n"},
+	}
+
+	for _, tt := range tests {
+		lines, complete := diffLines(tt.before, tt.after)
+		if got := formatDiff(lines); got != tt.expected || !complete {
+			t.Errorf("diffLines(%q, %q) = %q (complete: %v), expected %q", tt.before, tt.after, got, complete, tt.expected)
+		}
+	}
+}
+
+func TestDiffLinesTooManyEdits(t *testing.T) {
+	// One log is much longer than the other
+	before := []string{"=== RUN", "--- PASS"}
+	after := []string{"=== RUN"}
+	for i := 0; i < maxDiffEdits+10; i++ {
+		after = append(after, fmt.Sprintf("line %d", i))
+	}
+
+	lines, complete := diffLines(before, after)
+	if complete {
+		t.Fatal("Expected the diff to be incomplete")
+	}
+	if len(lines) != 1+1+maxDiffEdits+10 {
+		t.Errorf("Expected the common line, the removed line and all added lines, got %d lines", len(lines))
+	}
+	if lines[0].kind != DiffNone || lines[1].kind != DiffRemoved || lines[2].kind != DiffAdded {
+		t.Errorf("Unexpected diff: %s", formatDiff(lines[:3]))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shellDoneMsg is sent when the shell opened from the TUI exits
+type shellDoneMsg struct {
+	err error
+}
+
+// openShell suspends the TUI and opens a shell in the package directory of
+// the current test. The TUI is restored when the shell exits.
+func (m *Model) openShell() tea.Cmd {
+	if len(m.filteredList) == 0 || m.cursor >= len(m.filteredList) {
+		return nil
+	}
+
+	item := m.filteredList[m.cursor]
+	dir := filepath.Join(m.testDir, item.Info.Package)
+	if item.Info.File != "" {
+		dir = filepath.Dir(item.Info.File)
+	}
+
+	cmd := exec.Command(userShell())
+	cmd.Dir = dir
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return shellDoneMsg{err: err}
+	})
+}
+
+// userShell returns the shell of the user
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// handleShellDone reports a shell that couldn't be started
+func (m *Model) handleShellDone(msg shellDoneMsg) {
+	// A non-zero exit status of the last command in the shell isn't an error
+	var exitErr *exec.ExitError
+	if msg.err != nil && !errors.As(msg.err, &exitErr) {
+		m.setStatusMessage(fmt.Sprintf("shell failed: %v", msg.err))
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// stateFile is the name of the file in the log directory that holds the
+// session state of the test directory
+const stateFile = "state.json"
+
+// sessionState is the state that is remembered between sessions for a test
+// directory
+type sessionState struct {
+	Parallel  int           `json:"parallel,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`   // Timeout for each test
+	PassRate  *int          `json:"passRate,omitempty"`  // Pass rate (percentage) at the end of the session
+	TestFlags *string       `json:"testFlags,omitempty"` // Last-used extra go test flags
+	Tests     []savedTest   `json:"tests,omitempty"`     // Tests in list order
+}
+
+// savedTest is the state of a test that is remembered between sessions
+type savedTest struct {
+	Package    string        `json:"package,omitempty"`
+	Name       string        `json:"name"`
+	Selected   bool          `json:"selected,omitempty"`
+	Status     string        `json:"status,omitempty"` // Status of the last finished run
+	FinishedAt time.Time     `json:"finishedAt,omitzero"`
+	Duration   time.Duration `json:"duration,omitempty"`
+}
+
+// loadState loads the session state from the log directory. A missing state
+// file results in an empty state.
+func loadState(logDir string) (sessionState, error) {
+	var state sessionState
+
+	data, err := os.ReadFile(filepath.Join(logDir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return sessionState{}, err
+	}
+	return state, nil
+}
+
+// saveState writes the session state to the log directory
+func saveState(logDir string, state sessionState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash never leaves a partial file
+	tmpFile := filepath.Join(logDir, stateFile+".tmp")
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filepath.Join(logDir, stateFile))
+}
+
+// saveState remembers the current session state, so it can be restored when
+// the test directory is opened again. Errors are ignored, as the state is a
+// convenience only.
+func (m *Model) saveState() {
+	if m.noPersist {
+		return
+	}
+
+	state := sessionState{
+		Parallel: m.runner.GetMaxParallel(),
+		Timeout:  m.runner.GetTestTimeout(),
+		PassRate: m.previousPassRate,
+	}
+	testFlags := strings.Join(m.runner.GetTestFlags(), " ")
+	state.TestFlags = &testFlags
+	state.Tests = m.savedTests()
+
+	// Keep the rate of the previous session when nothing finished yet
+	if rate, ok := m.passRate(); ok {
+		state.PassRate = &rate
+	}
+
+	saveState(m.logDir, state)
+}
+
+// savedTests returns the state of the tests in list order
+func (m *Model) savedTests() []savedTest {
+	saved := make([]savedTest, 0, len(m.tests))
+	for _, t := range m.tests {
+		test := savedTest{
+			Package:  t.Info.Package,
+			Name:     t.Info.Name,
+			Selected: t.Selected,
+		}
+		if t.Status.Finished() {
+			test.Status = t.Status.String()
+			test.FinishedAt = t.FinishedAt
+		}
+
+		// The duration of the last completed run is also kept while a test
+		// runs again, so fast-first mode can order the tests in the next
+		// session
+		test.Duration = t.LastDuration
+		saved = append(saved, test)
+	}
+	return saved
+}
+
+// restoreTests applies the saved state to the discovered tests. Tests keep
+// their saved order, tests that weren't saved are appended in discovery
+// order and saved tests that no longer exist are dropped.
+func restoreTests(items []*runner.TestItem, saved []savedTest) []*runner.TestItem {
+	byKey := make(map[string]*runner.TestItem, len(items))
+	for _, item := range items {
+		byKey[item.Info.Package+"/"+item.Info.Name] = item
+	}
+
+	restored := make([]*runner.TestItem, 0, len(items))
+	for _, test := range saved {
+		key := test.Package + "/" + test.Name
+		item, ok := byKey[key]
+		if !ok {
+			continue
+		}
+		delete(byKey, key)
+
+		item.Selected = test.Selected
+		item.LastDuration = test.Duration
+		if status, ok := parseFinishedStatus(test.Status); ok {
+			item.Status = status
+			item.FinishedAt = test.FinishedAt
+			item.StartedAt = test.FinishedAt.Add(-test.Duration)
+		}
+		restored = append(restored, item)
+	}
+
+	for _, item := range items {
+		if _, ok := byKey[item.Info.Package+"/"+item.Info.Name]; ok {
+			restored = append(restored, item)
+		}
+	}
+	return restored
+}
+
+// parseFinishedStatus parses the status of a finished run
+func parseFinishedStatus(s string) (runner.TestStatus, bool) {
+	for _, status := range []runner.TestStatus{runner.StatusPassed, runner.StatusFailed, runner.StatusSkipped} {
+		if s == status.String() {
+			return status, true
+		}
+	}
+	return runner.StatusIdle, false
+}
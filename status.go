@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+// statusResponse is the JSON document served by the status endpoint
+type statusResponse struct {
+	MaxParallel int                `json:"maxParallel"`
+	Running     int                `json:"running"`
+	Queued      int                `json:"queued"`
+	Passed      int                `json:"passed"`
+	Failed      int                `json:"failed"`
+	Tests       []testStatusOutput `json:"tests"`
+}
+
+// testStatusOutput is the status of a single test in the status endpoint
+type testStatusOutput struct {
+	Name       string `json:"name"`
+	Package    string `json:"package"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// statusServer is a running status endpoint
+type statusServer struct {
+	server *http.Server
+	done   chan error // Receives the error that stopped the server
+}
+
+// startStatusServer serves a read-only JSON view of the runner state on the
+// given address. It returns an error if the address can't be listened on.
+func startStatusServer(addr string, r *runner.TestRunner) (*statusServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		data, err := json.Marshal(buildStatusResponse(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(data, '\n'))
+	})
+
+	s := &statusServer{
+		server: &http.Server{Addr: listener.Addr().String(), Handler: mux},
+		done:   make(chan error, 1),
+	}
+	go func() {
+		s.done <- s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// Close stops the server. It returns the error that stopped the server
+// before, if any.
+func (s *statusServer) Close() error {
+	s.server.Close()
+	if err := <-s.done; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// buildStatusResponse collects the current state of the runner
+func buildStatusResponse(r *runner.TestRunner) statusResponse {
+	resp := statusResponse{
+		MaxParallel: r.GetMaxParallel(),
+		Tests:       []testStatusOutput{},
+	}
+
+	for _, t := range r.Snapshot() {
+		switch t.Status {
+		case runner.StatusRunning:
+			resp.Running++
+		case runner.StatusQueued:
+			resp.Queued++
+		case runner.StatusPassed:
+			resp.Passed++
+		case runner.StatusFailed:
+			resp.Failed++
+		}
+
+		resp.Tests = append(resp.Tests, testStatusOutput{
+			Name:       t.Info.Name,
+			Package:    t.Info.Package,
+			Status:     t.Status.String(),
+			DurationMs: t.Duration.Milliseconds(),
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestStatusServer(t *testing.T) {
+	tests := []*runner.TestItem{{Info: runner.TestInfo{Name: "TestFoo"}, Status: runner.StatusPassed}}
+	r := runner.NewTestRunner(t.TempDir(), t.TempDir(), 2, 0)
+	r.SetTestList(&tests)
+
+	s, err := startStatusServer("127.0.0.1:0", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + s.server.Addr
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status statusResponse
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.MaxParallel != 2 || status.Passed != 1 || len(status.Tests) != 1 || status.Tests[0].Name != "TestFoo" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// The listener doesn't outlive the server
+	if err := s.Close(); err != nil {
+		t.Errorf("Expected a clean close, got %v", err)
+	}
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("Expected the status endpoint to be closed")
+	}
+}
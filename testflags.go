@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startTestFlags starts editing the extra go test flags, starting from the
+// flags that are currently used
+func (m *Model) startTestFlags() {
+	m.testFlagsMode = true
+	m.testFlagsText = strings.Join(m.runner.GetTestFlags(), " ")
+}
+
+// handleTestFlagsKey handles keys while the extra go test flags are edited
+func (m *Model) handleTestFlagsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+
+	switch key {
+	case "enter":
+		// Apply the flags to tests that are started from now on
+		m.testFlagsMode = false
+		m.runner.SetTestFlags(strings.Fields(m.testFlagsText))
+		m.saveState()
+		if flags := m.runner.GetTestFlags(); len(flags) > 0 {
+			m.setStatusMessage("test flags for new runs: " + strings.Join(flags, " "))
+		} else {
+			m.setStatusMessage("test flags cleared")
+		}
+
+	case "esc":
+		m.testFlagsMode = false
+
+	case "backspace":
+		if len(m.testFlagsText) > 0 {
+			m.testFlagsText = trimLastRune(m.testFlagsText)
+		}
+
+	default:
+		if len(key) == 1 {
+			m.testFlagsText += key
+		}
+	}
+
+	return m, nil
+}
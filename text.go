@@ -0,0 +1,55 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// truncateName shortens text to a display width, ending it with "..." when
+// it's cut. Runes are never split and wide characters (e.g. CJK and emoji)
+// count as two columns. Text is left alone when there is no room for more
+// than the ellipsis.
+func truncateName(s string, width int) string {
+	if width <= 3 || ansi.StringWidth(s) <= width {
+		return s
+	}
+	return ansi.Truncate(s, width, "...")
+}
+
+// columnOffset returns the byte offset in s after advancing the given number
+// of columns from the byte offset start. A wide character that doesn't fit
+// entirely is left out.
+func columnOffset(s string, start, columns int) int {
+	offset := start
+	for offset < len(s) {
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		width := runewidth.RuneWidth(r)
+		if width > columns {
+			break
+		}
+		columns -= width
+		offset += size
+	}
+	return offset
+}
+
+// skipColumns returns the byte offset in s of the first character that starts
+// at or after the given column. A wide character that starts before the column
+// is skipped entirely.
+func skipColumns(s string, columns int) int {
+	offset := 0
+	for offset < len(s) && columns > 0 {
+		r, size := utf8.DecodeRuneInString(s[offset:])
+		columns -= runewidth.RuneWidth(r)
+		offset += size
+	}
+	return offset
+}
+
+// trimLastRune removes the last character of typed text
+func trimLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
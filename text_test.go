@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestTruncateName(t *testing.T) {
+	tests := []struct {
+		name     string
+		width    int
+		expected string
+	}{
+		{"TestShort", 20, "TestShort"},
+		{"TestLongName", 8, "TestL..."},
+		{"Test日本語の名前", 12, "Test日本..."},
+		{"Test🚀🚀🚀🚀", 9, "Test🚀..."},
+		{"TestLongName", 3, "TestLongName"}, // No room to truncate
+	}
+
+	for _, tt := range tests {
+		got := truncateName(tt.name, tt.width)
+		if got != tt.expected {
+			t.Errorf("truncateName(%q, %d) = %q, expected %q", tt.name, tt.width, got, tt.expected)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateName(%q, %d) split a rune: %q", tt.name, tt.width, got)
+		}
+	}
+}
+
+func TestColumnOffset(t *testing.T) {
+	tests := []struct {
+		line           string
+		start, columns int
+		expected       int
+	}{
+		{"abcdef", 0, 3, 3},
+		{"abcdef", 2, 10, 6},
+		{"日本語", 0, 4, 6},
+		{"日本語", 0, 3, 3}, // The second character doesn't fit entirely
+		{"a🚀b", 0, 2, 1},
+		{"a🚀b", 1, 3, 6},
+	}
+
+	for _, tt := range tests {
+		if got := columnOffset(tt.line, tt.start, tt.columns); got != tt.expected {
+			t.Errorf("columnOffset(%q, %d, %d) = %d, expected %d", tt.line, tt.start, tt.columns, got, tt.expected)
+		}
+	}
+}
+
+func TestTrimLastRune(t *testing.T) {
+	tests := map[string]string{
+		"":    "",
+		"abc": "ab",
+		"日本":  "日",
+		"ab🚀": "ab",
+		"🚀":   "",
+	}
+
+	for s, expected := range tests {
+		if got := trimLastRune(s); got != expected {
+			t.Errorf("trimLastRune(%q) = %q, expected %q", s, got, expected)
+		}
+	}
+}
+
+func TestRenderWideCharacters(t *testing.T) {
+	dir := t.TempDir()
+	src := "package foo\n\nimport \"testing\"\n\n" +
+		"// Test日本語の名前 checks 🚀🚀🚀 rocket launches with a very long description\n" +
+		"func Test日本語の名前とても長いテスト名前(t *testing.T) {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewModel(dir, Options{LogDir: t.TempDir(), NoPersist: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.showDocs = true
+
+	for _, width := range []int{40, 61, 100} {
+		m.Update(tea.WindowSizeMsg{Width: width, Height: 12})
+		m.setOutputLines([]string{
+			strings.Repeat("日本語", 40),
+			"a" + strings.Repeat("🚀", 60),
+			strings.Repeat("ascii ", 30),
+		})
+
+		for _, scroll := range []int{0, 1, 4} {
+			m.horizontalScroll = scroll
+			for i, line := range strings.Split(m.View(), "\n") {
+				if !utf8.ValidString(line) {
+					t.Errorf("Line %d at width %d (scrolled %d) has a broken rune: %q", i, width, scroll, line)
+				}
+				if w := lipgloss.Width(line); w > width {
+					t.Errorf("Line %d at width %d (scrolled %d) is %d columns wide: %q", i, width, scroll, w, line)
+				}
+			}
+		}
+	}
+}
+
+func TestHorizontalScrollWideCharacters(t *testing.T) {
+	m, err := NewModel(t.TempDir(), Options{LogDir: t.TempDir(), NoPersist: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 60, Height: 10})
+	line := "a" + strings.Repeat("🚀", 50) + "end"
+	m.setOutputLines([]string{line})
+
+	// The scroll position stops where the end of the line is visible
+	expectedMax := 1 + 50*2 + 3 - m.outputWidth()
+	for range 200 {
+		m.scrollHorizontal(1)
+	}
+	if m.horizontalScroll != expectedMax {
+		t.Errorf("Expected the scroll position to stop at %d, got %d", expectedMax, m.horizontalScroll)
+	}
+	m.scrollHorizontal(-1000)
+	if m.horizontalScroll != 0 {
+		t.Errorf("Expected the scroll position to stop at 0, got %d", m.horizontalScroll)
+	}
+
+	for scroll := 0; scroll <= expectedMax; scroll++ {
+		m.horizontalScroll = scroll
+		start := skipColumns(line, scroll)
+		if !utf8.RuneStart(line[start]) {
+			t.Fatalf("Scrolling %d columns starts within a rune at byte %d", scroll, start)
+		}
+		view := m.View()
+		if !utf8.ValidString(view) {
+			t.Fatalf("Scrolling %d columns renders a broken rune", scroll)
+		}
+		if scroll == expectedMax && !strings.Contains(view, "🚀end") {
+			t.Errorf("Expected the end of the line to be visible when scrolled to the end")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// timeoutSteps are the test timeouts that can be selected at runtime
+var timeoutSteps = []time.Duration{
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+}
+
+// nextTimeout returns the next step above (dir > 0) or below (dir < 0) the
+// current timeout. The current timeout is returned when there is no such
+// step.
+func nextTimeout(current time.Duration, dir int) time.Duration {
+	if dir > 0 {
+		for _, step := range timeoutSteps {
+			if step > current {
+				return step
+			}
+		}
+	} else {
+		for i := len(timeoutSteps) - 1; i >= 0; i-- {
+			if timeoutSteps[i] < current {
+				return timeoutSteps[i]
+			}
+		}
+	}
+	return current
+}
+
+// formatTimeout formats a timeout compactly, e.g. "30m" instead of "30m0s"
+func formatTimeout(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
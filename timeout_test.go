@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTimeout(t *testing.T) {
+	tests := []struct {
+		current  time.Duration
+		dir      int
+		expected time.Duration
+	}{
+		{30 * time.Minute, 1, time.Hour},
+		{30 * time.Minute, -1, 10 * time.Minute},
+		{45 * time.Second, 1, time.Minute},
+		{45 * time.Second, -1, 30 * time.Second},
+		{10 * time.Second, -1, 10 * time.Second},
+		{2 * time.Hour, 1, 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := nextTimeout(tt.current, tt.dir); got != tt.expected {
+			t.Errorf("nextTimeout(%s, %d) = %s, expected %s", tt.current, tt.dir, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatTimeout(t *testing.T) {
+	tests := map[time.Duration]string{
+		10 * time.Second:           "10s",
+		30 * time.Minute:           "30m",
+		2 * time.Hour:              "2h",
+		90 * time.Second:           "1m30s",
+		time.Hour + 30*time.Minute: "1h30m",
+		time.Hour + 30*time.Minute + 5*time.Second: "1h30m5s",
+	}
+
+	for d, expected := range tests {
+		if got := formatTimeout(d); got != expected {
+			t.Errorf("formatTimeout(%s) = %q, expected %q", d, got, expected)
+		}
+	}
+}
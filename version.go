@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, injected at build time using:
+//
+//	go build -ldflags "-X main.version=v1.0.0 -X main.commit=abc1234"
+var (
+	version = ""
+	commit  = ""
+)
+
+// versionString returns the version, commit and Go version of this build
+func versionString() string {
+	v, c := version, commit
+
+	// Fall back to the build information embedded by the Go toolchain
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+		if c == "" {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					c = setting.Value
+				}
+			}
+		}
+	}
+
+	if v == "" {
+		v = "(devel)"
+	}
+	if c == "" {
+		c = "unknown"
+	}
+
+	return fmt.Sprintf("test-runner %s (commit %s, %s %s/%s)", v, c, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ramondeklein/test-runner/pkg/runner"
 )
 
 var (
@@ -16,30 +20,123 @@ var (
 	cursorColor          = lipgloss.Color("212")
 	statusBarColor       = lipgloss.Color("236")
 	statusTextColor      = lipgloss.Color("252")
+	queuedTimerColor     = lipgloss.Color("214")
+	runningTimerColor    = lipgloss.Color("39")
+	flakyColor           = lipgloss.Color("214")
+	matchColor           = lipgloss.Color("58")
+	currentMatchColor    = lipgloss.Color("220")
+
+	// Reliability colors, from reliable to always failing
+	reliabilityColors = []lipgloss.Color{
+		lipgloss.Color("42"),
+		lipgloss.Color("226"),
+		lipgloss.Color("214"),
+		lipgloss.Color("196"),
+	}
+
+	// Package colors, subtle enough to not distract from the status
+	packageColors = []lipgloss.AdaptiveColor{
+		{Light: "24", Dark: "74"},
+		{Light: "94", Dark: "180"},
+		{Light: "28", Dark: "108"},
+		{Light: "90", Dark: "140"},
+		{Light: "30", Dark: "73"},
+		{Light: "130", Dark: "174"},
+		{Light: "58", Dark: "144"},
+		{Light: "61", Dark: "110"},
+	}
+
+	// Diff colors
+	diffColors = map[DiffKind]lipgloss.Color{
+		DiffHeader:  lipgloss.Color("245"),
+		DiffHunk:    lipgloss.Color("39"),
+		DiffAdded:   lipgloss.Color("42"),
+		DiffRemoved: lipgloss.Color("203"),
+	}
+
+	// Mini-map colors and the priority of each status when tests share a cell
+	miniMapColors = map[runner.TestStatus]lipgloss.Color{
+		runner.StatusIdle:    lipgloss.Color("240"),
+		runner.StatusQueued:  lipgloss.Color("214"),
+		runner.StatusRunning: lipgloss.Color("39"),
+		runner.StatusPassed:  lipgloss.Color("42"),
+		runner.StatusFailed:  lipgloss.Color("196"),
+		runner.StatusSkipped: lipgloss.Color("245"),
+	}
+	miniMapPriority = map[runner.TestStatus]int{
+		runner.StatusIdle:    0,
+		runner.StatusSkipped: 1,
+		runner.StatusPassed:  1,
+		runner.StatusQueued:  2,
+		runner.StatusRunning: 3,
+		runner.StatusFailed:  4,
+	}
 
 	// Status icons
-	statusIcons = map[TestStatus]string{
-		StatusIdle:    "   ",
-		StatusQueued:  "🍵 ",
-		StatusRunning: "🏃 ",
-		StatusPassed:  "✅ ",
-		StatusFailed:  "❌ ",
+	statusIcons = map[runner.TestStatus]string{
+		runner.StatusIdle:    "   ",
+		runner.StatusQueued:  "🍵 ",
+		runner.StatusRunning: "🏃 ",
+		runner.StatusPassed:  "✅ ",
+		runner.StatusFailed:  "❌ ",
+		runner.StatusSkipped: "⏩ ",
+	}
+
+	// Icon of a test that failed because its package didn't build
+	buildFailedIcon = "🔨 "
+
+	// Benchmark icons (finished benchmarks use the status icons)
+	benchmarkIcons = map[runner.TestStatus]string{
+		runner.StatusIdle:    "📊 ",
+		runner.StatusRunning: "📈 ",
 	}
 )
 
+const (
+	// Absolute minimum terminal size, below which nothing is rendered
+	minTerminalWidth  = 20
+	minTerminalHeight = 6
+
+	// Minimum terminal width to show both panes side by side
+	splitPaneMinWidth = 60
+)
+
 // View renders the UI
 func (m *Model) View() string {
 	if m.width == 0 || m.height == 0 {
 		return "Loading..."
 	}
 
+	// Refuse to render the layout when it can't possibly fit
+	if m.width < minTerminalWidth || m.height < minTerminalHeight {
+		return fmt.Sprintf("Terminal too small (%dx%d)\nNeed at least %dx%d",
+			m.width, m.height, minTerminalWidth, minTerminalHeight)
+	}
+
+	contentHeight := m.height - 2 // -2 for status bar
+	statusBar := m.renderStatusBar()
+
+	// The help overlay replaces the panes, while tests keep running
+	if m.showHelp {
+		return lipgloss.JoinVertical(lipgloss.Left, renderHelp(m.width, contentHeight), statusBar)
+	}
+
+	// Show only the focused pane when there is no room for both
+	if m.isSinglePane() {
+		var pane string
+		if m.focusedPane == LeftPane {
+			pane = m.renderLeftPane(m.width, contentHeight)
+		} else {
+			pane = m.renderRightPane(m.width, contentHeight)
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, pane, statusBar)
+	}
+
 	leftWidth := m.width / 3
 	rightWidth := m.width - leftWidth - 1 // -1 for separator
-	contentHeight := m.height - 2         // -2 for status bar
 
 	leftPane := m.renderLeftPane(leftWidth, contentHeight)
 	rightPane := m.renderRightPane(rightWidth, contentHeight)
-	statusBar := m.renderStatusBar()
 
 	// Combine panes side by side
 	content := lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
@@ -47,6 +144,11 @@ func (m *Model) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, content, statusBar)
 }
 
+// isSinglePane reports whether the terminal is too narrow to show both panes
+func (m *Model) isSinglePane() bool {
+	return m.width < splitPaneMinWidth
+}
+
 // renderLeftPane renders the test list pane
 func (m *Model) renderLeftPane(width, height int) string {
 	borderColor := unfocusedBorderColor
@@ -57,38 +159,68 @@ func (m *Model) renderLeftPane(width, height int) string {
 	style := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(borderColor).
-		Width(width - 2).
-		Height(height - 2)
+		Width(max(width-2, 0)).
+		Height(max(height-2, 0))
+
+	// Reserve room for the mini-map column
+	miniMapWidth := 0
+	if m.showMiniMap && width > 20 {
+		miniMapWidth = 2
+		width -= miniMapWidth
+	}
 
 	// Build content
 	var content strings.Builder
 
-	// Filter line
+	// Filter and jump-to search lines
+	headerLines := 0
 	if m.filterMode {
 		content.WriteString(fmt.Sprintf("Filter: %s█\n", m.filterText))
+		headerLines++
 	} else if m.filterText != "" {
 		content.WriteString(fmt.Sprintf("Filter: %s\n", m.filterText))
+		headerLines++
 	}
-
-	// Calculate visible range
-	listHeight := height - 3 // Account for border and potential filter line
-	if m.filterMode || m.filterText != "" {
-		listHeight--
+	if m.jumpMode {
+		content.WriteString(fmt.Sprintf("Jump: %s█\n", m.jumpText))
+		headerLines++
+	}
+	if m.testFlagsMode {
+		content.WriteString(fmt.Sprintf("Test flags: %s█\n", m.testFlagsText))
+		headerLines++
 	}
 
-	startIdx := 0
-	if m.cursor >= listHeight {
-		startIdx = m.cursor - listHeight + 1
+	// Calculate visible range
+	listHeight := height - 3 - headerLines // Account for border and the filter and jump lines
+	if listHeight < 1 {
+		listHeight = 1
 	}
 
-	endIdx := startIdx + listHeight
-	if endIdx > len(m.filteredList) {
-		endIdx = len(m.filteredList)
+	rows := m.visibleRows()
+	cursorRow := m.cursorRow(rows)
+	startIdx, endIdx := listWindow(len(rows), cursorRow, listHeight)
+
+	// The number of rows scrolled off the top takes up a row, but only when
+	// the list is scrolled
+	indicatorStyle := lipgloss.NewStyle().Faint(true)
+	linesWritten := 0
+	if startIdx > 0 && listHeight > 1 {
+		content.WriteString(indicatorStyle.Render(fmt.Sprintf("▲ %d more", startIdx)) + "\n")
+		linesWritten++
 	}
 
-	// Render visible items
-	for i := startIdx; i < endIdx; i++ {
-		item := m.filteredList[i]
+	// Render visible rows
+	for r := startIdx; r < endIdx; r++ {
+		row := rows[r]
+		isCursor := r == cursorRow
+		if row.header {
+			content.WriteString(m.renderGroupHeader(row, width, isCursor))
+			if r < endIdx-1 {
+				content.WriteString("\n")
+			}
+			continue
+		}
+		item := m.filteredList[row.index]
 
 		// Build the line
 		var line strings.Builder
@@ -100,35 +232,142 @@ func (m *Model) renderLeftPane(width, height int) string {
 			line.WriteString(" ")
 		}
 
-		// Status icon
-		line.WriteString(statusIcons[item.Status])
+		// Status icon, with distinct icons for benchmarks that didn't finish
+		// and for tests whose package didn't build
+		icon := statusIcons[item.Status]
+		if benchIcon, ok := benchmarkIcons[item.Status]; ok && item.Info.Kind == runner.KindBenchmark {
+			icon = benchIcon
+		}
+		if item.Status == runner.StatusFailed && item.BuildFailed {
+			icon = buildFailedIcon
+		}
+		line.WriteString(icon)
+
+		// Reliability indicator based on the recent runs
+		indicatorWidth := 0
+		if m.showHistory {
+			indicatorWidth = 2
+			if h := m.testReliability(item.Info); h.runs > 0 {
+				dot := "•"
+				if !isCursor {
+					dot = lipgloss.NewStyle().Foreground(reliabilityColor(h)).Render(dot)
+				}
+				line.WriteString(dot + " ")
+			} else {
+				line.WriteString("  ")
+			}
+		}
 
-		// Test name (without "Test" prefix)
+		// Test name (without "Test" prefix), with subtests indented under
+		// their parent
 		name := strings.TrimPrefix(item.Info.Name, "Test")
-		if item.Info.Package != "" {
+		if item.Info.IsSubtest() {
+			depth := strings.Count(item.Info.Name, "/")
+			name = strings.Repeat("  ", depth) + item.Info.Name[strings.LastIndex(item.Info.Name, "/")+1:]
+		} else if item.Info.Package != "" && !m.grouped {
 			name = item.Info.Package + "/" + name
 		}
 
-		// Timer
+		// Parents show whether their subtests are collapsed
+		marker := ""
+		if !item.Info.IsSubtest() && m.hasSubtests[parentKey(item.Info)] {
+			marker = "▸ "
+			if m.expanded[parentKey(item.Info)] {
+				marker = "▾ "
+			}
+		}
+
+		// Timer (queue wait time is labeled to distinguish it from run time)
 		var timer string
-		if item.Status == StatusQueued || item.Status == StatusRunning ||
-			item.Status == StatusPassed || item.Status == StatusFailed {
-			dur := item.Duration()
-			timer = fmt.Sprintf(" %s", formatDuration(dur))
+		timerStyle := lipgloss.NewStyle()
+		switch item.Status {
+		case runner.StatusQueued:
+			timer = fmt.Sprintf(" wait %s", formatDuration(item.Duration()))
+			timerStyle = timerStyle.Foreground(queuedTimerColor)
+		case runner.StatusRunning:
+			timer = fmt.Sprintf(" %s", formatDuration(item.Duration()))
+			timerStyle = timerStyle.Foreground(runningTimerColor)
+		case runner.StatusPassed, runner.StatusFailed, runner.StatusSkipped:
+			if m.showFinishedAgo {
+				timer = fmt.Sprintf(" %s ago", formatDuration(time.Since(item.FinishedAt)))
+			} else if item.Bench != nil {
+				timer = " " + runner.FormatNsPerOp(item.Bench.NsPerOp)
+			} else {
+				timer = fmt.Sprintf(" %s", formatDuration(item.Duration()))
+			}
+		}
+
+		// Attempt while a failed test is being retried
+		if item.Attempt > 1 && (item.Status == runner.StatusQueued || item.Status == runner.StatusRunning) {
+			timer = fmt.Sprintf(" %d/%d", item.Attempt, m.runner.GetRetries()+1) + timer
+		}
+
+		// Coverage of the last run, when it was collected
+		if item.CoverProfile != "" && item.Status.Finished() {
+			timer = fmt.Sprintf(" %.1f%%", item.Coverage) + timer
+		}
+
+		// Failures out of the number of runs when a test ran repeatedly
+		if runs, failures := item.FlakeRate(); runs > 1 {
+			timer += fmt.Sprintf(" [%d/%d]", failures, runs)
+			if item.IsFlaky() {
+				timerStyle = timerStyle.Foreground(flakyColor)
+			}
 		}
 
 		// Truncate name if needed
-		maxNameWidth := width - 10 - len(timer) // Account for markers and timer
-		if len(name) > maxNameWidth && maxNameWidth > 3 {
-			name = name[:maxNameWidth-3] + "..."
+		// The grouped view shows the package in the header instead, with the
+		// tests indented below it
+		if m.grouped {
+			marker = "  " + marker
+		}
+
+		maxNameWidth := width - 10 - indicatorWidth - lipgloss.Width(marker) - lipgloss.Width(timer) // Account for markers and timer
+		name = truncateName(name, maxNameWidth)
+
+		line.WriteString(marker)
+
+		// Color the package prefix, so tests of the same package are grouped
+		pkgPrefix := item.Info.Package + "/"
+		if m.showPackageColors && item.Info.Package != "" && !m.grouped && !isCursor {
+			style := lipgloss.NewStyle().Foreground(packageColor(item.Info.Package))
+			if rest, ok := strings.CutPrefix(name, pkgPrefix); ok {
+				line.WriteString(style.Render(pkgPrefix) + rest)
+			} else {
+				line.WriteString(style.Render(name))
+			}
+		} else {
+			line.WriteString(name)
 		}
 
-		line.WriteString(name)
-		line.WriteString(timer)
+		// Doc comment as a dim second column, using the remaining width
+		if doc := testDoc(item.Info); m.showDocs && doc != "" {
+			if room := maxNameWidth - lipgloss.Width(name) - 1; room > 5 {
+				doc = truncateName(doc, room)
+				if isCursor {
+					line.WriteString(" " + doc)
+				} else {
+					line.WriteString(" " + lipgloss.NewStyle().Faint(true).Render(doc))
+				}
+				name += " " + doc
+			}
+		}
+
+		// Align the timer
+		if m.showDocs {
+			line.WriteString(strings.Repeat(" ", max(maxNameWidth-lipgloss.Width(name), 0)))
+		}
+
+		if isCursor {
+			// The cursor highlight takes precedence over the timer color
+			line.WriteString(timer)
+		} else {
+			line.WriteString(timerStyle.Render(timer))
+		}
 
 		// Apply cursor highlighting
 		lineStr := line.String()
-		if i == m.cursor {
+		if isCursor {
 			lineStr = lipgloss.NewStyle().
 				Background(cursorColor).
 				Foreground(lipgloss.Color("0")).
@@ -136,24 +375,94 @@ func (m *Model) renderLeftPane(width, height int) string {
 		} else if item.Selected {
 			lineStr = lipgloss.NewStyle().
 				Foreground(selectedColor).
+				Faint(item.Stale || m.isPreviousBatch(item)).
+				Render(lineStr)
+		} else if item.Stale || m.isPreviousBatch(item) {
+			// Dim results that predate the latest source change or that are
+			// from an earlier batch
+			lineStr = lipgloss.NewStyle().
+				Faint(true).
 				Render(lineStr)
 		}
 
 		content.WriteString(lineStr)
-		if i < endIdx-1 {
+		if r < endIdx-1 {
 			content.WriteString("\n")
 		}
 	}
 
 	// Fill remaining space
-	linesWritten := endIdx - startIdx
+	linesWritten += endIdx - startIdx
 	for i := linesWritten; i < listHeight; i++ {
 		content.WriteString("\n")
 	}
 
+	// The number of rows below the visible rows goes on the last line of the
+	// pane, which isn't used for the list
+	if below := len(rows) - endIdx; below > 0 {
+		content.WriteString("\n" + indicatorStyle.Render(fmt.Sprintf("▼ %d more", below)))
+	}
+
+	if miniMapWidth > 0 {
+		list := lipgloss.NewStyle().Width(max(width-2, 0)).Render(content.String())
+		miniMap := m.renderMiniMap(max(height-2, 1))
+		return style.Render(lipgloss.JoinHorizontal(lipgloss.Top, list, " ", miniMap))
+	}
+
 	return style.Render(content.String())
 }
 
+// listWindow returns the range of rows that is shown in a list of the given
+// height, so the cursor is visible. When the list is scrolled, the first line
+// shows the number of rows above, so one row less fits.
+func listWindow(rows, cursor, height int) (start, end int) {
+	switch {
+	case cursor < height:
+	case height > 1:
+		start = cursor - height + 2
+		height--
+	default:
+		start = cursor
+	}
+	return start, min(start+height, rows)
+}
+
+// renderMiniMap renders a single column that shows the status of all tests
+// in the list, scaled to the given height. Each cell shows the most
+// significant status of the tests it represents.
+func (m *Model) renderMiniMap(height int) string {
+	total := len(m.filteredList)
+	cells := make([]string, height)
+
+	for row := range height {
+		start := row * total / height
+		end := (row + 1) * total / height
+		if end == start && start < total {
+			end = start + 1
+		}
+
+		cell := " "
+		var color lipgloss.Color
+		best := -1
+		for _, item := range m.filteredList[start:end] {
+			if p := miniMapPriority[item.Status]; p > best {
+				best = p
+				color = miniMapColors[item.Status]
+				cell = "█"
+			}
+		}
+
+		// Mark the region that is currently under the cursor
+		if total > 0 && m.cursor >= start && m.cursor < end {
+			cell = "▐"
+		}
+
+		cells[row] = lipgloss.NewStyle().Foreground(color).Render(cell)
+	}
+
+	return strings.Join(cells, "\n")
+}
+
 // renderRightPane renders the output pane
 func (m *Model) renderRightPane(width, height int) string {
 	borderColor := unfocusedBorderColor
@@ -164,28 +473,66 @@ func (m *Model) renderRightPane(width, height int) string {
 	style := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(borderColor).
-		Width(width - 2).
-		Height(height - 2)
+		Width(max(width-2, 0)).
+		Height(max(height-2, 0))
+
+	// The dry run preview replaces the output
+	if m.showPreview {
+		return style.Render(m.renderPreview(width, height))
+	}
 
 	var content strings.Builder
 
 	// Show current test info
-	if len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
+	if selected := m.selectedTests(); m.combinedOutput && len(selected) > 0 {
+		header := fmt.Sprintf("Combined output: %d tests · %s lines · %s", len(selected), formatCount(len(m.outputLines)), formatBytes(m.currentLogSize))
+		content.WriteString(lipgloss.NewStyle().Bold(true).Render(ansi.Truncate(header, max(width-4, 0), "...")))
+		content.WriteString("\n")
+		content.WriteString(strings.Repeat("─", max(width-4, 0)))
+		content.WriteString("\n")
+	} else if len(m.filteredList) > 0 && m.cursor < len(m.filteredList) {
 		item := m.filteredList[m.cursor]
 		testName := strings.TrimPrefix(item.Info.Name, "Test")
 		header := fmt.Sprintf("Output: %s", testName)
+		if m.runDiff {
+			header = fmt.Sprintf("Diff with baseline: %s", testName)
+		}
 
 		// Add timestamp if showing a previous run's log
-		if item.LogFile == "" && !m.currentLogTimestamp.IsZero() {
+		if m.viewingHistorical && !m.currentLogTimestamp.IsZero() {
 			header += fmt.Sprintf(" (from %s)", m.currentLogTimestamp.Format("2006-01-02 15:04:05"))
 		}
 
-		if len(header) > width-4 {
-			header = header[:width-7] + "..."
+		if m.isPreviousBatch(item) {
+			header += " (previous batch)"
+		}
+
+		if !m.runDiff && !m.viewingHistorical && item.IsBuildFailed() {
+			header += " · build failed"
+		}
+
+		// Add the tally when the test ran repeatedly
+		if runs, failures := item.FlakeRate(); runs > 1 || item.IsUntilFail() {
+			header += fmt.Sprintf(" · runs: %d, failures: %d", runs, failures)
+			if item.IsFlaky() {
+				header += fmt.Sprintf(" (flaky, %d%%)", failures*100/runs)
+			}
+			if item.IsUntilFail() {
+				header += " (until it fails)"
+			}
+		}
+
+		// Add output size, so it's visible that a test is still producing output
+		if item.Status == runner.StatusQueued && m.currentLogSize == 0 {
+			header += " (waiting to start)"
+		} else if m.currentLogFile != "" {
+			header += fmt.Sprintf(" · %s lines · %s", formatCount(len(m.outputLines)), formatBytes(m.currentLogSize))
 		}
+
+		header = truncateName(header, width-4)
 		content.WriteString(lipgloss.NewStyle().Bold(true).Render(header))
 		content.WriteString("\n")
-		content.WriteString(strings.Repeat("─", width-4))
+		content.WriteString(strings.Repeat("─", max(width-4, 0)))
 		content.WriteString("\n")
 	}
 
@@ -195,34 +542,77 @@ func (m *Model) renderRightPane(width, height int) string {
 		outputHeight = 1
 	}
 
-	startLine := m.outputScroll
-	endLine := startLine + outputHeight
-	if endLine > len(m.outputLines) {
-		endLine = len(m.outputLines)
+	lineWidth := max(width-4, 0)
+
+	// Determine the part of each line on the visible rows. Without wrapping,
+	// a row is a line after horizontal scrolling, truncated to the width and
+	// marked when it continues beyond the pane.
+	var rows []outputRow
+	var suffixes []string
+	maxScroll := m.maxHorizontalScroll()
+	if m.wrapOutput {
+		all := m.outputRows()
+		start := min(m.outputScroll, len(all))
+		rows = all[start:min(start+outputHeight, len(all))]
+		suffixes = make([]string, len(rows))
+	} else {
+		for i := m.outputScroll; i < min(m.outputScroll+outputHeight, len(m.outputLines)); i++ {
+			line := m.outputLines[i]
+			start := skipColumns(line, min(m.horizontalScroll, maxScroll))
+			end := columnOffset(line, start, lineWidth)
+			suffix := ""
+			if end < len(line) && lineWidth > 1 {
+				end = columnOffset(line, start, lineWidth-1)
+				suffix = "…"
+			}
+			rows = append(rows, outputRow{line: i, start: start, end: end})
+			suffixes = append(suffixes, suffix)
+		}
 	}
 
-	lineWidth := width - 4
+	// Highlight the search matches once the search is performed
+	var searchPattern *regexp.Regexp
+	if len(m.searchMatches) > 0 {
+		searchPattern, _ = m.searchPattern()
+	}
+	currentMatchLine := -1
+	if m.currentMatchIdx >= 0 && m.currentMatchIdx < len(m.searchMatches) {
+		currentMatchLine = m.searchMatches[m.currentMatchIdx]
+	}
 
-	// Render visible lines
+	// Render visible rows
 	linesRendered := 0
-	for i := startLine; i < endLine; i++ {
+	for r, row := range rows {
+		i := row.line
 		line := m.outputLines[i]
-
-		// Apply horizontal scroll
-		if m.horizontalScroll > 0 && len(line) > m.horizontalScroll {
-			line = line[m.horizontalScroll:]
-		} else if m.horizontalScroll > 0 {
-			line = ""
+		start, end, suffix := row.start, row.end, suffixes[r]
+
+		// Colorize diff lines
+		render := func(s string) string { return s }
+		if i < len(m.diffKinds) {
+			if color, ok := diffColors[m.diffKinds[i]]; ok {
+				style := lipgloss.NewStyle().Foreground(color)
+				render = func(s string) string { return style.Render(s) }
+			}
 		}
 
-		// Truncate to width
-		if len(line) > lineWidth {
-			line = line[:lineWidth]
+		// The matches on the line of the current match stand out
+		if searchPattern != nil {
+			matchStyle := lipgloss.NewStyle().Background(matchColor)
+			if i == currentMatchLine {
+				matchStyle = lipgloss.NewStyle().Background(currentMatchColor).Foreground(lipgloss.Color("0"))
+			}
+			matches := searchPattern.FindAllStringIndex(line, -1)
+			highlight := func(s string) string { return matchStyle.Render(s) }
+			content.WriteString(highlightMatches(line, start, end, matches, render, highlight))
+		} else {
+			content.WriteString(render(line[start:end]))
+		}
+		if suffix != "" {
+			content.WriteString(render(suffix))
 		}
-
-		content.WriteString(line)
 		linesRendered++
-		if i < endLine-1 {
+		if r < len(rows)-1 {
 			content.WriteString("\n")
 		}
 	}
@@ -235,25 +625,44 @@ func (m *Model) renderRightPane(width, height int) string {
 	// Search mode input or scroll indicator
 	if m.searchMode {
 		content.WriteString("\n")
-		searchPrompt := fmt.Sprintf("Search: %s█", m.searchText)
+		label := "Search"
+		if m.searchRegex {
+			label = "Regex"
+		}
+		searchPrompt := fmt.Sprintf("%s: %s█", label, m.searchText)
 		content.WriteString(lipgloss.NewStyle().Bold(true).Render(searchPrompt))
 	} else {
 		// Scroll indicator and search info
 		var infoItems []string
 
-		if len(m.outputLines) > outputHeight {
-			scrollInfo := fmt.Sprintf("[%d/%d]", m.outputScroll+1, len(m.outputLines))
+		// The position is the line at the top, also when lines are wrapped
+		if m.outputRowCount() > outputHeight {
+			scrollInfo := fmt.Sprintf("[%d/%d]", m.scrollLine()+1, len(m.outputLines))
 			if m.autoScroll {
 				scrollInfo += " (auto)"
 			}
 			infoItems = append(infoItems, scrollInfo)
 		}
 
-		if m.searchText != "" && len(m.searchMatches) > 0 {
-			matchInfo := fmt.Sprintf("'%s' %d/%d", m.searchText, m.currentMatchIdx+1, len(m.searchMatches))
+		if m.colorizeDiffs {
+			infoItems = append(infoItems, "diff")
+		}
+		if m.wrapOutput {
+			infoItems = append(infoItems, "wrap")
+		}
+
+		// Regular expressions are shown between slashes
+		quoted := fmt.Sprintf("'%s'", m.searchText)
+		if m.searchRegex {
+			quoted = fmt.Sprintf("/%s/", m.searchText)
+		}
+		if m.searchErr != nil {
+			infoItems = append(infoItems, fmt.Sprintf("invalid regex %s: %v", quoted, m.searchErr))
+		} else if m.searchText != "" && len(m.searchMatches) > 0 {
+			matchInfo := fmt.Sprintf("%s %d/%d", quoted, m.currentMatchIdx+1, len(m.searchMatches))
 			infoItems = append(infoItems, matchInfo)
 		} else if m.searchText != "" {
-			infoItems = append(infoItems, fmt.Sprintf("'%s' not found", m.searchText))
+			infoItems = append(infoItems, fmt.Sprintf("%s not found", quoted))
 		}
 
 		if len(infoItems) > 0 {
@@ -273,8 +682,18 @@ func (m *Model) renderStatusBar() string {
 		Width(m.width).
 		Padding(0, 1)
 
+	// Confirmation prompt replaces the status bar content
+	if m.confirmMode {
+		return style.Bold(true).Render(m.confirmPrompt + " (y/n)")
+	}
+
 	// Left side: controls help
-	leftInfo := "q:quit │ g:go │ t:stop │ s:sort │ e:edit │ r:rec │ +/-:par │ /:filter"
+	leftInfo := statusBarHints()
+
+	// A transient message temporarily replaces the controls help
+	if m.statusMessage != "" && time.Since(m.statusMessageAt) < statusMessageDuration {
+		leftInfo = m.statusMessage
+	}
 
 	// Right side: status info with recursive indicator
 	recursiveIndicator := "on"
@@ -292,24 +711,216 @@ func (m *Model) renderStatusBar() string {
 		sortModeStr = "status"
 	}
 
-	rightInfo := fmt.Sprintf("Sort:%s │ Rec:%s │ Par:%d │ Run:%d │ Queue:%d",
+	parallelStr := fmt.Sprintf("%d", m.runner.GetMaxParallel())
+	if m.runner.IsSequential() {
+		parallelStr = "seq"
+	}
+
+	// Pass rate of the finished tests, with the trend since the previous session
+	passInfo := ""
+	if rate, ok := m.passRate(); ok {
+		passInfo = fmt.Sprintf("%d%% pass", rate)
+		if prev := m.previousPassRate; prev != nil && rate > *prev {
+			passInfo += "↑"
+		} else if prev != nil && rate < *prev {
+			passInfo += "↓"
+		}
+		passInfo += " │ "
+	}
+
+	rightInfo := fmt.Sprintf("%sSort:%s │ Rec:%s │ Par:%s │ Timeout:%s │ Run:%d │ Queue:%d",
+		passInfo,
 		sortModeStr,
 		recursiveIndicator,
-		m.runner.GetMaxParallel(),
+		parallelStr,
+		formatTimeout(m.runner.GetTestTimeout()),
 		m.runner.GetRunningCount(),
 		m.runner.GetQueuedCount())
 
-	// Calculate spacing
-	spacing := m.width - len(leftInfo) - len(rightInfo) - 4
+	if m.statusFilter != StatusFilterAll {
+		rightInfo = fmt.Sprintf("Show:%s │ %s", m.statusFilter, rightInfo)
+	}
+	if m.runner.IsCover() {
+		rightInfo = "cover │ " + rightInfo
+	}
+	if m.runner.IsFastFirst() {
+		rightInfo = "fast │ " + rightInfo
+	}
+	if m.runner.IsPackageRuns() {
+		rightInfo = "pkg │ " + rightInfo
+	}
+	if retries := m.runner.GetRetries(); retries > 0 {
+		rightInfo = fmt.Sprintf("Retry:%d │ %s", retries, rightInfo)
+	}
+
+	if m.autoRun {
+		rightInfo = "auto │ " + rightInfo
+	}
+
+	// Race builds are slower, so show when the race detector is enabled
+	if m.runner.IsRace() {
+		rightInfo = "race │ " + rightInfo
+	}
+
+	// Make it clear that tests can't be run while reviewing logs
+	if m.reviewMode {
+		rightInfo = "Review │ " + rightInfo
+	}
+
+	// Tests for another platform are only built, so make that stand out
+	if target := m.runner.GetCrossPlatform(); target != "" {
+		rightInfo = fmt.Sprintf("Build-only:%s │ %s", target, rightInfo)
+	}
+
+	// Progress of the running batch, so it's clear how much is left
+	if progress := m.progressInfo(); progress != "" {
+		rightInfo = progress + rightInfo
+	}
+
+	// Totals of all tests and the wall time of the current batch, which are
+	// left out first when there is no room for them
+	available := max(m.width-2, 0) // -2 for padding
+	if summary := m.summaryInfo(); lipgloss.Width(summary)+lipgloss.Width(rightInfo) <= available {
+		rightInfo = summary + rightInfo
+	}
+
+	// Calculate spacing, dropping the help text when there is no room for it
+	spacing := available - lipgloss.Width(leftInfo) - lipgloss.Width(rightInfo)
 	if spacing < 1 {
-		spacing = 1
+		leftInfo = ""
+		spacing = max(available-lipgloss.Width(rightInfo), 0)
 	}
 
 	statusText := leftInfo + strings.Repeat(" ", spacing) + rightInfo
 
+	// Never let the status bar wrap onto a second line
+	statusText = ansi.Truncate(statusText, available, "…")
+
 	return style.Render(statusText)
 }
 
+// isPreviousBatch reports whether the result of a finished test is from an
+// earlier batch than the most recently queued tests
+func (m *Model) isPreviousBatch(item *runner.TestItem) bool {
+	return item.Status.Finished() && item.Batch < m.runner.CurrentBatch()
+}
+
+// packageColor returns a stable color for a package, derived from a hash of
+// its path
+func packageColor(pkg string) lipgloss.AdaptiveColor {
+	h := fnv.New32a()
+	h.Write([]byte(pkg))
+	return packageColors[h.Sum32()%uint32(len(packageColors))]
+}
+
+// reliabilityColor returns the color of the reliability indicator. Tests
+// that failed in any of their recent runs get a warning color, even when the
+// last run passed.
+func reliabilityColor(h testHistory) lipgloss.Color {
+	switch {
+	case h.failures == 0:
+		return reliabilityColors[0]
+	case h.failures == h.runs:
+		return reliabilityColors[3]
+	case h.failures*4 <= h.runs:
+		return reliabilityColors[1]
+	default:
+		return reliabilityColors[2]
+	}
+}
+
+// summaryInfo returns the number of passed, failed and skipped tests (of all
+// tests, not only the filtered ones) and the wall time of the current batch
+func (m *Model) summaryInfo() string {
+	passed, failed, skipped := 0, 0, 0
+	for _, item := range m.tests {
+		switch item.Status {
+		case runner.StatusPassed:
+			passed++
+		case runner.StatusFailed:
+			failed++
+		case runner.StatusSkipped:
+			skipped++
+		}
+	}
+
+	summary := fmt.Sprintf("✓%d ✗%d ⊘%d", passed, failed, skipped)
+	if elapsed, _ := m.runner.BatchElapsed(); elapsed > 0 {
+		summary += " in " + formatDuration(elapsed)
+	}
+	return summary + " │ "
+}
+
+// progressWidth is the number of cells of the progress bar in the status bar
+const progressWidth = 8
+
+// progressInfo returns a progress bar with the number of finished tests of
+// the running batch. It's empty when no batch is running.
+func (m *Model) progressInfo() string {
+	if _, finished := m.runner.BatchElapsed(); finished {
+		return ""
+	}
+	done, total := m.runner.BatchProgress()
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s %d%% %d/%d │ ", progressBar(done, total, progressWidth), done*100/total, done, total)
+}
+
+// progressBar renders done out of total as a bar of width cells
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done*width/total, width)
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// passRate returns the percentage of finished tests that passed. It returns
+// false when no test has finished yet.
+func (m *Model) passRate() (int, bool) {
+	passed, finished := 0, 0
+	for _, item := range m.tests {
+		switch item.Status {
+		case runner.StatusPassed:
+			passed++
+			finished++
+		case runner.StatusFailed:
+			finished++
+		}
+	}
+	if finished == 0 {
+		return 0, false
+	}
+	return passed * 100 / finished, true
+}
+
+// testDoc returns the doc comment of a test without the leading test name
+func testDoc(info runner.TestInfo) string {
+	return strings.TrimPrefix(info.Doc, info.Name+" ")
+}
+
+// formatCount formats a count with thousands separators
+func formatCount(n int) string {
+	s := fmt.Sprintf("%d", n)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatBytes formats a size in bytes for display
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%d KB", n/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
 // formatDuration formats a duration for display
 func formatDuration(d time.Duration) string {
 	if d < time.Second {
@@ -320,3 +931,27 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
 }
+
+// highlightMatches renders the part of a line between the byte offsets start
+// and end, highlighting the (parts of) matches that fall within it. Matches
+// are byte offset pairs into the full line, as returned by
+// regexp.FindAllStringIndex.
+func highlightMatches(line string, start, end int, matches [][]int, render, highlight func(string) string) string {
+	var b strings.Builder
+	pos := start
+	for _, match := range matches {
+		from, to := max(match[0], start), min(match[1], end)
+		if from >= to {
+			continue
+		}
+		if from > pos {
+			b.WriteString(render(line[pos:from]))
+		}
+		b.WriteString(highlight(line[from:to]))
+		pos = to
+	}
+	if pos < end {
+		b.WriteString(render(line[pos:end]))
+	}
+	return b.String()
+}
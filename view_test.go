@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestFormatCount(t *testing.T) {
+	tests := map[int]string{
+		0:       "0",
+		999:     "999",
+		1204:    "1,204",
+		1234567: "1,234,567",
+	}
+
+	for n, expected := range tests {
+		if got := formatCount(n); got != expected {
+			t.Errorf("formatCount(%d) = %s, expected %s", n, got, expected)
+		}
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	plain := func(s string) string { return s }
+	mark := func(s string) string { return "[" + s + "]" }
+	line := "foo bar foo baz foo"
+	matches := [][]int{{0, 3}, {8, 11}, {16, 19}}
+
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		{0, len(line), "[foo] bar [foo] baz [foo]"},
+		{1, len(line), "[oo] bar [foo] baz [foo]"}, // Scrolled into a match
+		{4, 10, "bar [fo]"},                        // Truncated within a match
+		{12, 15, "baz"},                            // No match visible
+	}
+
+	for _, tt := range tests {
+		if got := highlightMatches(line, tt.start, tt.end, matches, plain, mark); got != tt.expected {
+			t.Errorf("highlightMatches(%d, %d) = %q, expected %q", tt.start, tt.end, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderHelpFits(t *testing.T) {
+	for _, size := range [][2]int{{80, 24}, {160, 40}, {60, 12}} {
+		help := renderHelp(size[0], size[1])
+		lines := strings.Split(help, "\n")
+		if len(lines) > size[1] {
+			t.Errorf("Help for %dx%d has %d lines", size[0], size[1], len(lines))
+		}
+		for _, line := range lines {
+			if w := lipgloss.Width(line); w > size[0] {
+				t.Errorf("Help for %dx%d has a line of width %d", size[0], size[1], w)
+				break
+			}
+		}
+	}
+}
+
+func TestStatusBarHints(t *testing.T) {
+	hints := statusBarHints()
+	for _, hint := range []string{"q:quit", "?:help", "g:go", "+/-:par", "/:filter"} {
+		if !strings.Contains(hints, hint) {
+			t.Errorf("Expected %q in the status bar hints %q", hint, hints)
+		}
+	}
+}
+
+func TestListWindow(t *testing.T) {
+	tests := []struct {
+		rows, cursor, height int
+		start, end           int
+	}{
+		{5, 4, 10, 0, 5},    // Everything fits
+		{20, 6, 7, 0, 7},    // Not scrolled yet
+		{20, 7, 7, 2, 8},    // Scrolled, with a row for the indicator above
+		{20, 19, 7, 14, 20}, // Scrolled to the end
+		{20, 5, 1, 5, 6},    // No room for the indicator
+	}
+
+	for _, tt := range tests {
+		start, end := listWindow(tt.rows, tt.cursor, tt.height)
+		if start != tt.start || end != tt.end {
+			t.Errorf("listWindow(%d, %d, %d) = %d, %d, expected %d, %d", tt.rows, tt.cursor, tt.height, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		done, total int
+		expected    string
+	}{
+		{0, 4, "░░░░░░░░"},
+		{1, 4, "██░░░░░░"},
+		{3, 8, "███░░░░░"},
+		{4, 4, "████████"},
+		{0, 0, "░░░░░░░░"},
+	}
+
+	for _, tt := range tests {
+		if got := progressBar(tt.done, tt.total, 8); got != tt.expected {
+			t.Errorf("progressBar(%d, %d) = %q, expected %q", tt.done, tt.total, got, tt.expected)
+		}
+	}
+}
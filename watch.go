@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits for more changes before they
+// are reported, so a burst of editor writes causes a single rediscovery or run
+const watchDebounce = 500 * time.Millisecond
+
+// filesChangedMsg is sent when Go files were created, modified, removed or
+// renamed
+type filesChangedMsg struct {
+	dirs      []string // Directories with changed Go files
+	testFiles bool     // Test files (or directories) were added, changed or removed
+}
+
+// testWatcher watches a directory tree for changes of Go files. fsnotify
+// doesn't watch recursively, so each directory is watched separately.
+type testWatcher struct {
+	watcher *fsnotify.Watcher
+	changes chan struct{}
+
+	mu          sync.Mutex
+	dirs        map[string]bool // Watched directories
+	pendingDirs map[string]bool // Directories with changes that weren't reported yet
+	pendingTest bool            // Test files changed since the last report
+}
+
+// newTestWatcher starts watching the directory tree for changes of Go files
+func newTestWatcher(dir string) (*testWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &testWatcher{
+		watcher:     watcher,
+		changes:     make(chan struct{}, 1),
+		dirs:        make(map[string]bool),
+		pendingDirs: make(map[string]bool),
+	}
+	if err := w.addTree(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// addTree watches a directory and its subdirectories, skipping the
+// directories that discovery skips as well
+func (w *testWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // Unreadable subdirectories can't be watched
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (d.Name() == "vendor" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+
+		if err := w.watcher.Add(path); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		w.dirs[path] = true
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// run handles the file system events until the watcher is closed
+func (w *testWatcher) run() {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			if !w.record(event) {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(watchDebounce, w.notify)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+
+		case _, ok := <-w.watcher.Errors:
+			// Errors (e.g. an event queue overflow) are ignored, as the
+			// next change triggers a rediscovery anyway
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// record keeps track of the changes of an event and returns whether it's
+// relevant. New directories are watched as well, as they may already contain
+// test files (e.g. when a directory is moved into the tree).
+func (w *testWatcher) record(event fsnotify.Event) bool {
+	if strings.HasSuffix(event.Name, ".go") {
+		if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+			return false
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.pendingDirs[filepath.Dir(event.Name)] = true
+		if strings.HasSuffix(event.Name, "_test.go") {
+			w.pendingTest = true
+		}
+		return true
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			name := filepath.Base(event.Name)
+			if name == "vendor" || strings.HasPrefix(name, ".") {
+				return false
+			}
+			w.addTree(event.Name)
+			w.mu.Lock()
+			w.pendingTest = true
+			w.mu.Unlock()
+			return true
+		}
+		return false
+	}
+
+	// A removed or renamed directory takes its test files along
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.dirs[event.Name] {
+			delete(w.dirs, event.Name)
+			w.pendingTest = true
+			return true
+		}
+	}
+	return false
+}
+
+// notify signals a change, unless a change is already pending
+func (w *testWatcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// wait returns a command that waits for the next changes and reports them
+func (w *testWatcher) wait() tea.Cmd {
+	return func() tea.Msg {
+		<-w.changes
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		msg := filesChangedMsg{testFiles: w.pendingTest}
+		for dir := range w.pendingDirs {
+			msg.dirs = append(msg.dirs, dir)
+		}
+		clear(w.pendingDirs)
+		w.pendingTest = false
+		return msg
+	}
+}
+
+// Close stops watching for changes
+func (w *testWatcher) Close() error {
+	return w.watcher.Close()
+}
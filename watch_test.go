@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ramondeklein/test-runner/pkg/runner"
+)
+
+func TestWatcherNotifiesChanges(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newTestWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	msgs := make(chan any, 1)
+	go func() { msgs <- w.wait()() }()
+
+	// Files other than Go files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-msgs:
+		t.Fatal("Expected no message for a non-Go file")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	// A burst of writes is reported once, with the directory of the files
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "foo.go"), []byte("package foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case msg := <-msgs:
+		changed := msg.(filesChangedMsg)
+		if changed.testFiles || len(changed.dirs) != 1 || changed.dirs[0] != dir {
+			t.Errorf("Expected a change of non-test files in %s, got %+v", dir, changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a message after writing a Go file")
+	}
+	go func() { msgs <- w.wait()() }()
+	select {
+	case msg := <-msgs:
+		t.Fatalf("Expected a single message for a burst of writes, got %+v", msg)
+	case <-time.After(2 * watchDebounce):
+	}
+
+	// Test files in new subdirectories are watched as well
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	<-msgs
+	go func() { msgs <- w.wait()() }()
+	time.Sleep(2 * watchDebounce)
+	if err := os.WriteFile(filepath.Join(sub, "foo_test.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-msgs:
+		if changed := msg.(filesChangedMsg); !changed.testFiles {
+			t.Errorf("Expected a change of test files, got %+v", changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a message after writing a test file")
+	}
+}
+
+func TestRediscoverKeepsExistingTests(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile := func(src string) {
+		if err := os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile("package foo\n\nimport \"testing\"\n\nfunc TestKept(t *testing.T) {}\n\nfunc TestDeleted(t *testing.T) {}\n")
+
+	m, err := NewModel(dir, Options{LogDir: t.TempDir(), NoPersist: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept *runner.TestItem
+	for _, item := range m.tests {
+		if item.Info.Name == "TestKept" {
+			kept = item
+		}
+	}
+	if kept == nil {
+		t.Fatalf("Expected TestKept to be discovered, got %d tests", len(m.tests))
+	}
+	kept.Status = runner.StatusFailed
+	kept.Selected = true
+
+	writeTestFile("package foo\n\nimport \"testing\"\n\nfunc TestAdded(t *testing.T) {}\n\nfunc TestKept(t *testing.T) {}\n")
+	m.rediscoverTests()
+
+	names := make(map[string]*runner.TestItem)
+	for _, item := range m.tests {
+		names[item.Info.Name] = item
+	}
+	if len(names) != 2 || names["TestAdded"] == nil || names["TestKept"] == nil {
+		t.Fatalf("Expected TestAdded and TestKept, got %v", names)
+	}
+	if item := names["TestKept"]; item != kept || item.Status != runner.StatusFailed || !item.Selected {
+		t.Errorf("Expected TestKept to keep its status and selection, got %s (selected: %v)", item.Status, item.Selected)
+	}
+	if kept.Info.Line != 7 {
+		t.Errorf("Expected the line of TestKept to be updated to 7, got %d", kept.Info.Line)
+	}
+}
+
+func TestAutoRunTests(t *testing.T) {
+	dir := t.TempDir()
+	affected := &runner.TestItem{Info: runner.TestInfo{Name: "TestAffected", Package: "sub/pkg"}, Status: runner.StatusPassed}
+	subtest := &runner.TestItem{Info: runner.TestInfo{Name: "TestAffected/sub", Package: "sub/pkg"}, Status: runner.StatusPassed}
+	running := &runner.TestItem{Info: runner.TestInfo{Name: "TestRunning", Package: "sub/pkg"}, Status: runner.StatusRunning}
+	other := &runner.TestItem{Info: runner.TestInfo{Name: "TestOther", Package: "other"}, Status: runner.StatusPassed}
+	failing := &runner.TestItem{Info: runner.TestInfo{Name: "TestFailing", Package: "other"}, Status: runner.StatusFailed}
+	tests := []*runner.TestItem{affected, subtest, running, other, failing}
+
+	testRunner := runner.NewTestRunner(dir, t.TempDir(), 0, 0)
+	m := &Model{tests: tests, filteredList: tests, runner: testRunner, testDir: dir}
+	testRunner.SetTestList(&m.tests)
+
+	m.autoRunTests([]string{filepath.Join(dir, "sub", "pkg")})
+	if affected.Status != runner.StatusQueued {
+		t.Errorf("Expected the test in the changed package to be queued, got %s", affected.Status)
+	}
+	if subtest.Status != runner.StatusPassed || other.Status != runner.StatusPassed || failing.Status != runner.StatusFailed {
+		t.Errorf("Expected subtests and other packages not to be queued, got %s, %s and %s", subtest.Status, other.Status, failing.Status)
+	}
+	if running.Status != runner.StatusRunning {
+		t.Errorf("Expected the running test not to be queued again, got %s", running.Status)
+	}
+
+	m.autoRunFailed = true
+	m.autoRunTests(nil)
+	if failing.Status != runner.StatusQueued || other.Status != runner.StatusPassed {
+		t.Errorf("Expected only the failing test to be queued, got %s and %s", failing.Status, other.Status)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import "github.com/mattn/go-runewidth"
+
+// outputRow is a visual row of the output when long lines are wrapped: the
+// part of a line between two byte offsets
+type outputRow struct {
+	line       int
+	start, end int
+}
+
+// wrapLine splits a line into rows of at most width columns and returns the
+// byte offset at which each row ends. An empty line is a single empty row.
+func wrapLine(line string, width int) []int {
+	width = max(width, 1)
+	var ends []int
+	columns := 0
+	for i, r := range line {
+		w := runewidth.RuneWidth(r)
+		if columns+w > width && columns > 0 {
+			ends = append(ends, i)
+			columns = 0
+		}
+		columns += w
+	}
+	return append(ends, len(line))
+}
+
+// outputWidth returns the number of columns available for an output line
+func (m *Model) outputWidth() int {
+	width := m.width
+	if !m.isSinglePane() {
+		width = m.width - m.width/3 - 1
+	}
+	return max(width-4, 1)
+}
+
+// outputRows returns the visual rows of the wrapped output. The rows are
+// cached until the output or the width of the pane changes.
+func (m *Model) outputRows() []outputRow {
+	width := m.outputWidth()
+	if m.wrapRows != nil && m.wrapWidth == width && m.wrapLines == len(m.outputLines) {
+		return m.wrapRows
+	}
+
+	rows := make([]outputRow, 0, len(m.outputLines))
+	for i, line := range m.outputLines {
+		start := 0
+		for _, end := range wrapLine(line, width) {
+			rows = append(rows, outputRow{line: i, start: start, end: end})
+			start = end
+		}
+	}
+	m.wrapRows, m.wrapWidth, m.wrapLines = rows, width, len(m.outputLines)
+	return rows
+}
+
+// outputRowCount returns the number of rows the output takes up
+func (m *Model) outputRowCount() int {
+	if !m.wrapOutput {
+		return len(m.outputLines)
+	}
+	return len(m.outputRows())
+}
+
+// lineRow returns the first row of a line
+func (m *Model) lineRow(line int) int {
+	if !m.wrapOutput {
+		return line
+	}
+	rows := m.outputRows()
+	for i, row := range rows {
+		if row.line >= line {
+			return i
+		}
+	}
+	return len(rows)
+}
+
+// scrollLine returns the line shown at the top of the output pane
+func (m *Model) scrollLine() int {
+	if !m.wrapOutput {
+		return m.outputScroll
+	}
+	rows := m.outputRows()
+	if m.outputScroll < len(rows) {
+		return rows[m.outputScroll].line
+	}
+	return len(m.outputLines)
+}
+
+// toggleWrap switches between wrapping and truncating long lines, keeping the
+// line at the top of the output pane in view
+func (m *Model) toggleWrap() {
+	line := m.scrollLine()
+	m.wrapOutput = !m.wrapOutput
+	m.horizontalScroll = 0
+	m.outputScroll = min(m.lineRow(line), m.maxOutputScroll())
+	if m.autoScroll {
+		m.outputScroll = m.maxOutputScroll()
+	}
+}
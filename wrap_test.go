@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		width    int
+		expected []int
+	}{
+		{"", 10, []int{0}},
+		{"abcdef", 10, []int{6}},
+		{"abcdef", 3, []int{3, 6}},
+		{"abcdefg", 3, []int{3, 6, 7}},
+		// Wide characters take up two columns and are never split
+		{"日本語", 4, []int{6, 9}},
+		{"a日本", 2, []int{1, 4, 7}},
+	}
+
+	for _, tt := range tests {
+		if got := wrapLine(tt.line, tt.width); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wrapLine(%q, %d) = %v, expected %v", tt.line, tt.width, got, tt.expected)
+		}
+	}
+}
+
+func TestWrappedScrolling(t *testing.T) {
+	m := &Model{width: 200, height: 4}
+	width := m.outputWidth()
+
+	// Line 1 takes up three rows when wrapped
+	m.setOutputLines([]string{"first", strings.Repeat("x", width*2+1), "third"})
+	m.wrapOutput = true
+
+	if got := m.outputRowCount(); got != 5 {
+		t.Errorf("outputRowCount() = %d, expected 5", got)
+	}
+	if got := m.lineRow(2); got != 4 {
+		t.Errorf("lineRow(2) = %d, expected 4", got)
+	}
+
+	m.outputScroll = 3
+	if got := m.scrollLine(); got != 1 {
+		t.Errorf("scrollLine() = %d, expected 1", got)
+	}
+
+	// Turning wrapping off keeps the line at the top
+	m.toggleWrap()
+	if m.outputScroll != 1 {
+		t.Errorf("Expected the scroll position to be line 1 without wrapping, got %d", m.outputScroll)
+	}
+}